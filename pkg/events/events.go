@@ -0,0 +1,281 @@
+// Package events defines the events the services exchange over RabbitMQ:
+// one Go type per event type and version, the JSON Schema generated from
+// it, and helpers that validate payloads against it.
+//
+// Producers encode the latest version of an event with Encode and send the
+// version in the VersionHeader message header. Consumers decode with Decode
+// or DecodeAs, which validate the payload against the version it was
+// published as and upgrade it to the latest version, so they only handle
+// that one. A published version never changes; an incompatible change is a
+// new version with an upgrade from the previous one.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Event types.
+const (
+	TypeOrderCreated       = "order.created"
+	TypeOrderCancelled     = "order.cancelled"
+	TypeLowStock           = "inventory.low_stock"
+	TypeOutOfStock         = "inventory.out_of_stock"
+	TypeRestocked          = "inventory.restocked"
+	TypeBackInStock        = "inventory.back_in_stock"
+	TypeReservationExpired = "inventory.reservation_expired"
+	TypeLotQuarantined     = "inventory.lot_quarantined"
+	TypeStockDrift         = "inventory.stock_drift"
+	TypeStockTransferred   = "inventory.transferred"
+	TypeReceived           = "inventory.received"
+	TypeStockAdjusted      = "inventory.adjusted"
+	TypeFulfilmentFailed   = "inventory.fulfilment_failed"
+)
+
+// VersionHeader is the AMQP header carrying an event's version. Messages
+// without it were published before versioning and are DefaultVersion.
+const (
+	VersionHeader  = "event_version"
+	DefaultVersion = "v1"
+)
+
+// The latest version of every event. Consumers use these, so that moving an
+// event to a new version is a change in this package only.
+type (
+	OrderCreated       = OrderCreatedV1
+	OrderCancelled     = OrderCancelledV2
+	StockAlert         = StockAlertV1
+	BackInStock        = BackInStockV1
+	ReservationExpired = ReservationExpiredV1
+	LotQuarantined     = LotQuarantinedV1
+	StockDrift         = StockDriftV1
+	StockTransferred   = StockTransferredV1
+	Received           = ReceivedV1
+	StockAdjusted      = StockAdjustedV1
+	FulfilmentFailed   = FulfilmentFailedV1
+)
+
+var (
+	ErrUnknownEvent = errors.New("unknown event type or version")
+	ErrWrongPayload = errors.New("payload does not match event type")
+)
+
+// Contract names one version of one event type.
+type Contract struct {
+	Type    string
+	Version string
+}
+
+func (c Contract) String() string {
+	return c.Type + " " + c.Version
+}
+
+type contract struct {
+	Contract
+	payload reflect.Type
+	// upgrade converts a decoded payload to the next version of the event;
+	// it is nil for the latest version.
+	upgrade func(interface{}) interface{}
+	schema  *Schema
+}
+
+// registry lists every version of every event, oldest version first.
+var registry = []*contract{
+	define(TypeOrderCreated, "v1", OrderCreatedV1{}, nil),
+	define(TypeOrderCancelled, "v1", OrderCancelledV1{}, upgradeOrderCancelledV1),
+	define(TypeOrderCancelled, "v2", OrderCancelledV2{}, nil),
+	define(TypeLowStock, "v1", StockAlertV1{}, nil),
+	define(TypeOutOfStock, "v1", StockAlertV1{}, nil),
+	define(TypeRestocked, "v1", StockAlertV1{}, nil),
+	define(TypeBackInStock, "v1", BackInStockV1{}, nil),
+	define(TypeReservationExpired, "v1", ReservationExpiredV1{}, nil),
+	define(TypeLotQuarantined, "v1", LotQuarantinedV1{}, nil),
+	define(TypeStockDrift, "v1", StockDriftV1{}, nil),
+	define(TypeStockTransferred, "v1", StockTransferredV1{}, nil),
+	define(TypeReceived, "v1", ReceivedV1{}, nil),
+	define(TypeStockAdjusted, "v1", StockAdjustedV1{}, nil),
+	define(TypeFulfilmentFailed, "v1", FulfilmentFailedV1{}, nil),
+}
+
+func define(eventType, version string, payload interface{}, upgrade func(interface{}) interface{}) *contract {
+	c := &contract{
+		Contract: Contract{Type: eventType, Version: version},
+		payload:  reflect.TypeOf(payload),
+		upgrade:  upgrade,
+	}
+	c.schema = generateSchema(c.Contract, c.payload)
+	return c
+}
+
+func lookup(eventType, version string) (*contract, error) {
+	if version == "" {
+		version = DefaultVersion
+	}
+	for _, c := range registry {
+		if c.Type == eventType && c.Version == version {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrUnknownEvent, eventType, version)
+}
+
+func latest(eventType string) (*contract, error) {
+	var found *contract
+	for _, c := range registry {
+		if c.Type == eventType {
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, eventType)
+	}
+	return found, nil
+}
+
+// Contracts returns every event type and version, sorted.
+func Contracts() []Contract {
+	list := make([]Contract, 0, len(registry))
+	for _, c := range registry {
+		list = append(list, c.Contract)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Type != list[j].Type {
+			return list[i].Type < list[j].Type
+		}
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// EventTypes returns every event type, sorted.
+func EventTypes() []string {
+	var types []string
+	for _, c := range Contracts() {
+		if len(types) == 0 || types[len(types)-1] != c.Type {
+			types = append(types, c.Type)
+		}
+	}
+	return types
+}
+
+// Latest returns the version producers publish eventType as.
+func Latest(eventType string) (string, error) {
+	c, err := latest(eventType)
+	if err != nil {
+		return "", err
+	}
+	return c.Version, nil
+}
+
+// LatestPayload returns a pointer to an empty payload of the latest version
+// of eventType, ready to be unmarshalled into.
+func LatestPayload(eventType string) (interface{}, error) {
+	c, err := latest(eventType)
+	if err != nil {
+		return nil, err
+	}
+	return reflect.New(c.payload).Interface(), nil
+}
+
+// SchemaFor returns the JSON Schema of a version of eventType; an empty
+// version is DefaultVersion.
+func SchemaFor(eventType, version string) (*Schema, error) {
+	c, err := lookup(eventType, version)
+	if err != nil {
+		return nil, err
+	}
+	return c.schema, nil
+}
+
+// Validate checks body against the schema of the given version of
+// eventType. Payloads that do not conform yield a *ValidationError listing
+// every problem found.
+func Validate(eventType, version string, body []byte) error {
+	c, err := lookup(eventType, version)
+	if err != nil {
+		return err
+	}
+	return c.schema.Validate(c.Contract, body)
+}
+
+// Encode marshals payload, which must be the latest version of eventType,
+// and validates the result. It returns the body and the version to publish
+// it as. A body that fails validation is returned along with the
+// *ValidationError, so that it can be quarantined.
+func Encode(eventType string, payload interface{}) ([]byte, string, error) {
+	c, err := latest(eventType)
+	if err != nil {
+		return nil, "", err
+	}
+	t := reflect.TypeOf(payload)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != c.payload {
+		return nil, "", fmt.Errorf("%w: %s %s is %s, not %v", ErrWrongPayload, c.Type, c.Version, c.payload, t)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal %s: %w", eventType, err)
+	}
+	if err := c.schema.Validate(c.Contract, body); err != nil {
+		return body, c.Version, err
+	}
+	return body, c.Version, nil
+}
+
+// Decode validates body as the given version of eventType and returns it
+// upgraded to the latest version, as a pointer to its payload type.
+func Decode(eventType, version string, body []byte) (interface{}, error) {
+	c, err := lookup(eventType, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.schema.Validate(c.Contract, body); err != nil {
+		return nil, err
+	}
+
+	v := reflect.New(c.payload).Interface()
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", c, err)
+	}
+	for c.upgrade != nil {
+		v = c.upgrade(v)
+		if c, err = next(c); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// DecodeAs is Decode for consumers that know the payload type of eventType.
+func DecodeAs[T any](eventType, version string, body []byte) (T, error) {
+	var zero T
+	v, err := Decode(eventType, version, body)
+	if err != nil {
+		return zero, err
+	}
+	p, ok := v.(*T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s decodes to %T, not %T", ErrWrongPayload, eventType, v, p)
+	}
+	return *p, nil
+}
+
+func next(c *contract) (*contract, error) {
+	for i, r := range registry {
+		if r != c {
+			continue
+		}
+		for _, n := range registry[i+1:] {
+			if n.Type == c.Type {
+				return n, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: no version of %s after %s", ErrUnknownEvent, c.Type, c.Version)
+}
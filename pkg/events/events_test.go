@@ -0,0 +1,299 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run `go test ./pkg/events -update` after adding an event or a version to
+// write its schema, and add a sample payload to testdata/. Existing schemas
+// must not change.
+var update = flag.Bool("update", false, "write schemas/ for new event versions")
+
+func schemaPath(c Contract) string {
+	return filepath.Join("schemas", c.Type+"."+c.Version+".json")
+}
+
+func samplePath(c Contract) string {
+	return filepath.Join("testdata", c.Type+"."+c.Version+".json")
+}
+
+func marshalSchema(t *testing.T, s *Schema) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, '\n')
+}
+
+// TestSchemasAreFrozen fails when a published event version changes shape.
+// Consumers that are not redeployed still read the old shape, so such a
+// change needs a new version instead.
+func TestSchemasAreFrozen(t *testing.T) {
+	for _, c := range Contracts() {
+		t.Run(c.String(), func(t *testing.T) {
+			generated, err := SchemaFor(c.Type, c.Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := marshalSchema(t, generated)
+
+			got, err := os.ReadFile(schemaPath(c))
+			if errors.Is(err, os.ErrNotExist) && *update {
+				if err := os.WriteFile(schemaPath(c), want, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("no published schema for %s, run go test -update to add it: %v", c, err)
+			}
+			if bytes.Equal(got, want) {
+				return
+			}
+
+			var published Schema
+			if err := json.Unmarshal(got, &published); err != nil {
+				t.Fatalf("%s: %v", schemaPath(c), err)
+			}
+			breaking := incompatibilities(&published, generated, "$")
+			if len(breaking) > 0 {
+				t.Fatalf("%s changed incompatibly; add a new version with an upgrade instead:\n  %s",
+					c, strings.Join(breaking, "\n  "))
+			}
+			t.Fatalf("%s changed; published versions are frozen, add a new version instead", c)
+		})
+	}
+}
+
+// incompatibilities lists the changes from published to current that
+// break consumers or producers of published.
+func incompatibilities(published, current *Schema, path string) []string {
+	var found []string
+	for _, typ := range published.Type {
+		if !current.Type.allows(typ) {
+			found = append(found, fmt.Sprintf("%s: no longer accepts %s", path, typ))
+		}
+	}
+	for name, prop := range published.Properties {
+		next, ok := current.Properties[name]
+		if !ok {
+			found = append(found, fmt.Sprintf("%s.%s: removed", path, name))
+			continue
+		}
+		found = append(found, incompatibilities(prop, next, path+"."+name)...)
+	}
+	for _, name := range current.Required {
+		if !contains(published.Required, name) {
+			found = append(found, fmt.Sprintf("%s.%s: now required", path, name))
+		}
+	}
+	if published.Format != current.Format {
+		found = append(found, fmt.Sprintf("%s: format %q is now %q", path, published.Format, current.Format))
+	}
+	for _, v := range published.Enum {
+		if len(current.Enum) > 0 && !contains(current.Enum, v) {
+			found = append(found, fmt.Sprintf("%s: no longer accepts %q", path, v))
+		}
+	}
+	if len(published.Enum) == 0 && len(current.Enum) > 0 {
+		found = append(found, fmt.Sprintf("%s: now restricted to %s", path, strings.Join(current.Enum, ", ")))
+	}
+	if current.Minimum != nil && (published.Minimum == nil || *current.Minimum > *published.Minimum) {
+		found = append(found, fmt.Sprintf("%s: minimum raised to %d", path, *current.Minimum))
+	}
+	if published.Items != nil && current.Items != nil {
+		found = append(found, incompatibilities(published.Items, current.Items, path+"[]")...)
+	}
+	sort.Strings(found)
+	return found
+}
+
+// TestSamplesDecode checks that a payload as published of every event
+// version still decodes to the latest version.
+func TestSamplesDecode(t *testing.T) {
+	for _, c := range Contracts() {
+		t.Run(c.String(), func(t *testing.T) {
+			body, err := os.ReadFile(samplePath(c))
+			if err != nil {
+				t.Fatalf("every event version needs a sample payload in %s: %v", samplePath(c), err)
+			}
+			v, err := Decode(c.Type, c.Version, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := LatestPayload(c.Type)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(v) != reflect.TypeOf(want) {
+				t.Fatalf("decoded to %T, want the latest version %T", v, want)
+			}
+		})
+	}
+}
+
+func TestLatestAliases(t *testing.T) {
+	aliases := map[string]interface{}{
+		TypeOrderCreated:       OrderCreated{},
+		TypeOrderCancelled:     OrderCancelled{},
+		TypeLowStock:           StockAlert{},
+		TypeOutOfStock:         StockAlert{},
+		TypeRestocked:          StockAlert{},
+		TypeBackInStock:        BackInStock{},
+		TypeReservationExpired: ReservationExpired{},
+		TypeLotQuarantined:     LotQuarantined{},
+		TypeStockDrift:         StockDrift{},
+		TypeStockTransferred:   StockTransferred{},
+		TypeReceived:           Received{},
+		TypeStockAdjusted:      StockAdjusted{},
+		TypeFulfilmentFailed:   FulfilmentFailed{},
+	}
+	for _, eventType := range EventTypes() {
+		alias, ok := aliases[eventType]
+		if !ok {
+			t.Errorf("%s has no alias for its latest version", eventType)
+			continue
+		}
+		c, err := latest(eventType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := reflect.TypeOf(alias); got != c.payload {
+			t.Errorf("%s alias is %s, latest version is %s %s", eventType, got, c.Version, c.payload)
+		}
+	}
+}
+
+// TestTypesAreRegistered checks that every event type constant has a
+// contract, so that publishing one cannot fail with ErrUnknownEvent.
+func TestTypesAreRegistered(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "events.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if !strings.HasPrefix(name.Name, "Type") || i >= len(vs.Values) {
+					continue
+				}
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				eventType, _ := strconv.Unquote(lit.Value)
+				found++
+				if _, err := Latest(eventType); err != nil {
+					t.Errorf("%s = %q has no contract: %v", name.Name, eventType, err)
+				}
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatal("no Type constants found in events.go")
+	}
+}
+
+func TestDecodeUpgradesOrderCancelledV1(t *testing.T) {
+	body := []byte(`{"order_id": 1042, "variant_id": 12, "product_id": 12, "quantity": 2}`)
+
+	got, err := DecodeAs[OrderCancelled](TypeOrderCancelled, "", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := OrderCancelled{ID: 1042, VariantID: 12, ProductID: 12, Quantity: 2, Status: "cancelled"}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	body := []byte(`{"id": "1042", "user_id": 7, "product_id": 12, "quantity": 0, "status": "created", "created_at": "yesterday"}`)
+
+	err := Validate(TypeOrderCreated, "v1", body)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a *ValidationError", err)
+	}
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	want := []string{"$.created_at", "$.id", "$.quantity"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("problems at %v, want %v: %v", paths, want, err)
+	}
+}
+
+func TestValidateRequiredAndNull(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		ok   bool
+	}{
+		{"pointers omitted", `{"lot_id": 1, "product_id": 2, "lot_number": "L1", "quantity": 5}`, true},
+		{"null pointer", `{"lot_id": 1, "product_id": 2, "lot_number": "L1", "quantity": 5, "expires_at": null, "quarantined_at": null}`, true},
+		{"missing lot number", `{"lot_id": 1, "product_id": 2, "quantity": 5}`, false},
+		{"null string", `{"lot_id": 1, "product_id": 2, "lot_number": null, "quantity": 5}`, false},
+		{"not an object", `[1, 2]`, false},
+		{"malformed", `{"lot_id": `, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(TypeLotQuarantined, "v1", []byte(tt.body))
+			if (err == nil) != tt.ok {
+				t.Fatalf("Validate(%s) = %v, want ok=%v", tt.body, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEncodeRejectsOtherPayloads(t *testing.T) {
+	if _, _, err := Encode(TypeOrderCancelled, OrderCancelledV1{OrderID: 1, ProductID: 2, Quantity: 1}); !errors.Is(err, ErrWrongPayload) {
+		t.Fatalf("encoding an old version: got %v, want ErrWrongPayload", err)
+	}
+	if _, _, err := Encode(TypeOrderCreated, map[string]interface{}{"id": 1}); !errors.Is(err, ErrWrongPayload) {
+		t.Fatalf("encoding a map: got %v, want ErrWrongPayload", err)
+	}
+	if _, _, err := Encode("order.shipped", OrderCreated{}); !errors.Is(err, ErrUnknownEvent) {
+		t.Fatalf("encoding an unknown type: got %v, want ErrUnknownEvent", err)
+	}
+
+	invalid := OrderCreated{ID: 1, UserID: 7, ProductID: 12, Quantity: 0, Status: "created", CreatedAt: time.Now()}
+	var verr *ValidationError
+	body, version, err := Encode(TypeOrderCreated, invalid)
+	if !errors.As(err, &verr) {
+		t.Fatalf("encoding quantity 0: got %v, want a *ValidationError", err)
+	}
+	if len(body) == 0 || version != "v1" {
+		t.Fatalf("encoding quantity 0 returned body %q version %q, want them for quarantine", body, version)
+	}
+}
+
+func TestDecodeUnknownVersion(t *testing.T) {
+	if _, err := Decode(TypeOrderCreated, "v9", []byte(`{}`)); !errors.Is(err, ErrUnknownEvent) {
+		t.Fatalf("got %v, want ErrUnknownEvent", err)
+	}
+}
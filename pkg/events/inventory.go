@@ -0,0 +1,133 @@
+package events
+
+import "time"
+
+// StockAlertV1 is published by inventory-service as inventory.low_stock,
+// inventory.out_of_stock or inventory.restocked when a product's on-hand
+// stock crosses its reorder threshold or zero.
+type StockAlertV1 struct {
+	ProductID     int64     `json:"product_id" schema:"min=1"`
+	SKU           string    `json:"sku"`
+	ProductName   string    `json:"product_name"`
+	State         string    `json:"state" schema:"enum=in_stock|low_stock|out_of_stock"`
+	PreviousState string    `json:"previous_state" schema:"enum=in_stock|low_stock|out_of_stock"`
+	Stock         int       `json:"stock"`
+	Threshold     int       `json:"threshold" schema:"min=0"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// BackInStockV1 is published by inventory-service as inventory.back_in_stock
+// when a product goes from zero to positive stock. UserIDs are the users
+// whose subscriptions were used up by this event; every subscription is in
+// one event only.
+type BackInStockV1 struct {
+	ProductID   int64     `json:"product_id" schema:"min=1"`
+	SKU         string    `json:"sku"`
+	ProductName string    `json:"product_name"`
+	Stock       int       `json:"stock" schema:"min=1"`
+	UserIDs     []int64   `json:"user_ids"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// ReservationExpiredV1 is published by inventory-service when an order's
+// stock reservation runs out before the order is confirmed.
+type ReservationExpiredV1 struct {
+	ReservationID int64     `json:"reservation_id" schema:"min=1"`
+	OrderID       int64     `json:"order_id" schema:"min=1"`
+	ProductID     int64     `json:"product_id" schema:"min=1"`
+	Quantity      int       `json:"quantity" schema:"min=1"`
+	ExpiredAt     time.Time `json:"expired_at"`
+}
+
+// LotQuarantinedV1 is published by inventory-service when an expired lot is
+// taken out of stock.
+type LotQuarantinedV1 struct {
+	LotID         int64      `json:"lot_id" schema:"min=1"`
+	ProductID     int64      `json:"product_id" schema:"min=1"`
+	LotNumber     string     `json:"lot_number"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	Quantity      int        `json:"quantity" schema:"min=0"`
+	QuarantinedAt *time.Time `json:"quarantined_at"`
+}
+
+// StockDriftV1 is published by inventory-service when a reconciliation run
+// finds products whose stock differs from the net of their stock logs.
+type StockDriftV1 struct {
+	RunID   int64               `json:"run_id" schema:"min=1"`
+	Trigger string              `json:"trigger" schema:"enum=scheduled|manual"`
+	Drifted int                 `json:"drifted" schema:"min=0"`
+	Drifts  []ProductStockDrift `json:"drifts"`
+}
+
+// ProductStockDrift is one drifted product of a StockDriftV1.
+type ProductStockDrift struct {
+	ProductID     int64  `json:"product_id" schema:"min=1"`
+	SKU           string `json:"sku"`
+	ProductName   string `json:"product_name"`
+	Stock         int    `json:"stock"`
+	ExpectedStock int    `json:"expected_stock"`
+	Drift         int    `json:"drift"`
+}
+
+// StockTransferredV1 is published by inventory-service when stock moves
+// between warehouse locations. A nil location stands for the product's
+// unassigned pool.
+type StockTransferredV1 struct {
+	ID             int64     `json:"id" schema:"min=1"`
+	ProductID      int64     `json:"product_id" schema:"min=1"`
+	FromLocationID *int64    `json:"from_location_id"`
+	ToLocationID   *int64    `json:"to_location_id"`
+	Quantity       int       `json:"quantity" schema:"min=1"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReceivedV1 is published by inventory-service as inventory.received when
+// goods are received against a purchase order. Receipts are the lines
+// received by this delivery only.
+type ReceivedV1 struct {
+	PurchaseOrderID int64          `json:"purchase_order_id" schema:"min=1"`
+	SupplierID      int64          `json:"supplier_id" schema:"min=1"`
+	Status          string         `json:"status" schema:"enum=partially_received|received"`
+	Receipts        []GoodsReceipt `json:"receipts"`
+}
+
+// GoodsReceipt is one received purchase order line of a ReceivedV1.
+type GoodsReceipt struct {
+	ID              int64     `json:"id" schema:"min=1"`
+	PurchaseOrderID int64     `json:"purchase_order_id" schema:"min=1"`
+	LineID          int64     `json:"line_id" schema:"min=1"`
+	ProductID       int64     `json:"product_id" schema:"min=1"`
+	Quantity        int       `json:"quantity" schema:"min=1"`
+	LocationID      *int64    `json:"location_id,omitempty"`
+	LotID           *int64    `json:"lot_id,omitempty"`
+	StockLogID      int64     `json:"stock_log_id" schema:"min=1"`
+	Actor           string    `json:"actor"`
+	ReceivedAt      time.Time `json:"received_at"`
+}
+
+// StockAdjustedV1 is published by inventory-service as inventory.adjusted
+// for a manual stock adjustment or a posted cycle count variance. It is the
+// stock log entry the adjustment wrote.
+type StockAdjustedV1 struct {
+	ID         int64     `json:"id" schema:"min=1"`
+	ProductID  int64     `json:"product_id" schema:"min=1"`
+	Change     int       `json:"change"`
+	Reason     string    `json:"reason"`
+	OrderID    *int64    `json:"order_id,omitempty"`
+	LocationID *int64    `json:"location_id,omitempty"`
+	Actor      *string   `json:"actor,omitempty"`
+	Note       *string   `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FulfilmentFailedV1 is published by inventory-service as
+// inventory.fulfilment_failed when the stock for an order.created cannot be
+// taken, whether it was reserved or not: there is not enough, or only expired
+// lots are left. The order is not retried.
+type FulfilmentFailedV1 struct {
+	OrderID   int64     `json:"order_id" schema:"min=1"`
+	ProductID int64     `json:"product_id" schema:"min=1"`
+	Quantity  int       `json:"quantity" schema:"min=1"`
+	Reason    string    `json:"reason" schema:"enum=insufficient_stock|lot_expired"`
+	FailedAt  time.Time `json:"failed_at"`
+}
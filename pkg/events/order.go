@@ -0,0 +1,70 @@
+package events
+
+import "time"
+
+// OrderCreatedV1 is published by order-service when an order is placed. It
+// carries the order itself. Events published before variants have no
+// variant_id, and those published before price snapshots no price.
+type OrderCreatedV1 struct {
+	ID             int64     `json:"id" schema:"min=1"`
+	UserID         int64     `json:"user_id" schema:"min=1"`
+	VariantID      int64     `json:"variant_id,omitempty"`
+	ProductID      int64     `json:"product_id" schema:"min=1"`
+	Quantity       int       `json:"quantity" schema:"min=1"`
+	UnitPriceMinor int64     `json:"unit_price_minor,omitempty"`
+	Currency       string    `json:"currency,omitempty"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ItemID is the inventory item the order consumes.
+func (e OrderCreatedV1) ItemID() int64 {
+	if e.VariantID != 0 {
+		return e.VariantID
+	}
+	return e.ProductID
+}
+
+// OrderCancelledV1 is the order.cancelled payload published before v2. It
+// names the order as order_id and carries no user or dates.
+type OrderCancelledV1 struct {
+	OrderID   int64 `json:"order_id" schema:"min=1"`
+	VariantID int64 `json:"variant_id,omitempty"`
+	ProductID int64 `json:"product_id" schema:"min=1"`
+	Quantity  int   `json:"quantity" schema:"min=1"`
+}
+
+// OrderCancelledV2 is published by order-service when an order is
+// cancelled. Like order.created it carries the order itself, in its
+// cancelled state.
+type OrderCancelledV2 struct {
+	ID          int64     `json:"id" schema:"min=1"`
+	UserID      int64     `json:"user_id" schema:"min=1"`
+	VariantID   int64     `json:"variant_id,omitempty"`
+	ProductID   int64     `json:"product_id" schema:"min=1"`
+	Quantity    int       `json:"quantity" schema:"min=1"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// ItemID is the inventory item the order consumed.
+func (e OrderCancelledV2) ItemID() int64 {
+	if e.VariantID != 0 {
+		return e.VariantID
+	}
+	return e.ProductID
+}
+
+// upgradeOrderCancelledV1 fills in what a v1 event carries. Its user and
+// dates are unknown and left zero.
+func upgradeOrderCancelledV1(v interface{}) interface{} {
+	e := v.(*OrderCancelledV1)
+	return &OrderCancelledV2{
+		ID:        e.OrderID,
+		VariantID: e.VariantID,
+		ProductID: e.ProductID,
+		Quantity:  e.Quantity,
+		Status:    "cancelled",
+	}
+}
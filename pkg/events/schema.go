@@ -0,0 +1,265 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema that event payloads are described
+// with. It is generated from the payload types: a field is required unless
+// it is a pointer or tagged omitempty, and a `schema:"min=1"` or
+// `schema:"enum=a|b"` tag adds a minimum or the allowed values. Properties
+// not in the schema are allowed, so that consumers accept events from
+// producers that are ahead of them.
+type Schema struct {
+	Dialect    string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Type       Types              `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Minimum    *int64             `json:"minimum,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+// Types is a schema's "type", written as a string when there is only one.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+func (t Types) allows(jsonType string) bool {
+	for _, s := range t {
+		if s == jsonType || s == "number" && jsonType == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func generateSchema(c Contract, t reflect.Type) *Schema {
+	s := schemaOf(t)
+	s.Dialect = schemaDialect
+	s.Title = c.String()
+	return s
+}
+
+// schemaOf describes the JSON encoding of t. Payload types are plain
+// structs; anything it cannot describe is a mistake in this package.
+func schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := schemaOf(t.Elem())
+		s.Type = append(s.Type, "null")
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema)}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := schemaOf(f.Type)
+			applyTag(prop, f)
+			s.Properties[name] = prop
+			if f.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		sort.Strings(s.Required)
+		return s
+	case reflect.Slice:
+		// A nil slice is encoded as null.
+		return &Schema{Type: Types{"array", "null"}, Items: schemaOf(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	default:
+		panic(fmt.Sprintf("events: cannot describe %s in a schema", t))
+	}
+}
+
+func applyTag(s *Schema, f reflect.StructField) {
+	tag := f.Tag.Get("schema")
+	if tag == "" {
+		return
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "min":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				panic(fmt.Sprintf("events: invalid min on %s: %q", f.Name, value))
+			}
+			s.Minimum = &n
+		case "enum":
+			s.Enum = strings.Split(value, "|")
+		default:
+			panic(fmt.Sprintf("events: unknown schema option on %s: %q", f.Name, opt))
+		}
+	}
+}
+
+// Problem is one way in which a payload breaks its schema. Path locates the
+// value, e.g. "$.drifts[0].sku".
+type Problem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports every problem found in a payload.
+type ValidationError struct {
+	Contract Contract  `json:"-"`
+	Problems []Problem `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = p.Path + ": " + p.Message
+	}
+	return fmt.Sprintf("invalid %s event: %s", e.Contract, strings.Join(parts, "; "))
+}
+
+// Validate checks body against s; see the package Validate.
+func (s *Schema) Validate(c Contract, body []byte) error {
+	dec := json.NewDecoder(strings.NewReader(string(body)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return &ValidationError{Contract: c, Problems: []Problem{{Path: "$", Message: "invalid JSON: " + err.Error()}}}
+	}
+	if dec.More() {
+		return &ValidationError{Contract: c, Problems: []Problem{{Path: "$", Message: "invalid JSON: trailing data"}}}
+	}
+
+	var problems []Problem
+	s.check(v, "$", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Contract: c, Problems: problems}
+	}
+	return nil
+}
+
+func (s *Schema) check(v interface{}, path string, problems *[]Problem) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	jsonType := jsonTypeOf(v)
+	if len(s.Type) > 0 && !s.Type.allows(jsonType) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), jsonType)
+		return
+	}
+
+	switch v := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				report("expected an RFC 3339 date-time, got %q", v)
+			}
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, v) {
+			report("expected one of %s, got %q", strings.Join(s.Enum, ", "), v)
+		}
+	case json.Number:
+		if s.Minimum != nil {
+			if n, err := v.Float64(); err == nil && n < float64(*s.Minimum) {
+				report("must be at least %d, got %s", *s.Minimum, v)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, Problem{Path: path + "." + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value, ok := v[name]; ok {
+				s.Properties[name].check(value, path+"."+name, problems)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.check(item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
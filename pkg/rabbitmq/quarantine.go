@@ -0,0 +1,153 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+)
+
+// Events that fail validation against their contract in pkg/events, when
+// published or when consumed, are parked in the quarantine queue instead of
+// being sent, retried or dropped. A quarantined message keeps the body, type,
+// ID and headers it had; ReportHeader is added with the validation report.
+const (
+	QuarantineExchange = "events.quarantine"
+	QuarantineQueue    = "events.quarantine"
+	ReportHeader       = "x-validation-report"
+)
+
+// Where an invalid event was caught.
+const (
+	StagePublish = "publish"
+	StageConsume = "consume"
+)
+
+// SetupQuarantineQueue declares the quarantine exchange and queue. Every
+// service that publishes or consumes events declares them.
+func SetupQuarantineQueue(ch *amqp.Channel, log zerolog.Logger) error {
+	if err := ch.ExchangeDeclare(QuarantineExchange, "fanout", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := ch.QueueDeclare(QuarantineQueue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(QuarantineQueue, "", QuarantineExchange, false, nil); err != nil {
+		return err
+	}
+
+	log.Info().Str("queue", QuarantineQueue).Msg("✅ Quarantine queue setup complete")
+	return nil
+}
+
+// Report is the validation report of a quarantined event.
+type Report struct {
+	Service       string           `json:"service"`
+	Stage         string           `json:"stage"`
+	Queue         string           `json:"queue,omitempty"`
+	EventType     string           `json:"event_type"`
+	EventVersion  string           `json:"event_version"`
+	Error         string           `json:"error"`
+	Problems      []events.Problem `json:"problems,omitempty"`
+	QuarantinedAt time.Time        `json:"quarantined_at"`
+}
+
+// Quarantine validates events against their contracts and quarantines the
+// ones that do not conform.
+type Quarantine struct {
+	ch      *amqp.Channel
+	service string
+	log     zerolog.Logger
+}
+
+func NewQuarantine(ch *amqp.Channel, service string, log zerolog.Logger) *Quarantine {
+	return &Quarantine{ch: ch, service: service, log: log}
+}
+
+// Check validates a message consumed from queue against the contract of its
+// type and version. It returns false if the message is invalid and has been
+// quarantined; the caller then acks it. Event types without a contract are
+// left to the consumer. An error means the message could not be quarantined
+// and should be redelivered.
+func (q *Quarantine) Check(queue string, msg amqp.Delivery) (bool, error) {
+	version, _ := msg.Headers[events.VersionHeader].(string)
+	err := events.Validate(msg.Type, version, msg.Body)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, events.ErrUnknownEvent) {
+		if _, latestErr := events.Latest(msg.Type); latestErr != nil {
+			return true, nil
+		}
+	}
+
+	report := q.report(StageConsume, msg.Type, version, err)
+	report.Queue = queue
+	if err := q.publish(report, amqp.Publishing{
+		Headers:     msg.Headers,
+		ContentType: msg.ContentType,
+		MessageId:   msg.MessageId,
+		Timestamp:   msg.Timestamp,
+		Type:        msg.Type,
+		AppId:       msg.AppId,
+		Body:        msg.Body,
+	}); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Publish quarantines msg, which the service refused to publish because of
+// err.
+func (q *Quarantine) Publish(msg amqp.Publishing, err error) error {
+	version, _ := msg.Headers[events.VersionHeader].(string)
+	return q.publish(q.report(StagePublish, msg.Type, version, err), msg)
+}
+
+func (q *Quarantine) report(stage, eventType, version string, err error) Report {
+	if version == "" {
+		version = events.DefaultVersion
+	}
+	report := Report{
+		Service:       q.service,
+		Stage:         stage,
+		EventType:     eventType,
+		EventVersion:  version,
+		Error:         err.Error(),
+		QuarantinedAt: time.Now().UTC(),
+	}
+	var verr *events.ValidationError
+	if errors.As(err, &verr) {
+		report.Problems = verr.Problems
+	}
+	return report
+}
+
+func (q *Quarantine) publish(report Report, msg amqp.Publishing) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode validation report: %w", err)
+	}
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[ReportHeader] = string(data)
+	msg.Headers = headers
+
+	if err := q.ch.Publish(QuarantineExchange, msg.Type, false, false, msg); err != nil {
+		q.log.Error().Err(err).Str("event", msg.Type).Msg("Failed to quarantine invalid event")
+		return fmt.Errorf("failed to quarantine %s: %w", msg.Type, err)
+	}
+
+	q.log.Warn().
+		Str("event", msg.Type).
+		Str("stage", report.Stage).
+		Str("error", report.Error).
+		Msg("⚠️ Invalid event quarantined")
+	return nil
+}
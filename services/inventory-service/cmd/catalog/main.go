@@ -0,0 +1,119 @@
+// Command catalog imports and exports the product catalog directly against
+// the inventory database.
+//
+//	catalog import -file products.csv [-format csv|ndjson] [-dry-run]
+//	catalog export -format csv|ndjson [-out products.csv]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/catalog"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/config"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/database"
+	"github.com/cemrezr/ecommerce-system/pkg/logger"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	log := logger.NewLogger("inventory-catalog")
+	cfg := config.Load()
+
+	db := database.Connect(cfg.DBDSN, log)
+	defer db.Close()
+
+	repo := repository.NewPostgresProductRepository(db)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ExitOnError)
+		file := fs.String("file", "", "CSV or NDJSON file to import (- for stdin)")
+		formatName := fs.String("format", "", "csv or ndjson (default: from file extension)")
+		dryRun := fs.Bool("dry-run", false, "validate against the database without saving")
+		_ = fs.Parse(os.Args[2:])
+
+		if *file == "" {
+			log.Fatal().Msg("-file is required")
+		}
+		if *formatName == "" {
+			*formatName = filepath.Ext(*file)
+		}
+		format, err := catalog.ParseFormat(*formatName)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unknown import format")
+		}
+
+		var in io.Reader = os.Stdin
+		if *file != "-" {
+			f, err := os.Open(*file)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to open import file")
+			}
+			defer f.Close()
+			in = f
+		}
+
+		report, err := catalog.NewImporter(repo).Import(ctx, format, in, *dryRun)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Import aborted")
+		}
+		log.Info().
+			Bool("dry_run", *dryRun).
+			Int("created", report.Created).
+			Int("updated", report.Updated).
+			Int("failed", report.Failed).
+			Msg("Import finished")
+		if report.Failed > 0 {
+			os.Exit(2)
+		}
+
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		formatName := fs.String("format", "csv", "csv or ndjson")
+		out := fs.String("out", "-", "output file (- for stdout)")
+		_ = fs.Parse(os.Args[2:])
+
+		format, err := catalog.ParseFormat(*formatName)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unknown export format")
+		}
+
+		var w io.Writer = os.Stdout
+		if *out != "-" {
+			f, err := os.Create(*out)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to create export file")
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := catalog.Export(ctx, repo, format, w, nil); err != nil {
+			log.Fatal().Err(err).Msg("Export failed")
+		}
+		log.Info().Str("format", string(format)).Str("out", *out).Msg("Export finished")
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: catalog import -file <path> [-format csv|ndjson] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "       catalog export [-format csv|ndjson] [-out <path>]")
+	os.Exit(1)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/event"
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
 	"github.com/cemrezr/ecommerce-system/pkg/database"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
 	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
 	"github.com/rs/zerolog"
 )
@@ -20,6 +21,17 @@ func Run(cfg *config.Config, log zerolog.Logger) {
 
 	inventoryRepo := repository.NewPostgresInventoryRepository(db)
 	productRepo := repository.NewPostgresProductRepository(db)
+	reservationRepo := repository.NewPostgresReservationRepository(db)
+	warehouseRepo := repository.NewPostgresWarehouseRepository(db)
+	familyRepo := repository.NewPostgresFamilyRepository(db)
+	categoryRepo := repository.NewPostgresCategoryRepository(db)
+	stockAlertRepo := repository.NewPostgresStockAlertRepository(db)
+	outboxRepo := repository.NewPostgresOutboxRepository(db)
+	subscriptionRepo := repository.NewPostgresSubscriptionRepository(db)
+	purchaseOrderRepo := repository.NewPostgresPurchaseOrderRepository(db)
+	lotRepo := repository.NewPostgresLotRepository(db)
+	reconciliationRepo := repository.NewPostgresReconciliationRepository(db)
+	cycleCountRepo := repository.NewPostgresCycleCountRepository(db)
 
 	// RabbitMQ
 	conn, ch, err := rabbitmq.Connect(cfg.RabbitMQURL, log)
@@ -31,23 +43,57 @@ func Run(cfg *config.Config, log zerolog.Logger) {
 
 	// Setup queues for inventory
 	if err := rabbitmq.SetupBasicQueue(ch, cfg.RabbitMQExchange, cfg.RabbitMQQueue,
-		[]string{"order.created", "order.cancelled"}, log); err != nil {
+		[]string{events.TypeOrderCreated, events.TypeOrderCancelled}, log); err != nil {
 		log.Fatal().Err(err).Msg("Failed to setup inventory queue")
 	}
+	if err := rabbitmq.SetupQuarantineQueue(ch, log); err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup quarantine queue")
+	}
+	quarantine := rabbitmq.NewQuarantine(ch, "inventory-service", log)
 
-	// Start consumer
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	publisher := event.NewPublisher(ch, cfg.RabbitMQExchange, quarantine, log)
+
+	// Publish stock alerts and the events queued in the outbox
+	outboxRelay := event.NewOutboxRelay(stockAlertRepo, outboxRepo, publisher, cfg.StockAlertInterval, log)
+	go outboxRelay.Run(ctx)
+
+	// Start consumer
 	go func() {
-		consumer := event.NewConsumer(ch, cfg.RabbitMQQueue, inventoryRepo, log)
+		consumer := event.NewConsumer(ch, cfg.RabbitMQQueue, inventoryRepo, reservationRepo, outboxRelay, quarantine, log)
 		if err := consumer.StartConsuming(ctx); err != nil {
 			log.Fatal().Err(err).Msg("Inventory consumer failed")
 		}
 	}()
 
+	// Release expired reservations
+	sweeper := event.NewReservationSweeper(reservationRepo, outboxRelay, cfg.ReservationSweepInterval, log)
+	go sweeper.Run(ctx)
+
+	// Quarantine expired lots
+	lotSweeper := event.NewLotSweeper(lotRepo, outboxRelay, cfg.LotSweepInterval, log)
+	go lotSweeper.Run(ctx)
+
+	// Check stock against stock_logs
+	reconciler := event.NewReconciler(reconciliationRepo, outboxRelay, cfg.ReconcileInterval, log)
+	go reconciler.Run(ctx)
+
 	// Start HTTP
-	startHTTPServer(cfg.AppPort, productRepo, log)
+	startHTTPServer(cfg, serverDeps{
+		inventory:       inventoryRepo,
+		products:        productRepo,
+		families:        familyRepo,
+		categories:      categoryRepo,
+		reservations:    reservationRepo,
+		warehouses:      warehouseRepo,
+		subscriptions:   subscriptionRepo,
+		purchaseOrders:  purchaseOrderRepo,
+		lots:            lotRepo,
+		reconciliations: reconciliationRepo,
+		cycleCounts:     cycleCountRepo,
+	}, log)
 
 	// Wait for shutdown
 	sigs := make(chan os.Signal, 1)
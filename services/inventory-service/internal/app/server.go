@@ -3,25 +3,102 @@ package app
 import (
 	"net/http"
 
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/config"
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/handler"
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 )
 
-func startHTTPServer(port string, repo repository.ProductRepository, log zerolog.Logger) {
-	handler := handler.NewProductHandler(repo, log)
+type serverDeps struct {
+	inventory       repository.InventoryRepository
+	products        repository.ProductRepository
+	families        repository.FamilyRepository
+	categories      repository.CategoryRepository
+	reservations    repository.ReservationRepository
+	warehouses      repository.WarehouseRepository
+	subscriptions   repository.SubscriptionRepository
+	purchaseOrders  repository.PurchaseOrderRepository
+	lots            repository.LotRepository
+	reconciliations repository.ReconciliationRepository
+	cycleCounts     repository.CycleCountRepository
+}
+
+func startHTTPServer(cfg *config.Config, deps serverDeps, log zerolog.Logger) {
+	productHandler := handler.NewProductHandler(deps.products, log)
+	familyHandler := handler.NewFamilyHandler(deps.families, log)
+	categoryHandler := handler.NewCategoryHandler(deps.categories, log)
+	catalogHandler := handler.NewCatalogHandler(deps.products, log)
+	reservationHandler := handler.NewReservationHandler(deps.reservations, cfg.ReservationTTL, log)
+	warehouseHandler := handler.NewWarehouseHandler(deps.warehouses, log)
+	stockHandler := handler.NewStockHandler(deps.inventory, log)
+	subscriptionHandler := handler.NewSubscriptionHandler(deps.subscriptions, cfg.SubscriptionTTL, log)
+	purchaseOrderHandler := handler.NewPurchaseOrderHandler(deps.purchaseOrders, log)
+	lotHandler := handler.NewLotHandler(deps.lots, log)
+	reconciliationHandler := handler.NewReconciliationHandler(deps.reconciliations, log)
+	cycleCountHandler := handler.NewCycleCountHandler(deps.cycleCounts, log)
 
 	router := mux.NewRouter()
-	router.HandleFunc("/products", handler.CreateProduct).Methods("POST")
-	router.HandleFunc("/products", handler.ListProducts).Methods("GET")
-	router.HandleFunc("/products/{product_id}", handler.UpdateProduct).Methods("PUT")
-	router.HandleFunc("/products/{product_id}", handler.DeleteProduct).Methods("DELETE")
-	router.HandleFunc("/products/{id}", handler.GetProduct).Methods("GET")
+	router.HandleFunc("/products", productHandler.CreateProduct).Methods("POST")
+	router.HandleFunc("/products", productHandler.ListProducts).Methods("GET")
+	router.HandleFunc("/products/import", catalogHandler.ImportProducts).Methods("POST")
+	router.HandleFunc("/products/export", catalogHandler.ExportProducts).Methods("GET")
+	router.HandleFunc("/products/{product_id}", productHandler.UpdateProduct).Methods("PUT")
+	router.HandleFunc("/products/{product_id}", productHandler.PatchProduct).Methods("PATCH")
+	router.HandleFunc("/products/{product_id}", productHandler.DeleteProduct).Methods("DELETE")
+	router.HandleFunc("/products/{product_id}/restore", productHandler.RestoreProduct).Methods("POST")
+	router.HandleFunc("/products/{id}", productHandler.GetProduct).Methods("GET")
+	router.HandleFunc("/products/{product_id}/locations", warehouseHandler.GetStockByLocation).Methods("GET")
+	router.HandleFunc("/products/{product_id}/stock-adjustments", stockHandler.CreateAdjustment).Methods("POST")
+	router.HandleFunc("/products/{product_id}/stock-history", stockHandler.GetHistory).Methods("GET")
+	router.HandleFunc("/products/{product_id}/subscriptions", subscriptionHandler.Subscribe).Methods("POST")
+	router.HandleFunc("/products/{product_id}/subscriptions", subscriptionHandler.ListSubscriptions).Methods("GET")
+	router.HandleFunc("/products/{product_id}/subscriptions/{user_id}", subscriptionHandler.Unsubscribe).Methods("DELETE")
+	router.HandleFunc("/products/{product_id}/lots", lotHandler.ListLots).Methods("GET")
+	router.HandleFunc("/lots/{lot_id}/allocations", lotHandler.GetLotAllocations).Methods("GET")
+
+	router.HandleFunc("/categories", categoryHandler.CreateCategory).Methods("POST")
+	router.HandleFunc("/categories", categoryHandler.ListCategories).Methods("GET")
+
+	router.HandleFunc("/families", familyHandler.CreateFamily).Methods("POST")
+	router.HandleFunc("/families", familyHandler.ListFamilies).Methods("GET")
+	router.HandleFunc("/families/{family_id}", familyHandler.GetFamily).Methods("GET")
+	router.HandleFunc("/families/{family_id}", familyHandler.PatchFamily).Methods("PATCH")
+	router.HandleFunc("/families/{family_id}/variants", familyHandler.CreateVariant).Methods("POST")
+
+	router.HandleFunc("/reservations", reservationHandler.CreateReservation).Methods("POST")
+	router.HandleFunc("/reservations/{order_id}", reservationHandler.GetReservations).Methods("GET")
+	router.HandleFunc("/reservations/{order_id}/commit", reservationHandler.CommitReservation).Methods("POST")
+	router.HandleFunc("/reservations/{order_id}/release", reservationHandler.ReleaseReservation).Methods("POST")
+
+	router.HandleFunc("/warehouses", warehouseHandler.CreateWarehouse).Methods("POST")
+	router.HandleFunc("/warehouses", warehouseHandler.ListWarehouses).Methods("GET")
+	router.HandleFunc("/warehouses/{warehouse_id}/locations", warehouseHandler.CreateLocation).Methods("POST")
+	router.HandleFunc("/warehouses/{warehouse_id}/locations", warehouseHandler.ListLocations).Methods("GET")
+	router.HandleFunc("/stock-transfers", warehouseHandler.TransferStock).Methods("POST")
+
+	router.HandleFunc("/reconciliations", reconciliationHandler.RunReconciliation).Methods("POST")
+	router.HandleFunc("/reconciliations", reconciliationHandler.ListReconciliations).Methods("GET")
+	router.HandleFunc("/reconciliations/{run_id}", reconciliationHandler.GetReconciliation).Methods("GET")
+	router.HandleFunc("/cycle-counts", cycleCountHandler.OpenCycleCount).Methods("POST")
+	router.HandleFunc("/cycle-counts", cycleCountHandler.ListCycleCounts).Methods("GET")
+	router.HandleFunc("/cycle-counts/{cycle_count_id}", cycleCountHandler.GetCycleCount).Methods("GET")
+	router.HandleFunc("/cycle-counts/{cycle_count_id}/counts", cycleCountHandler.SubmitCounts).Methods("POST")
+	router.HandleFunc("/cycle-counts/{cycle_count_id}/post", cycleCountHandler.PostCycleCount).Methods("POST")
+	router.HandleFunc("/cycle-counts/{cycle_count_id}/cancel", cycleCountHandler.CancelCycleCount).Methods("POST")
+
+	router.HandleFunc("/suppliers", purchaseOrderHandler.CreateSupplier).Methods("POST")
+	router.HandleFunc("/suppliers", purchaseOrderHandler.ListSuppliers).Methods("GET")
+	router.HandleFunc("/purchase-orders", purchaseOrderHandler.CreatePurchaseOrder).Methods("POST")
+	router.HandleFunc("/purchase-orders", purchaseOrderHandler.ListPurchaseOrders).Methods("GET")
+	router.HandleFunc("/purchase-orders/{purchase_order_id}", purchaseOrderHandler.GetPurchaseOrder).Methods("GET")
+	router.HandleFunc("/purchase-orders/{purchase_order_id}/receipts", purchaseOrderHandler.ReceivePurchaseOrder).Methods("POST")
+	router.HandleFunc("/purchase-orders/{purchase_order_id}/cancel", purchaseOrderHandler.CancelPurchaseOrder).Methods("POST")
+	router.HandleFunc("/reorder-suggestions", purchaseOrderHandler.SuggestReorders).Methods("GET")
 
 	go func() {
-		log.Info().Str("addr", ":"+port).Msg("Starting HTTP server for product management")
-		if err := http.ListenAndServe(":"+port, router); err != nil {
+		log.Info().Str("addr", ":"+cfg.AppPort).Msg("Starting HTTP server for product management")
+		if err := http.ListenAndServe(":"+cfg.AppPort, router); err != nil {
 			log.Fatal().Err(err).Msg("Failed to start HTTP server")
 		}
 	}()
@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+)
+
+const exportPageSize = 200
+
+var csvColumns = []string{
+	"sku", "product_name", "description", "price_minor", "currency", "attributes", "status", "category_id",
+	"stock", "reorder_threshold", "reserved", "available", "stock_state", "product_id", "updated_at",
+}
+
+// ExportRecord is one exported product. The leading fields match Row, so an
+// export can be imported again; the stock figures are informational.
+type ExportRecord struct {
+	SKU         string          `json:"sku"`
+	ProductName string          `json:"product_name"`
+	Description string          `json:"description"`
+	PriceMinor  int64           `json:"price_minor"`
+	Currency    string          `json:"currency"`
+	Attributes  json.RawMessage `json:"attributes"`
+	Status      string          `json:"status"`
+	CategoryID  *int64          `json:"category_id"`
+	Stock       int             `json:"stock"`
+
+	ReorderThreshold int       `json:"reorder_threshold"`
+	Reserved         int       `json:"reserved"`
+	Available        int       `json:"available"`
+	StockState       string    `json:"stock_state"`
+	ProductID        int64     `json:"product_id"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Export streams every product that is not deleted to w, one page at a time,
+// so memory use does not grow with the catalog. flush, when set, is called
+// after each page.
+func Export(ctx context.Context, repo repository.ProductRepository, format Format, w io.Writer, flush func()) error {
+	var write func(p *repository.Product) error
+	var endPage func() error
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		write = func(p *repository.Product) error { return cw.Write(csvRecord(p)) }
+		endPage = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		write = func(p *repository.Product) error { return enc.Encode(exportRecord(p)) }
+		endPage = func() error { return nil }
+	default:
+		return ErrUnknownFormat
+	}
+
+	filter := repository.ProductFilter{Sort: "id", Limit: exportPageSize}
+	for {
+		page, err := repo.ListProducts(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to read products for export: %w", err)
+		}
+		for i := range page.Items {
+			if err := write(&page.Items[i]); err != nil {
+				return err
+			}
+		}
+		if err := endPage(); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func exportRecord(p *repository.Product) ExportRecord {
+	return ExportRecord{
+		SKU:         p.SKU,
+		ProductName: p.ProductName,
+		Description: p.Description,
+		PriceMinor:  p.PriceMinor,
+		Currency:    p.Currency,
+		Attributes:  p.Attributes,
+		Status:      p.Status,
+		CategoryID:  p.CategoryID,
+		Stock:       p.Stock,
+
+		ReorderThreshold: p.ReorderThreshold,
+		Reserved:         p.Reserved,
+		Available:        p.Available,
+		StockState:       p.StockState,
+		ProductID:        p.ProductID,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+func csvRecord(p *repository.Product) []string {
+	categoryID := ""
+	if p.CategoryID != nil {
+		categoryID = strconv.FormatInt(*p.CategoryID, 10)
+	}
+	return []string{
+		p.SKU,
+		p.ProductName,
+		p.Description,
+		strconv.FormatInt(p.PriceMinor, 10),
+		p.Currency,
+		string(p.Attributes),
+		p.Status,
+		categoryID,
+		strconv.Itoa(p.Stock),
+		strconv.Itoa(p.ReorderThreshold),
+		strconv.Itoa(p.Reserved),
+		strconv.Itoa(p.Available),
+		p.StockState,
+		strconv.FormatInt(p.ProductID, 10),
+		p.UpdatedAt.Format(time.RFC3339),
+	}
+}
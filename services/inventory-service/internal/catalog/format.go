@@ -0,0 +1,273 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("format must be csv or ndjson")
+	ErrMalformedInput = errors.New("malformed import file")
+)
+
+// maxLineBytes bounds a single NDJSON line; descriptions and attributes make
+// rows larger than bufio.Scanner's 64 KiB default possible.
+const maxLineBytes = 1 << 20
+
+// ParseFormat accepts a format name, a file extension or a Content-Type.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch s {
+	case "csv", ".csv", "text/csv":
+		return FormatCSV, nil
+	case "ndjson", ".ndjson", "jsonl", ".jsonl", "application/x-ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Row is one product in an import file. CSV columns and NDJSON keys use the
+// same names; unknown columns such as the export-only stock figures are
+// ignored so an export can be fed back in. Has reports which fields the row
+// actually carries, so an update leaves the others alone.
+type Row struct {
+	SKU         string          `json:"sku"`
+	ProductName string          `json:"product_name"`
+	Description string          `json:"description"`
+	PriceMinor  int64           `json:"price_minor"`
+	Currency    string          `json:"currency"`
+	Attributes  json.RawMessage `json:"attributes"`
+	Status      string          `json:"status"`
+	CategoryID  *int64          `json:"category_id"`
+	Stock       int             `json:"stock"`
+
+	ReorderThreshold int `json:"reorder_threshold"`
+
+	present map[string]bool
+}
+
+// Has reports whether the row sets the named field: a non-empty CSV cell or
+// an NDJSON key, including one that is null.
+func (r Row) Has(name string) bool {
+	return r.present[name]
+}
+
+func (r Row) Input() repository.ProductInput {
+	return repository.ProductInput{
+		SKU:         r.SKU,
+		ProductName: r.ProductName,
+		Description: r.Description,
+		PriceMinor:  r.PriceMinor,
+		Currency:    r.Currency,
+		Attributes:  r.Attributes,
+		Status:      r.Status,
+		CategoryID:  r.CategoryID,
+		Stock:       r.Stock,
+
+		ReorderThreshold: r.ReorderThreshold,
+	}
+}
+
+// Patch returns the fields of in that the row carries, for updating an
+// existing product. in is the row's normalized input. Stock is never part of
+// it: imports only set stock on products they create.
+func (r Row) Patch(in repository.ProductInput) repository.ProductPatch {
+	var patch repository.ProductPatch
+	if r.Has("product_name") {
+		patch.ProductName = &in.ProductName
+	}
+	if r.Has("description") {
+		patch.Description = &in.Description
+	}
+	if r.Has("price_minor") {
+		patch.PriceMinor = &in.PriceMinor
+	}
+	if r.Has("currency") {
+		patch.Currency = &in.Currency
+	}
+	if r.Has("attributes") {
+		patch.Attributes = in.Attributes
+	}
+	if r.Has("status") {
+		patch.Status = &in.Status
+	}
+	if r.Has("category_id") {
+		patch.CategoryID = in.CategoryID
+		patch.ClearCategory = in.CategoryID == nil
+	}
+	if r.Has("reorder_threshold") {
+		patch.ReorderThreshold = &in.ReorderThreshold
+	}
+	return patch
+}
+
+// RowFunc receives each decoded row with its 1-based line number. err is set
+// when the row itself could not be parsed; returning an error stops decoding.
+type RowFunc func(line int, row Row, err error) error
+
+// DecodeRows streams rows from r. Only errors that make the rest of the file
+// unreadable are returned; per-row problems are passed to fn.
+func DecodeRows(format Format, r io.Reader, fn RowFunc) error {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r, fn)
+	case FormatNDJSON:
+		return decodeNDJSON(r, fn)
+	}
+	return ErrUnknownFormat
+}
+
+func decodeCSV(r io.Reader, fn RowFunc) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: failed to read csv header: %v", ErrMalformedInput, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["sku"]; !ok {
+		return fmt.Errorf("%w: csv header must contain a sku column", ErrMalformedInput)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return fmt.Errorf("%w: failed to read csv: %v", ErrMalformedInput, err)
+			}
+			if err := fn(parseErr.Line, Row{}, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, err := csvRow(columns, record)
+		if err := fn(line, row, err); err != nil {
+			return err
+		}
+	}
+}
+
+func csvRow(columns map[string]int, record []string) (Row, error) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	row := Row{
+		SKU:         get("sku"),
+		ProductName: get("product_name"),
+		Description: get("description"),
+		Currency:    get("currency"),
+		Status:      get("status"),
+		present:     make(map[string]bool, len(columns)),
+	}
+	for name := range columns {
+		if strings.TrimSpace(get(name)) != "" {
+			row.present[name] = true
+		}
+	}
+	if v := strings.TrimSpace(get("attributes")); v != "" {
+		row.Attributes = json.RawMessage(v)
+	}
+
+	var err error
+	if v := strings.TrimSpace(get("price_minor")); v != "" {
+		if row.PriceMinor, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return row, fmt.Errorf("price_minor: %q is not an integer", v)
+		}
+	}
+	if v := strings.TrimSpace(get("stock")); v != "" {
+		if row.Stock, err = strconv.Atoi(v); err != nil {
+			return row, fmt.Errorf("stock: %q is not an integer", v)
+		}
+	}
+	if v := strings.TrimSpace(get("reorder_threshold")); v != "" {
+		if row.ReorderThreshold, err = strconv.Atoi(v); err != nil {
+			return row, fmt.Errorf("reorder_threshold: %q is not an integer", v)
+		}
+	}
+	if v := strings.TrimSpace(get("category_id")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return row, fmt.Errorf("category_id: %q is not an integer", v)
+		}
+		row.CategoryID = &id
+	}
+	return row, nil
+}
+
+func decodeNDJSON(r io.Reader, fn RowFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		row, err := ndjsonRow([]byte(raw))
+		if err := fn(line, row, err); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: failed to read ndjson at line %d: %v", ErrMalformedInput, line+1, err)
+	}
+	return nil
+}
+
+func ndjsonRow(raw []byte) (Row, error) {
+	var row Row
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return row, err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return row, err
+	}
+	row.present = make(map[string]bool, len(keys))
+	for name := range keys {
+		row.present[name] = true
+	}
+	return row, nil
+}
@@ -0,0 +1,46 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRowPatchOnlyCarriesPresentFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{"csv", FormatCSV, "sku,price_minor,description,stock\nmug-1,1299,,40\n"},
+		{"ndjson", FormatNDJSON, `{"sku":"mug-1","price_minor":1299,"stock":40,"category_id":null}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []Row
+			err := DecodeRows(tt.format, strings.NewReader(tt.input), func(_ int, row Row, err error) error {
+				if err != nil {
+					t.Fatalf("row: %v", err)
+				}
+				rows = append(rows, row)
+				return nil
+			})
+			if err != nil || len(rows) != 1 {
+				t.Fatalf("DecodeRows = %v, %d rows", err, len(rows))
+			}
+
+			in := rows[0].Input()
+			NormalizeProduct(&in)
+			patch := rows[0].Patch(in)
+			if patch.PriceMinor == nil || *patch.PriceMinor != 1299 {
+				t.Errorf("PriceMinor = %v, want 1299", patch.PriceMinor)
+			}
+			if patch.ProductName != nil || patch.Description != nil || patch.Currency != nil ||
+				patch.Status != nil || patch.Attributes != nil || patch.ReorderThreshold != nil {
+				t.Errorf("patch sets fields the row does not carry: %+v", patch)
+			}
+			if wantClear := tt.format == FormatNDJSON; patch.ClearCategory != wantClear {
+				t.Errorf("ClearCategory = %v, want %v", patch.ClearCategory, wantClear)
+			}
+		})
+	}
+}
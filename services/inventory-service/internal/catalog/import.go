@@ -0,0 +1,138 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+)
+
+// maxReportedErrors caps the per-row errors kept in a report so a badly
+// broken file cannot produce an unbounded response.
+const maxReportedErrors = 1000
+
+type RowError struct {
+	Line   int               `json:"line"`
+	SKU    string            `json:"sku,omitempty"`
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool       `json:"dry_run"`
+	Total   int        `json:"total"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+type Importer struct {
+	Repo repository.ProductRepository
+}
+
+func NewImporter(repo repository.ProductRepository) *Importer {
+	return &Importer{Repo: repo}
+}
+
+// Import upserts every row of r by SKU. A new SKU is created from the whole
+// row; an existing product only gets the fields the row carries, and never
+// its stock. Rows are applied independently, so a bad row is reported and
+// skipped without affecting the others. An error is
+// returned only when the input or the database fails, and the report still
+// covers the rows handled up to that point.
+func (i *Importer) Import(ctx context.Context, format Format, r io.Reader, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Errors: []RowError{}}
+	seen := make(map[string]int)
+
+	err := DecodeRows(format, r, func(line int, row Row, parseErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		report.Total++
+
+		if parseErr != nil {
+			report.fail(RowError{Line: line, SKU: row.SKU, Error: parseErr.Error()})
+			return nil
+		}
+
+		in := row.Input()
+		NormalizeProduct(&in)
+		fields := ValidateProduct(in, true)
+		if rowFields := presentFields(row, fields); rowFields != nil {
+			report.fail(RowError{Line: line, SKU: in.SKU, Error: "validation failed", Fields: rowFields})
+			return nil
+		}
+		if first, ok := seen[in.SKU]; ok {
+			report.fail(RowError{Line: line, SKU: in.SKU, Error: fmt.Sprintf("sku already appears on line %d", first)})
+			return nil
+		}
+		seen[in.SKU] = line
+
+		// A row may leave out fields when it updates an existing product, but
+		// it can only create one when it is valid as a whole.
+		var create *repository.ProductInput
+		if fields == nil {
+			create = &in
+		}
+		_, created, err := i.Repo.UpsertProductBySKU(ctx, in.SKU, create, row.Patch(in), dryRun)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			report.fail(RowError{Line: line, SKU: in.SKU, Error: "sku is new and the row is not a complete product", Fields: fields})
+			return nil
+		}
+		if err != nil {
+			if msg, ok := rowErrorMessage(err); ok {
+				report.fail(RowError{Line: line, SKU: in.SKU, Error: msg})
+				return nil
+			}
+			return err
+		}
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		return nil
+	})
+	return report, err
+}
+
+// presentFields keeps the validation errors for the sku and the fields the
+// row carries; the rest only matter when the row creates a product.
+func presentFields(row Row, fields map[string]string) map[string]string {
+	var kept map[string]string
+	for name, msg := range fields {
+		if name != "sku" && !row.Has(name) {
+			continue
+		}
+		if kept == nil {
+			kept = make(map[string]string)
+		}
+		kept[name] = msg
+	}
+	return kept
+}
+
+func (r *ImportReport) fail(e RowError) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, e)
+	}
+}
+
+// rowErrorMessage turns repository errors caused by the row's content into a
+// message for the report. Other errors (e.g. the database going away) abort
+// the import.
+func rowErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return "category does not exist", true
+	case errors.Is(err, repository.ErrProductDeleted):
+		return "sku belongs to a deleted product; restore it first", true
+	case errors.Is(err, repository.ErrDuplicateSKU):
+		return "sku was created concurrently; retry the row", true
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+// Package catalog holds product catalog rules shared by the HTTP handlers and
+// the bulk import/export tooling.
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+)
+
+const MaxDescriptionLength = 4000
+
+var (
+	SKUPattern      = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]{0,63}$`)
+	CurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// NormalizeProduct trims and upper-cases identifiers and fills defaults so
+// that validation and storage see the same values.
+func NormalizeProduct(in *repository.ProductInput) {
+	in.SKU = strings.ToUpper(strings.TrimSpace(in.SKU))
+	in.ProductName = strings.TrimSpace(in.ProductName)
+	in.Description = strings.TrimSpace(in.Description)
+	in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+	if in.Currency == "" {
+		in.Currency = "USD"
+	}
+	if in.Status == "" {
+		in.Status = repository.ProductStatusActive
+	}
+	if len(bytes.TrimSpace(in.Attributes)) == 0 || string(bytes.TrimSpace(in.Attributes)) == "null" {
+		in.Attributes = json.RawMessage(`{}`)
+	}
+}
+
+// ValidateProduct returns a message per invalid field, or nil when the input
+// is acceptable. requireSKU is false for updates, where the SKU is fixed.
+func ValidateProduct(in repository.ProductInput, requireSKU bool) map[string]string {
+	errs := make(map[string]string)
+
+	if requireSKU && !SKUPattern.MatchString(in.SKU) {
+		errs["sku"] = "sku is required and may contain only letters, digits, '.', '_' and '-' (max 64)"
+	}
+	if in.ProductName == "" {
+		errs["product_name"] = "product_name is required"
+	}
+	if len(in.Description) > MaxDescriptionLength {
+		errs["description"] = "description must be at most 4000 characters"
+	}
+	if in.PriceMinor < 0 {
+		errs["price_minor"] = "price_minor must not be negative"
+	}
+	if !CurrencyPattern.MatchString(in.Currency) {
+		errs["currency"] = "currency must be a 3-letter ISO 4217 code"
+	}
+	if !IsJSONObject(in.Attributes) {
+		errs["attributes"] = "attributes must be a JSON object"
+	}
+	if !IsValidStatus(in.Status) {
+		errs["status"] = "status must be active or archived"
+	}
+	if in.Stock < 0 {
+		errs["stock"] = "stock must not be negative"
+	}
+	if in.ReorderThreshold < 0 {
+		errs["reorder_threshold"] = "reorder_threshold must not be negative"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func IsValidStatus(status string) bool {
+	return status == repository.ProductStatusActive || status == repository.ProductStatusArchived
+}
+
+func IsJSONObject(raw json.RawMessage) bool {
+	var obj map[string]interface{}
+	return json.Unmarshal(raw, &obj) == nil && obj != nil
+}
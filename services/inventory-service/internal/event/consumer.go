@@ -2,23 +2,36 @@ package event
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"time"
 
-	"github.com/cemrezr/ecommerce-system/inventory-service/internal/model"
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
 	"github.com/rs/zerolog"
 	"github.com/streadway/amqp"
 )
 
 type Consumer struct {
-	ch    *amqp.Channel
-	queue string
-	repo  repository.InventoryRepository
-	log   zerolog.Logger
+	ch           *amqp.Channel
+	queue        string
+	repo         repository.InventoryRepository
+	reservations repository.ReservationRepository
+	outbox       *OutboxRelay
+	quarantine   *rabbitmq.Quarantine
+	log          zerolog.Logger
 }
 
-func NewConsumer(ch *amqp.Channel, queue string, repo repository.InventoryRepository, log zerolog.Logger) *Consumer {
-	return &Consumer{ch: ch, queue: queue, repo: repo, log: log}
+func NewConsumer(
+	ch *amqp.Channel,
+	queue string,
+	repo repository.InventoryRepository,
+	reservations repository.ReservationRepository,
+	outbox *OutboxRelay,
+	quarantine *rabbitmq.Quarantine,
+	log zerolog.Logger,
+) *Consumer {
+	return &Consumer{ch: ch, queue: queue, repo: repo, reservations: reservations, outbox: outbox, quarantine: quarantine, log: log}
 }
 
 func (c *Consumer) StartConsuming(ctx context.Context) error {
@@ -42,95 +55,152 @@ func (c *Consumer) StartConsuming(ctx context.Context) error {
 		for msg := range msgs {
 			c.log.Debug().Str("type", msg.Type).Msg("Received message")
 
+			// Invalid events are quarantined with their validation report;
+			// redelivering them would not make them valid.
+			valid, err := c.quarantine.Check(c.queue, msg)
+			if err != nil {
+				c.log.Error().Err(err).Str("type", msg.Type).Msg("Failed to quarantine invalid event — NACKing for retry")
+				_ = msg.Nack(false, true)
+				continue
+			}
+			if !valid {
+				_ = msg.Ack(false)
+				continue
+			}
+
 			switch msg.Type {
 
-			case "order.created":
-				var order model.Order
-				if err := json.Unmarshal(msg.Body, &order); err != nil {
+			case events.TypeOrderCreated:
+				order, err := events.DecodeAs[events.OrderCreated](msg.Type, eventVersion(msg), msg.Body)
+				if err != nil {
 					c.log.Error().Err(err).Msg("Failed to parse order.created payload")
 					_ = msg.Nack(false, false)
 					continue
 				}
 
-				if c.repo.HasAlreadyProcessed(order.ID, order.ProductID, "order.created") {
+				_, err = c.reservations.Commit(ctx, order.ID)
+				switch {
+				case err == nil:
+					c.log.Info().Int64("order_id", order.ID).Msg("Reservation committed for order.created")
+					c.outbox.Wake()
+					_ = msg.Ack(false)
+					continue
+				case errors.Is(err, repository.ErrAlreadyProcessed):
 					c.log.Warn().Int64("order_id", order.ID).Msg("💡 Duplicate order detected — skipping")
 					_ = msg.Ack(false)
 					continue
+				case isFulfilmentFailure(err):
+					// The rejected order will not take its stock, so its hold
+					// is dropped; if that fails, the sweeper expires it.
+					if c.rejectOrder(ctx, msg, order, err) {
+						if _, err := c.reservations.Release(ctx, order.ID); err != nil {
+							c.log.Warn().Err(err).Int64("order_id", order.ID).Msg("Failed to release reservation of rejected order")
+						}
+					}
+					continue
+				case !errors.Is(err, repository.ErrReservationNotFound):
+					c.log.Error().Err(err).Msg("Failed to commit reservation — NACKing for retry")
+					_ = msg.Nack(false, true)
+					continue
 				}
 
+				itemID := order.ItemID()
 				c.log.Info().
 					Str("event", msg.Type).
-					Int64("product_id", order.ProductID).
+					Int64("variant_id", itemID).
 					Int("quantity", order.Quantity).
 					Msg("Decreasing stock for order.created")
 
-				if err := c.repo.DecreaseStock(ctx, order.ProductID, order.Quantity); err != nil {
+				allocations, err := c.repo.FulfilOrder(ctx, order.ID, itemID, order.Quantity)
+				if errors.Is(err, repository.ErrAlreadyProcessed) {
+					c.log.Warn().Int64("order_id", order.ID).Msg("💡 Duplicate order detected — skipping")
+					_ = msg.Ack(false)
+					continue
+				}
+				if isFulfilmentFailure(err) {
+					c.rejectOrder(ctx, msg, order, err)
+					continue
+				}
+				if err != nil {
 					c.log.Error().Err(err).Msg("Failed to decrease stock — NACKing for retry")
 					_ = msg.Nack(false, true)
 					continue
 				}
 
-				if err := c.repo.LogStockChange(ctx, order.ProductID, -order.Quantity, "order.created", &order.ID); err != nil {
-					c.log.Warn().Err(err).Msg("Failed to log stock change — proceeding anyway")
-				}
-
 				c.log.Info().
-					Int64("product_id", order.ProductID).
+					Int64("variant_id", itemID).
 					Int("quantity", order.Quantity).
+					Int("allocations", len(allocations)).
 					Msg("Stock decreased successfully")
 
+				c.outbox.Wake()
 				_ = msg.Ack(false)
 
-			case "order.cancelled":
-				var payload struct {
-					OrderID   int64 `json:"id"`
-					ProductID int64 `json:"product_id"`
-					Quantity  int   `json:"quantity"`
-				}
-
-				if err := json.Unmarshal(msg.Body, &payload); err != nil {
+			case events.TypeOrderCancelled:
+				payload, err := events.DecodeAs[events.OrderCancelled](msg.Type, eventVersion(msg), msg.Body)
+				if err != nil {
 					c.log.Error().Err(err).Msg("Failed to parse order.cancelled payload")
 					_ = msg.Nack(false, false)
 					continue
 				}
+				itemID := payload.ItemID()
 
-				if !c.repo.HasOrderCreatedLog(payload.OrderID, payload.ProductID) {
-					c.log.Warn().
-						Int64("order_id", payload.OrderID).
-						Int64("product_id", payload.ProductID).
-						Msg("Cancelled event received without a matching order.created log — skipping")
+				_, err = c.reservations.Release(ctx, payload.ID)
+				if err == nil {
+					c.log.Info().Int64("order_id", payload.ID).Msg("Reservation released for cancelled order")
 					_ = msg.Ack(false)
 					continue
 				}
+				if !errors.Is(err, repository.ErrReservationNotFound) {
+					c.log.Error().Err(err).Msg("Failed to release reservation — NACKing for retry")
+					_ = msg.Nack(false, true)
+					continue
+				}
 
-				if c.repo.HasAlreadyProcessed(payload.OrderID, payload.ProductID, "order.cancelled") {
-					c.log.Warn().Int64("order_id", payload.OrderID).Msg("💡 Duplicate cancelled order detected — skipping")
+				if !c.repo.HasOrderCreatedLog(payload.ID, itemID) {
+					c.log.Warn().
+						Int64("order_id", payload.ID).
+						Int64("variant_id", itemID).
+						Msg("Cancelled event received without a matching order.created log — skipping")
 					_ = msg.Ack(false)
 					continue
 				}
 
 				c.log.Info().
 					Str("event", msg.Type).
-					Int64("order_id", payload.OrderID).
-					Int64("product_id", payload.ProductID).
+					Int64("order_id", payload.ID).
+					Int64("variant_id", itemID).
 					Int("quantity", payload.Quantity).
 					Msg("Restoring stock for cancelled order")
 
-				if err := c.repo.IncreaseStock(ctx, payload.ProductID, payload.Quantity); err != nil {
+				err = c.repo.RestoreOrder(ctx, payload.ID, itemID, payload.Quantity)
+				if errors.Is(err, repository.ErrAlreadyProcessed) {
+					c.log.Warn().Int64("order_id", payload.ID).Msg("💡 Duplicate cancelled order detected — skipping")
+					_ = msg.Ack(false)
+					continue
+				}
+				if errors.Is(err, repository.ErrInsufficientStock) {
+					// Adding stock only fails this way when the inventory row is gone
+					// (hard-deleted before soft delete existed); retrying cannot help.
+					c.log.Error().
+						Int64("order_id", payload.ID).
+						Int64("variant_id", itemID).
+						Msg("Product missing for cancelled order — rejecting without requeue")
+					_ = msg.Nack(false, false)
+					continue
+				}
+				if err != nil {
 					c.log.Error().Err(err).Msg("Failed to increase stock — NACKing for retry")
 					_ = msg.Nack(false, true)
 					continue
 				}
 
-				if err := c.repo.LogStockChange(ctx, payload.ProductID, payload.Quantity, "order.cancelled", &payload.OrderID); err != nil {
-					c.log.Warn().Err(err).Msg("⚠ Failed to log stock change for order.cancelled")
-				}
-
 				c.log.Info().
-					Int64("product_id", payload.ProductID).
+					Int64("variant_id", itemID).
 					Int("quantity", payload.Quantity).
 					Msg("Stock restored successfully")
 
+				c.outbox.Wake()
 				_ = msg.Ack(false)
 
 			default:
@@ -144,3 +214,57 @@ func (c *Consumer) StartConsuming(ctx context.Context) error {
 	c.log.Info().Msg("Consumer shutdown initiated")
 	return nil
 }
+
+// eventVersion is the version the message was published as; messages from
+// before versioning have none.
+func eventVersion(msg amqp.Delivery) string {
+	version, _ := msg.Headers[events.VersionHeader].(string)
+	return version
+}
+
+// rejectOrder handles an order.created whose stock cannot be taken, err
+// being a fulfilment failure. Redelivering the order would fail the same
+// way, so it is rejected without requeue and the failure published for the
+// order's owner. Only if the failure cannot be recorded is it retried, and
+// rejectOrder returns false.
+func (c *Consumer) rejectOrder(ctx context.Context, msg amqp.Delivery, order events.OrderCreated, err error) bool {
+	reason, _ := fulfilmentFailureReason(err)
+	c.log.Error().
+		Err(err).
+		Int64("order_id", order.ID).
+		Int64("variant_id", order.ItemID()).
+		Int("quantity", order.Quantity).
+		Msg("Cannot fulfil order — rejecting without requeue")
+	if err := c.repo.RecordFulfilmentFailure(ctx, events.FulfilmentFailed{
+		OrderID:   order.ID,
+		ProductID: order.ItemID(),
+		Quantity:  order.Quantity,
+		Reason:    reason,
+		FailedAt:  time.Now().UTC(),
+	}); err != nil {
+		c.log.Error().Err(err).Int64("order_id", order.ID).Msg("Failed to record fulfilment failure — NACKing for retry")
+		_ = msg.Nack(false, true)
+		return false
+	}
+	c.outbox.Wake()
+	_ = msg.Nack(false, false)
+	return true
+}
+
+func isFulfilmentFailure(err error) bool {
+	_, ok := fulfilmentFailureReason(err)
+	return ok
+}
+
+// fulfilmentFailureReason reports whether err from FulfilOrder or a
+// reservation commit is permanent for the order, and names it for inventory.fulfilment_failed.
+func fulfilmentFailureReason(err error) (string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return "insufficient_stock", true
+	case errors.Is(err, repository.ErrLotExpired):
+		return "lot_expired", true
+	default:
+		return "", false
+	}
+}
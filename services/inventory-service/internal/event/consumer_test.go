@@ -0,0 +1,30 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+)
+
+func TestFulfilmentFailureReason(t *testing.T) {
+	tests := []struct {
+		err       error
+		reason    string
+		permanent bool
+	}{
+		{repository.ErrInsufficientStock, "insufficient_stock", true},
+		{fmt.Errorf("fulfil order 1042: %w", repository.ErrInsufficientStock), "insufficient_stock", true},
+		{repository.ErrLotExpired, "lot_expired", true},
+		{fmt.Errorf("commit reservation for order 1042: %w", repository.ErrLotExpired), "lot_expired", true},
+		{errors.New("connection reset by peer"), "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		reason, permanent := fulfilmentFailureReason(tt.err)
+		if reason != tt.reason || permanent != tt.permanent {
+			t.Errorf("fulfilmentFailureReason(%v) = %q, %v; want %q, %v", tt.err, reason, permanent, tt.reason, tt.permanent)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// LotSweeper quarantines lots once they pass their expiry date, taking their
+// remaining units out of stock.
+type LotSweeper struct {
+	repo     repository.LotRepository
+	outbox   *OutboxRelay
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func NewLotSweeper(repo repository.LotRepository, outbox *OutboxRelay, interval time.Duration, log zerolog.Logger) *LotSweeper {
+	return &LotSweeper{repo: repo, outbox: outbox, interval: interval, log: log}
+}
+
+func (s *LotSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.log.Info().Dur("interval", s.interval).Msg("Lot sweeper started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info().Msg("Lot sweeper stopped")
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+// sweep walks the expired lots by ID so that lots deferred because of open
+// reservations are skipped for the rest of this pass instead of refetched.
+func (s *LotSweeper) sweep(ctx context.Context) {
+	var afterID int64
+	for {
+		quarantined, deferred, err := s.repo.QuarantineExpired(ctx, afterID, sweepBatchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to quarantine expired lots")
+			return
+		}
+
+		for _, lot := range deferred {
+			s.log.Warn().
+				Int64("lot_id", lot.ID).
+				Int64("product_id", lot.ProductID).
+				Str("lot_number", lot.LotNumber).
+				Msg("Expired lot still needed by reservations — quarantine deferred")
+			afterID = max(afterID, lot.ID)
+		}
+
+		for _, lot := range quarantined {
+			s.log.Warn().
+				Int64("lot_id", lot.ID).
+				Int64("product_id", lot.ProductID).
+				Str("lot_number", lot.LotNumber).
+				Int("quantity", lot.Quantity).
+				Msg("🧪 Expired lot quarantined")
+			afterID = max(afterID, lot.ID)
+		}
+
+		if len(quarantined) > 0 {
+			s.outbox.Wake()
+		}
+		if len(quarantined)+len(deferred) < sweepBatchSize {
+			return
+		}
+	}
+}
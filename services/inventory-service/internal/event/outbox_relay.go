@@ -0,0 +1,110 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/rs/zerolog"
+)
+
+const outboxBatchSize = 100
+
+// OutboxRelay publishes the events queued by stock changes: the stock alerts
+// in stock_alerts and every other event in event_outbox. They are written in
+// the same transaction as the change, so every path that moves stock is
+// covered and an event survives a failed publish. Each row is one event, so
+// a failed publish retries only that event.
+type OutboxRelay struct {
+	alerts    repository.StockAlertRepository
+	outbox    repository.OutboxRepository
+	publisher *Publisher
+	interval  time.Duration
+	wake      chan struct{}
+	log       zerolog.Logger
+}
+
+func NewOutboxRelay(alerts repository.StockAlertRepository, outbox repository.OutboxRepository, publisher *Publisher, interval time.Duration, log zerolog.Logger) *OutboxRelay {
+	return &OutboxRelay{alerts: alerts, outbox: outbox, publisher: publisher, interval: interval, wake: make(chan struct{}, 1), log: log}
+}
+
+// Wake asks the relay to publish now instead of at the next tick. It never
+// blocks; wake-ups arriving while one is pending are merged.
+func (s *OutboxRelay) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.log.Info().Dur("interval", s.interval).Msg("Outbox relay started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info().Msg("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			s.relay(ctx)
+		case <-s.wake:
+			s.relay(ctx)
+		}
+	}
+}
+
+func (s *OutboxRelay) relay(ctx context.Context) {
+	s.relayAlerts(ctx)
+	s.relayOutbox(ctx)
+}
+
+func (s *OutboxRelay) relayAlerts(ctx context.Context) {
+	for {
+		n, err := s.alerts.PublishPending(ctx, outboxBatchSize, func(alert repository.StockAlert) error {
+			s.log.Info().
+				Int64("product_id", alert.ProductID).
+				Str("from", alert.PreviousState).
+				Str("to", alert.State).
+				Int("stock", alert.Stock).
+				Int("threshold", alert.Threshold).
+				Msg("📉 Stock state changed")
+
+			return s.publisher.Publish(alert.EventType(), MessageID("stock_alerts", alert.ID), events.StockAlert{
+				ProductID:     alert.ProductID,
+				SKU:           alert.SKU,
+				ProductName:   alert.ProductName,
+				State:         alert.State,
+				PreviousState: alert.PreviousState,
+				Stock:         alert.Stock,
+				Threshold:     alert.Threshold,
+				OccurredAt:    alert.CreatedAt,
+			})
+		})
+		if err != nil {
+			s.log.Warn().Err(err).Int("relayed", n).Msg("Failed to relay stock alerts — retrying on next tick")
+			return
+		}
+		if n < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (s *OutboxRelay) relayOutbox(ctx context.Context) {
+	for {
+		n, err := s.outbox.PublishPending(ctx, outboxBatchSize, func(e repository.OutboxEvent) error {
+			return s.publisher.PublishEncoded(e.EventType, e.EventVersion, MessageID("event_outbox", e.ID), e.Payload)
+		})
+		if err != nil {
+			s.log.Warn().Err(err).Int("relayed", n).Msg("Failed to relay outbox events — retrying on next tick")
+			return
+		}
+		if n < outboxBatchSize {
+			return
+		}
+	}
+}
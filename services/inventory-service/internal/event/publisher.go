@@ -0,0 +1,76 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+)
+
+type Publisher struct {
+	ch         *amqp.Channel
+	exchange   string
+	quarantine *rabbitmq.Quarantine
+	log        zerolog.Logger
+}
+
+func NewPublisher(ch *amqp.Channel, exchange string, quarantine *rabbitmq.Quarantine, log zerolog.Logger) *Publisher {
+	return &Publisher{ch: ch, exchange: exchange, quarantine: quarantine, log: log}
+}
+
+// Publish sends payload, the latest version of eventType in pkg/events, as
+// message messageID. A payload that does not match the event's schema is
+// quarantined instead of sent, and Publish fails.
+func (p *Publisher) Publish(eventType, messageID string, payload interface{}) error {
+	body, version, err := events.Encode(eventType, payload)
+	return p.send(eventType, version, messageID, body, err)
+}
+
+// PublishEncoded sends body, an event already encoded as version of
+// eventType, such as one queued in the outbox. It is validated like a payload
+// given to Publish.
+func (p *Publisher) PublishEncoded(eventType, version, messageID string, body []byte) error {
+	return p.send(eventType, version, messageID, body, events.Validate(eventType, version, body))
+}
+
+// send publishes body unless encoding or validating it failed with err.
+func (p *Publisher) send(eventType, version, messageID string, body []byte, err error) error {
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+		Type:        eventType,
+		MessageId:   messageID,
+		Timestamp:   time.Now(),
+		Headers:     amqp.Table{events.VersionHeader: version},
+	}
+	var verr *events.ValidationError
+	if errors.As(err, &verr) {
+		p.log.Error().Err(err).Str("event", eventType).Msg("Refusing to publish invalid event")
+		_ = p.quarantine.Publish(msg, err)
+		return err
+	}
+	if err != nil {
+		p.log.Error().Err(err).Str("event", eventType).Msg("Refusing to publish event")
+		return err
+	}
+
+	err = p.ch.Publish(p.exchange, eventType, false, false, msg)
+	if err != nil {
+		p.log.Error().Err(err).Str("event", eventType).Msg("Failed to publish event")
+		return fmt.Errorf("failed to publish %s: %w", eventType, err)
+	}
+
+	p.log.Info().Str("event", eventType).Msg("Published successfully")
+	return nil
+}
+
+// MessageID identifies an event by the row it was published from, so that an
+// event published again, after a failed commit or a retry, keeps the ID of
+// the original and consumers can recognise it as a duplicate.
+func MessageID(table string, id int64) string {
+	return fmt.Sprintf("inventory-service/%s/%d", table, id)
+}
@@ -0,0 +1,53 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+)
+
+// TestPublishedEventsHaveContracts checks every event type this service
+// publishes, directly or through the outbox. Publish and the outbox refuse
+// event types without a contract in pkg/events, so such an event would never
+// be sent.
+func TestPublishedEventsHaveContracts(t *testing.T) {
+	published := []string{
+		events.TypeLowStock,
+		events.TypeOutOfStock,
+		events.TypeRestocked,
+		events.TypeBackInStock,
+		events.TypeReservationExpired,
+		events.TypeLotQuarantined,
+		events.TypeStockDrift,
+		events.TypeStockTransferred,
+		events.TypeReceived,
+		events.TypeStockAdjusted,
+		events.TypeFulfilmentFailed,
+	}
+	for _, eventType := range published {
+		if _, err := events.Latest(eventType); err != nil {
+			t.Errorf("%s has no contract: %v", eventType, err)
+		}
+	}
+
+	// Stock alerts pick their event type from the state they moved to.
+	for _, state := range []string{repository.StockStateInStock, repository.StockStateLowStock, repository.StockStateOutOfStock} {
+		alert := repository.StockAlert{State: state}
+		if _, err := events.Latest(alert.EventType()); err != nil {
+			t.Errorf("stock alert to %s publishes %s, which has no contract: %v", state, alert.EventType(), err)
+		}
+	}
+}
+
+func TestMessageIDIdentifiesSourceRow(t *testing.T) {
+	if got, want := MessageID("stock_alerts", 42), "inventory-service/stock_alerts/42"; got != want {
+		t.Errorf("MessageID = %q, want %q", got, want)
+	}
+	if MessageID("stock_alerts", 42) != MessageID("stock_alerts", 42) {
+		t.Error("MessageID differs for the same row")
+	}
+	if MessageID("stock_alerts", 42) == MessageID("event_outbox", 42) {
+		t.Error("MessageID is the same for rows of different tables")
+	}
+}
@@ -0,0 +1,59 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// Reconciler periodically checks inventory.stock against the net of
+// stock_logs and flags drift.
+type Reconciler struct {
+	repo     repository.ReconciliationRepository
+	outbox   *OutboxRelay
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func NewReconciler(repo repository.ReconciliationRepository, outbox *OutboxRelay, interval time.Duration, log zerolog.Logger) *Reconciler {
+	return &Reconciler{repo: repo, outbox: outbox, interval: interval, log: log}
+}
+
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.log.Info().Dur("interval", r.interval).Msg("Stock reconciler started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info().Msg("Stock reconciler stopped")
+			return
+		case <-ticker.C:
+			r.reconcile(ctx)
+		}
+	}
+}
+
+func (r *Reconciler) reconcile(ctx context.Context) {
+	run, err := r.repo.Reconcile(ctx, repository.ReconciliationTriggerScheduled)
+	if err != nil {
+		r.log.Error().Err(err).Msg("Failed to reconcile stock")
+		return
+	}
+
+	if run.Drifted == 0 {
+		r.log.Info().Int64("run_id", run.ID).Int("products_checked", run.ProductsChecked).Msg("Stock reconciled — no drift")
+		return
+	}
+
+	r.log.Warn().
+		Int64("run_id", run.ID).
+		Int("products_checked", run.ProductsChecked).
+		Int("drifted", run.Drifted).
+		Msg("⚖️ Stock drift detected")
+	r.outbox.Wake()
+}
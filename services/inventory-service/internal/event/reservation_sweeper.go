@@ -0,0 +1,64 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const sweepBatchSize = 100
+
+type ReservationSweeper struct {
+	repo     repository.ReservationRepository
+	outbox   *OutboxRelay
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func NewReservationSweeper(repo repository.ReservationRepository, outbox *OutboxRelay, interval time.Duration, log zerolog.Logger) *ReservationSweeper {
+	return &ReservationSweeper{repo: repo, outbox: outbox, interval: interval, log: log}
+}
+
+func (s *ReservationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.log.Info().Dur("interval", s.interval).Msg("Reservation sweeper started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info().Msg("Reservation sweeper stopped")
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *ReservationSweeper) sweep(ctx context.Context) {
+	for {
+		expired, err := s.repo.ExpireDue(ctx, sweepBatchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to expire reservations")
+			return
+		}
+
+		for _, res := range expired {
+			s.log.Info().
+				Int64("order_id", res.OrderID).
+				Int64("product_id", res.ProductID).
+				Int("quantity", res.Quantity).
+				Msg("⌛ Reservation expired — stock released")
+		}
+		if len(expired) > 0 {
+			s.outbox.Wake()
+		}
+
+		if len(expired) < sweepBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/catalog"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+const maxImportBytes = 64 << 20
+
+type CatalogHandler struct {
+	Importer *catalog.Importer
+	Repo     repository.ProductRepository
+	Log      zerolog.Logger
+}
+
+func NewCatalogHandler(repo repository.ProductRepository, log zerolog.Logger) *CatalogHandler {
+	return &CatalogHandler{Importer: catalog.NewImporter(repo), Repo: repo, Log: log}
+}
+
+// ImportProducts upserts products by SKU from a CSV or NDJSON body. The format
+// comes from ?format= or the Content-Type; ?dry_run=true validates every row
+// against the database without saving anything.
+func (h *CatalogHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = r.Header.Get("Content-Type")
+	}
+	format, err := catalog.ParseFormat(formatName)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "format must be csv or ndjson (query param or Content-Type)")
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	report, err := h.Importer.Import(r.Context(), format, body, dryRun)
+	if errors.Is(err, catalog.ErrMalformedInput) {
+		h.Log.Warn().Err(err).Int("rows", report.Total).Msg("Malformed product import")
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int("rows", report.Total).Msg("Product import failed")
+		utils.WriteError(w, http.StatusInternalServerError, "Product import failed")
+		return
+	}
+
+	h.Log.Info().
+		Str("format", string(format)).
+		Bool("dry_run", dryRun).
+		Int("total", report.Total).
+		Int("created", report.Created).
+		Int("updated", report.Updated).
+		Int("failed", report.Failed).
+		Msg("Product import finished")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// ExportProducts streams the catalog with stock figures as CSV or NDJSON.
+func (h *CatalogHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := catalog.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "format must be csv or ndjson")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="products.`+string(format)+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	var flush func()
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+	// Headers are already sent, so a failure can only be logged; the client
+	// sees a truncated body.
+	if err := catalog.Export(r.Context(), h.Repo, format, w, flush); err != nil {
+		h.Log.Error().Err(err).Str("format", string(format)).Msg("Product export failed")
+		return
+	}
+	h.Log.Info().Str("format", string(format)).Msg("Product export finished")
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CategoryNode is a category with its subcategories, as returned by
+// GET /categories.
+type CategoryNode struct {
+	repository.Category
+	Children []*CategoryNode `json:"children"`
+}
+
+type CategoryHandler struct {
+	Repo repository.CategoryRepository
+	Log  zerolog.Logger
+}
+
+func NewCategoryHandler(repo repository.CategoryRepository, log zerolog.Logger) *CategoryHandler {
+	return &CategoryHandler{Repo: repo, Log: log}
+}
+
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string `json:"name"`
+		Slug     string `json:"slug"`
+		ParentID *int64 `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid category payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	fields := make(map[string]string)
+	if name == "" {
+		fields["name"] = "name is required"
+	}
+	if !slugPattern.MatchString(slug) {
+		fields["slug"] = "slug must be lowercase letters and digits separated by '-'"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	category, err := h.Repo.CreateCategory(r.Context(), name, slug, req.ParentID)
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		utils.WriteValidationError(w, map[string]string{"parent_id": "parent category does not exist"})
+		return
+	case errors.Is(err, repository.ErrDuplicateSlug):
+		utils.WriteError(w, http.StatusConflict, "A category with this slug already exists")
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Str("slug", slug).Msg("Failed to create category")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create category")
+		return
+	}
+
+	h.Log.Info().Int64("category_id", category.ID).Str("slug", category.Slug).Msg("Category created")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(category)
+}
+
+// ListCategories returns the category hierarchy as a forest of root nodes.
+func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Repo.ListCategories(r.Context())
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list categories")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list categories")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(buildCategoryTree(categories))
+}
+
+func buildCategoryTree(categories []repository.Category) []*CategoryNode {
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{Category: c, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+const maxCycleCountLines = 500
+
+var cycleCountStatuses = map[string]bool{
+	repository.CycleCountStatusOpen:      true,
+	repository.CycleCountStatusPosted:    true,
+	repository.CycleCountStatusCancelled: true,
+}
+
+type CycleCountHandler struct {
+	Repo repository.CycleCountRepository
+	Log  zerolog.Logger
+}
+
+func NewCycleCountHandler(repo repository.CycleCountRepository, log zerolog.Logger) *CycleCountHandler {
+	return &CycleCountHandler{Repo: repo, Log: log}
+}
+
+// OpenCycleCount starts a count session for the given products, optionally
+// limited to one location.
+func (h *CycleCountHandler) OpenCycleCount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Actor      string  `json:"actor"`
+		Note       string  `json:"note"`
+		LocationID *int64  `json:"location_id"`
+		ProductIDs []int64 `json:"product_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid cycle count payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	fields := make(map[string]string)
+	in := repository.CycleCountInput{
+		Actor:      strings.TrimSpace(req.Actor),
+		Note:       strings.TrimSpace(req.Note),
+		LocationID: req.LocationID,
+	}
+	if in.Actor == "" {
+		fields["actor"] = "actor is required"
+	}
+	if len(req.ProductIDs) == 0 || len(req.ProductIDs) > maxCycleCountLines {
+		fields["product_ids"] = "product_ids must list between 1 and 500 products"
+	}
+	seen := make(map[int64]bool)
+	for _, id := range req.ProductIDs {
+		if id <= 0 {
+			fields["product_ids"] = "product_ids must be positive"
+			break
+		}
+		if !seen[id] {
+			seen[id] = true
+			in.ProductIDs = append(in.ProductIDs, id)
+		}
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	count, err := h.Repo.OpenCycleCount(r.Context(), in)
+	switch {
+	case errors.Is(err, repository.ErrLocationNotFound):
+		utils.WriteValidationError(w, map[string]string{"location_id": "location does not exist"})
+		return
+	case errors.Is(err, repository.ErrProductNotFound):
+		utils.WriteValidationError(w, map[string]string{"product_ids": err.Error()})
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Msg("Failed to open cycle count")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to open cycle count")
+		return
+	}
+
+	h.Log.Info().
+		Int64("cycle_count_id", count.ID).
+		Int("lines", len(count.Lines)).
+		Str("actor", in.Actor).
+		Msg("Cycle count opened")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(count)
+}
+
+func (h *CycleCountHandler) ListCycleCounts(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !cycleCountStatuses[status] {
+		utils.WriteError(w, http.StatusBadRequest, "status must be open, posted or cancelled")
+		return
+	}
+
+	list, err := h.Repo.ListCycleCounts(r.Context(), status)
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list cycle counts")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list cycle counts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// GetCycleCount returns the session with each line's expected and counted
+// quantity and their variance, for review before posting.
+func (h *CycleCountHandler) GetCycleCount(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.Repo.GetCycleCount(r.Context(), id)
+	if errors.Is(err, repository.ErrCycleCountNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Cycle count not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("cycle_count_id", id).Msg("Failed to fetch cycle count")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch cycle count")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(count)
+}
+
+func (h *CycleCountHandler) SubmitCounts(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Actor  string `json:"actor"`
+		Counts []struct {
+			ProductID int64 `json:"product_id"`
+			Counted   *int  `json:"counted"`
+		} `json:"counts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid count submission payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	fields := make(map[string]string)
+	actor := strings.TrimSpace(req.Actor)
+	if actor == "" {
+		fields["actor"] = "actor is required"
+	}
+	if len(req.Counts) == 0 {
+		fields["counts"] = "at least one count is required"
+	}
+	counts := make([]repository.CountInput, 0, len(req.Counts))
+	for i, c := range req.Counts {
+		if c.ProductID <= 0 || c.Counted == nil || *c.Counted < 0 {
+			fields["counts["+strconv.Itoa(i)+"]"] = "product_id must be positive and counted must not be negative"
+			continue
+		}
+		counts = append(counts, repository.CountInput{ProductID: c.ProductID, Counted: *c.Counted})
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	count, err := h.Repo.SubmitCounts(r.Context(), id, actor, counts)
+	if !h.handleSessionError(w, err, id, "Failed to submit counts") {
+		return
+	}
+
+	h.Log.Info().Int64("cycle_count_id", id).Int("counts", len(counts)).Str("actor", actor).Msg("Counts submitted")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(count)
+}
+
+// PostCycleCount turns the reviewed variances into stock adjustments.
+func (h *CycleCountHandler) PostCycleCount(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Actor string `json:"actor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid cycle count posting payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	actor := strings.TrimSpace(req.Actor)
+	if actor == "" {
+		utils.WriteValidationError(w, map[string]string{"actor": "actor is required"})
+		return
+	}
+
+	count, entries, err := h.Repo.PostCycleCount(r.Context(), id, actor)
+	if !h.handleSessionError(w, err, id, "Failed to post cycle count") {
+		return
+	}
+
+	h.Log.Info().
+		Int64("cycle_count_id", id).
+		Int("adjustments", len(entries)).
+		Str("actor", actor).
+		Msg("Cycle count posted")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"cycle_count": count,
+		"adjustments": entries,
+	})
+}
+
+func (h *CycleCountHandler) CancelCycleCount(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.Repo.CancelCycleCount(r.Context(), id)
+	if !h.handleSessionError(w, err, id, "Failed to cancel cycle count") {
+		return
+	}
+
+	h.Log.Info().Int64("cycle_count_id", id).Msg("Cycle count cancelled")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(count)
+}
+
+func (h *CycleCountHandler) handleSessionError(w http.ResponseWriter, err error, id int64, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrCycleCountNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Cycle count not found")
+	case errors.Is(err, repository.ErrCycleCountClosed):
+		utils.WriteError(w, http.StatusConflict, "Cycle count is already posted or cancelled")
+	case errors.Is(err, repository.ErrCountLineNotFound), errors.Is(err, repository.ErrCycleCountIncomplete):
+		utils.WriteValidationError(w, map[string]string{"counts": err.Error()})
+	case errors.Is(err, repository.ErrInsufficientStock):
+		utils.WriteError(w, http.StatusConflict, "Variance cannot be posted: stock is reserved or held in lots")
+	default:
+		h.Log.Error().Err(err).Int64("cycle_count_id", id).Msg(message)
+		utils.WriteError(w, http.StatusInternalServerError, message)
+	}
+	return false
+}
+
+func (h *CycleCountHandler) idParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := mux.Vars(r)["cycle_count_id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("cycle_count_id", idStr).Msg("Invalid cycle_count_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid cycle_count_id path param")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/catalog"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+type FamilyDTO struct {
+	ID             int64        `json:"id"`
+	Name           string       `json:"name"`
+	Description    string       `json:"description"`
+	OptionNames    []string     `json:"option_names"`
+	BasePriceMinor int64        `json:"base_price_minor"`
+	Currency       string       `json:"currency"`
+	Status         string       `json:"status"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Variants       []ProductDTO `json:"variants,omitempty"`
+}
+
+func NewFamilyDTO(f *repository.Family) FamilyDTO {
+	dto := FamilyDTO{
+		ID:             f.ID,
+		Name:           f.Name,
+		Description:    f.Description,
+		OptionNames:    f.OptionNames,
+		BasePriceMinor: f.BasePriceMinor,
+		Currency:       f.Currency,
+		Status:         f.Status,
+		CreatedAt:      f.CreatedAt,
+		UpdatedAt:      f.UpdatedAt,
+	}
+	if f.Variants != nil {
+		dto.Variants = make([]ProductDTO, 0, len(f.Variants))
+		for i := range f.Variants {
+			dto.Variants = append(dto.Variants, NewProductDTO(&f.Variants[i]))
+		}
+	}
+	return dto
+}
+
+type FamilyHandler struct {
+	Repo repository.FamilyRepository
+	Log  zerolog.Logger
+}
+
+func NewFamilyHandler(repo repository.FamilyRepository, log zerolog.Logger) *FamilyHandler {
+	return &FamilyHandler{Repo: repo, Log: log}
+}
+
+func (h *FamilyHandler) CreateFamily(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name           string   `json:"name"`
+		Description    string   `json:"description"`
+		OptionNames    []string `json:"option_names"`
+		BasePriceMinor int64    `json:"base_price_minor"`
+		Currency       string   `json:"currency"`
+		Status         string   `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid product family payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	in := repository.FamilyInput{
+		Name:           strings.TrimSpace(req.Name),
+		Description:    strings.TrimSpace(req.Description),
+		BasePriceMinor: req.BasePriceMinor,
+		Currency:       strings.ToUpper(strings.TrimSpace(req.Currency)),
+		Status:         req.Status,
+	}
+	if in.Currency == "" {
+		in.Currency = "USD"
+	}
+	if in.Status == "" {
+		in.Status = repository.ProductStatusActive
+	}
+
+	fields := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, name := range req.OptionNames {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			fields["option_names"] = "option_names must be unique and non-empty"
+			break
+		}
+		seen[name] = true
+		in.OptionNames = append(in.OptionNames, name)
+	}
+	if len(in.OptionNames) == 0 && fields["option_names"] == "" {
+		fields["option_names"] = "at least one option name is required"
+	}
+	if in.Name == "" {
+		fields["name"] = "name is required"
+	}
+	if len(in.Description) > catalog.MaxDescriptionLength {
+		fields["description"] = "description must be at most 4000 characters"
+	}
+	if in.BasePriceMinor < 0 {
+		fields["base_price_minor"] = "base_price_minor must not be negative"
+	}
+	if !catalog.CurrencyPattern.MatchString(in.Currency) {
+		fields["currency"] = "currency must be a 3-letter ISO 4217 code"
+	}
+	if !catalog.IsValidStatus(in.Status) {
+		fields["status"] = "status must be active or archived"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	family, err := h.Repo.CreateFamily(r.Context(), in)
+	if err != nil {
+		h.Log.Error().Err(err).Str("name", in.Name).Msg("Failed to create product family")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create product family")
+		return
+	}
+
+	h.Log.Info().Int64("family_id", family.ID).Strs("options", family.OptionNames).Msg("Product family created")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(NewFamilyDTO(family))
+}
+
+func (h *FamilyHandler) PatchFamily(w http.ResponseWriter, r *http.Request) {
+	familyID, ok := h.familyIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name           *string `json:"name"`
+		Description    *string `json:"description"`
+		BasePriceMinor *int64  `json:"base_price_minor"`
+		Currency       *string `json:"currency"`
+		Status         *string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid product family patch payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	patch := repository.FamilyPatch{BasePriceMinor: req.BasePriceMinor, Status: req.Status}
+	fields := make(map[string]string)
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			fields["name"] = "name must not be empty"
+		}
+		patch.Name = &name
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if len(description) > catalog.MaxDescriptionLength {
+			fields["description"] = "description must be at most 4000 characters"
+		}
+		patch.Description = &description
+	}
+	if req.BasePriceMinor != nil && *req.BasePriceMinor < 0 {
+		fields["base_price_minor"] = "base_price_minor must not be negative"
+	}
+	if req.Currency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*req.Currency))
+		if !catalog.CurrencyPattern.MatchString(currency) {
+			fields["currency"] = "currency must be a 3-letter ISO 4217 code"
+		}
+		patch.Currency = &currency
+	}
+	if req.Status != nil && !catalog.IsValidStatus(*req.Status) {
+		fields["status"] = "status must be active or archived"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	family, err := h.Repo.PatchFamily(r.Context(), familyID, patch)
+	if errors.Is(err, repository.ErrFamilyNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Product family not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("family_id", familyID).Msg("Failed to patch product family")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to patch product family")
+		return
+	}
+
+	h.Log.Info().Int64("family_id", family.ID).Msg("Product family patched")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(NewFamilyDTO(family))
+}
+
+func (h *FamilyHandler) ListFamilies(w http.ResponseWriter, r *http.Request) {
+	families, err := h.Repo.ListFamilies(r.Context())
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list product families")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list product families")
+		return
+	}
+
+	items := make([]FamilyDTO, 0, len(families))
+	for i := range families {
+		items = append(items, NewFamilyDTO(&families[i]))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(items)
+}
+
+func (h *FamilyHandler) GetFamily(w http.ResponseWriter, r *http.Request) {
+	familyID, ok := h.familyIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	family, err := h.Repo.GetFamily(r.Context(), familyID)
+	if errors.Is(err, repository.ErrFamilyNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Product family not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("family_id", familyID).Msg("Failed to fetch product family")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch product family")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(NewFamilyDTO(family))
+}
+
+// CreateVariant adds a sellable variant to a family. The variant's
+// product_id is the variant ID that orders and stock endpoints refer to.
+func (h *FamilyHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	familyID, ok := h.familyIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		SKU                string            `json:"sku"`
+		ProductName        string            `json:"product_name"`
+		Options            map[string]string `json:"options"`
+		PriceOverrideMinor *int64            `json:"price_override_minor"`
+		Attributes         json.RawMessage   `json:"attributes"`
+		Stock              int               `json:"stock"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid variant payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	in := repository.VariantInput{
+		SKU:                strings.ToUpper(strings.TrimSpace(req.SKU)),
+		ProductName:        strings.TrimSpace(req.ProductName),
+		Options:            make(map[string]string, len(req.Options)),
+		PriceOverrideMinor: req.PriceOverrideMinor,
+		Attributes:         req.Attributes,
+		Stock:              req.Stock,
+	}
+	for name, value := range req.Options {
+		in.Options[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	if len(in.Attributes) == 0 || string(in.Attributes) == "null" {
+		in.Attributes = json.RawMessage(`{}`)
+	}
+
+	fields := make(map[string]string)
+	if !catalog.SKUPattern.MatchString(in.SKU) {
+		fields["sku"] = "sku is required and may contain only letters, digits, '.', '_' and '-' (max 64)"
+	}
+	if len(in.Options) == 0 {
+		fields["options"] = "options are required"
+	}
+	if in.PriceOverrideMinor != nil && *in.PriceOverrideMinor < 0 {
+		fields["price_override_minor"] = "price_override_minor must not be negative"
+	}
+	if !catalog.IsJSONObject(in.Attributes) {
+		fields["attributes"] = "attributes must be a JSON object"
+	}
+	if in.Stock < 0 {
+		fields["stock"] = "stock must not be negative"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	variant, err := h.Repo.CreateVariant(r.Context(), familyID, in)
+	switch {
+	case errors.Is(err, repository.ErrFamilyNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Product family not found")
+		return
+	case errors.Is(err, repository.ErrInvalidOptions):
+		utils.WriteValidationError(w, map[string]string{"options": "options must set a value for exactly the family's option names"})
+		return
+	case errors.Is(err, repository.ErrDuplicateVariant):
+		utils.WriteError(w, http.StatusConflict, "A variant with these options already exists")
+		return
+	case errors.Is(err, repository.ErrDuplicateSKU):
+		utils.WriteError(w, http.StatusConflict, "A product with this sku already exists")
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Int64("family_id", familyID).Msg("Failed to create variant")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create variant")
+		return
+	}
+
+	h.Log.Info().Int64("family_id", familyID).Int64("variant_id", variant.ProductID).Str("sku", variant.SKU).Msg("Variant created")
+	setETag(w, variant.Version)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(NewProductDTO(variant))
+}
+
+func (h *FamilyHandler) familyIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := mux.Vars(r)["family_id"]
+	familyID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("family_id", idStr).Msg("Invalid family_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid family_id path param")
+		return 0, false
+	}
+	return familyID, true
+}
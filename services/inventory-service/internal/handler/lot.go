@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+const maxLotNumberLength = 64
+
+type LotHandler struct {
+	Repo repository.LotRepository
+	Log  zerolog.Logger
+}
+
+func NewLotHandler(repo repository.LotRepository, log zerolog.Logger) *LotHandler {
+	return &LotHandler{Repo: repo, Log: log}
+}
+
+func (h *LotHandler) ListLots(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.pathID(w, r, "product_id")
+	if !ok {
+		return
+	}
+
+	lots, err := h.Repo.ListLots(r.Context(), productID)
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to list lots")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list lots")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(lots)
+}
+
+// GetLotAllocations lists the orders that shipped from a lot.
+func (h *LotHandler) GetLotAllocations(w http.ResponseWriter, r *http.Request) {
+	lotID, ok := h.pathID(w, r, "lot_id")
+	if !ok {
+		return
+	}
+
+	allocations, err := h.Repo.GetLotAllocations(r.Context(), lotID)
+	if errors.Is(err, repository.ErrLotNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Lot not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("lot_id", lotID).Msg("Failed to get lot allocations")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get lot allocations")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(allocations)
+}
+
+func (h *LotHandler) pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	idStr := mux.Vars(r)[name]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str(name, idStr).Msgf("Invalid %s path param", name)
+		utils.WriteError(w, http.StatusBadRequest, "Invalid "+name+" path param")
+		return 0, false
+	}
+	return id, true
+}
+
+// parseLotInput turns the optional lot_number/expires_at request fields into
+// a lot reference. It returns a validation message instead of an error.
+func parseLotInput(number, expiresAt string) (*repository.LotInput, string) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		if expiresAt != "" {
+			return nil, "expires_at requires lot_number"
+		}
+		return nil, ""
+	}
+	if len(number) > maxLotNumberLength {
+		return nil, "lot_number must be at most 64 characters"
+	}
+	expiry, err := parseDate(expiresAt)
+	if err != nil {
+		return nil, "expires_at must be a date (YYYY-MM-DD)"
+	}
+	return &repository.LotInput{Number: number, ExpiresAt: expiry}, ""
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/catalog"
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
 	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
 	"github.com/gorilla/mux"
@@ -22,27 +25,39 @@ func NewProductHandler(repo repository.ProductRepository, log zerolog.Logger) *P
 }
 
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		ProductName string `json:"product_name"`
-		Stock       int    `json:"stock"`
-	}
-
+	var req productRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.Log.Warn().Err(err).Msg("Invalid product creation payload")
 		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	product, err := h.Repo.InsertProduct(r.Context(), req.ProductName, req.Stock)
+	in := req.toInput()
+	catalog.NormalizeProduct(&in)
+	if fields := catalog.ValidateProduct(in, true); fields != nil {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	product, err := h.Repo.InsertProduct(r.Context(), in)
+	if errors.Is(err, repository.ErrDuplicateSKU) {
+		utils.WriteError(w, http.StatusConflict, "A product with this sku already exists")
+		return
+	}
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		utils.WriteValidationError(w, map[string]string{"category_id": "category does not exist"})
+		return
+	}
 	if err != nil {
-		h.Log.Error().Err(err).Str("product_name", req.ProductName).Msg("Failed to insert product")
+		h.Log.Error().Err(err).Str("sku", in.SKU).Msg("Failed to insert product")
 		utils.WriteError(w, http.StatusInternalServerError, "Failed to insert product")
 		return
 	}
 
-	h.Log.Info().Int64("product_id", product.ProductID).Msg("Product created")
+	h.Log.Info().Int64("product_id", product.ProductID).Str("sku", product.SKU).Msg("Product created")
+	setETag(w, product.Version)
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(product)
+	_ = json.NewEncoder(w).Encode(NewProductDTO(product))
 }
 
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
@@ -54,26 +69,102 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		ProductName string `json:"product_name"`
-		Stock       int    `json:"stock"`
+	if r.Header.Get("If-Match") == "" {
+		utils.WriteError(w, http.StatusPreconditionRequired, "If-Match header is required")
+		return
+	}
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid If-Match header")
+		return
 	}
+
+	var req productRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.Log.Warn().Err(err).Msg("Invalid update product payload")
 		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	product, err := h.Repo.UpdateProduct(r.Context(), productID, req.ProductName, req.Stock)
+	in := req.toInput()
+	catalog.NormalizeProduct(&in)
+	if fields := catalog.ValidateProduct(in, false); fields != nil {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	product, err := h.Repo.UpdateProduct(r.Context(), productID, in, expectedVersion)
+	if !h.handleWriteError(w, err, productID, "Failed to update product") {
+		return
+	}
+
+	h.Log.Info().Int64("product_id", product.ProductID).Int64("version", product.Version).Msg("Product updated")
+	setETag(w, product.Version)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(NewProductDTO(product))
+}
+
+// PatchProduct changes catalog fields and the reorder threshold. It never
+// touches stock, so If-Match is honoured when sent but not required.
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["product_id"]
+	productID, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to update product")
-		utils.WriteError(w, http.StatusInternalServerError, "Failed to update product")
+		h.Log.Warn().Str("product_id", idStr).Msg("Invalid product_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid product_id path param")
+		return
+	}
+
+	var expectedVersion *int64
+	if r.Header.Get("If-Match") != "" {
+		if expectedVersion, err = parseIfMatch(r); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid If-Match header")
+			return
+		}
+	}
+
+	var req productPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid patch product payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	h.Log.Info().Int64("product_id", product.ProductID).Msg("Product updated")
+	patch, fields := req.toPatch()
+	if fields != nil {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	product, err := h.Repo.PatchProduct(r.Context(), productID, patch, expectedVersion)
+	if !h.handleWriteError(w, err, productID, "Failed to patch product") {
+		return
+	}
+
+	h.Log.Info().Int64("product_id", product.ProductID).Int64("version", product.Version).Msg("Product patched")
+	setETag(w, product.Version)
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(product)
+	_ = json.NewEncoder(w).Encode(NewProductDTO(product))
+}
+
+func (h *ProductHandler) handleWriteError(w http.ResponseWriter, err error, productID int64, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrProductNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Product not found")
+	case errors.Is(err, repository.ErrVersionMismatch):
+		h.Log.Warn().Int64("product_id", productID).Msg("Product version mismatch")
+		utils.WriteError(w, http.StatusPreconditionFailed, "Product has been modified; re-fetch and retry")
+	case errors.Is(err, repository.ErrInsufficientStock):
+		utils.WriteError(w, http.StatusConflict, "Stock cannot be set below reserved, located or lot quantities")
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		utils.WriteValidationError(w, map[string]string{"category_id": "category does not exist"})
+	default:
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg(message)
+		utils.WriteError(w, http.StatusInternalServerError, message)
+	}
+	return false
 }
 
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
@@ -85,27 +176,119 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Repo.DeleteProduct(r.Context(), productID); err != nil {
-		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to delete product")
-		utils.WriteError(w, http.StatusInternalServerError, "Failed to delete product")
+	err = h.Repo.DeleteProduct(r.Context(), productID)
+	if errors.Is(err, repository.ErrProductInUse) {
+		utils.WriteError(w, http.StatusConflict, "Product has active reservations; release them before deleting")
+		return
+	}
+	if !h.handleWriteError(w, err, productID, "Failed to delete product") {
 		return
 	}
 
-	h.Log.Info().Int64("product_id", productID).Msg("Product deleted")
+	h.Log.Info().Int64("product_id", productID).Msg("Product archived")
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.Repo.GetAllProducts(context.Background())
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["product_id"]
+	productID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("product_id", idStr).Msg("Invalid product_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid product_id path param")
+		return
+	}
+
+	product, err := h.Repo.RestoreProduct(r.Context(), productID)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "No deleted product with this id")
+		return
+	}
 	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to restore product")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to restore product")
+		return
+	}
+
+	h.Log.Info().Int64("product_id", productID).Msg("Product restored")
+	setETag(w, product.Version)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(NewProductDTO(product))
+}
+
+// ListProducts supports full-text search (q), filters (category_id including
+// subcategories, in_stock, min_price, max_price, status), sort and cursor
+// pagination. The response carries next_cursor while more pages remain.
+// Only active products are listed unless status is given; status=all lists
+// archived ones too.
+func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.ProductFilter{
+		Query:  strings.TrimSpace(query.Get("q")),
+		Status: repository.ProductStatusActive,
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+
+	switch status := query.Get("status"); status {
+	case "":
+	case "all":
+		filter.Status = ""
+	case repository.ProductStatusActive, repository.ProductStatusArchived:
+		filter.Status = status
+	default:
+		utils.WriteError(w, http.StatusBadRequest, "status must be active, archived or all")
+		return
+	}
+
+	var err error
+	if filter.Limit, err = queryInt(r, "limit", defaultProductLimit); err != nil || filter.Limit <= 0 || filter.Limit > maxProductLimit {
+		utils.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+		return
+	}
+	if filter.CategoryID, err = queryInt64Ptr(r, "category_id"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "category_id must be an integer")
+		return
+	}
+	if filter.MinPrice, err = queryInt64Ptr(r, "min_price"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "min_price must be an integer amount in minor units")
+		return
+	}
+	if filter.MaxPrice, err = queryInt64Ptr(r, "max_price"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "max_price must be an integer amount in minor units")
+		return
+	}
+	if v := query.Get("in_stock"); v != "" {
+		if filter.InStock, err = strconv.ParseBool(v); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "in_stock must be true or false")
+			return
+		}
+	}
+
+	page, err := h.Repo.ListProducts(r.Context(), filter)
+	switch {
+	case errors.Is(err, repository.ErrInvalidSort):
+		utils.WriteError(w, http.StatusBadRequest, "sort must be one of id, name, -name, price, -price, updated_at, -updated_at, relevance (with q)")
+		return
+	case errors.Is(err, repository.ErrInvalidCursor):
+		utils.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	case err != nil:
 		h.Log.Error().Err(err).Msg("Failed to list products")
 		utils.WriteError(w, http.StatusInternalServerError, "Failed to list products")
 		return
 	}
 
-	h.Log.Info().Int("count", len(products)).Msg("Product list retrieved")
+	items := make([]ProductDTO, 0, len(page.Items))
+	for i := range page.Items {
+		items = append(items, NewProductDTO(&page.Items[i]))
+	}
+
+	h.Log.Info().Int("count", len(items)).Str("q", filter.Query).Msg("Product list retrieved")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(products)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":       items,
+		"next_cursor": page.NextCursor,
+	})
 }
 
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
@@ -118,13 +301,39 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	product, err := h.Repo.GetByID(r.Context(), id)
-	if err != nil {
-		h.Log.Error().Err(err).Int64("product_id", id).Msg("Product not found")
+	if errors.Is(err, repository.ErrProductNotFound) {
+		h.Log.Warn().Int64("product_id", id).Msg("Product not found")
 		utils.WriteError(w, http.StatusNotFound, "Product not found")
 		return
 	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch product")
+		return
+	}
 
 	h.Log.Info().Int64("product_id", product.ProductID).Msg("Product fetched")
+	setETag(w, product.Version)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(product)
+	json.NewEncoder(w).Encode(NewProductDTO(product))
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatInt(version, 10)))
+}
+
+// parseIfMatch returns the version named by the If-Match header, or nil for
+// "*", which matches any current version of the product.
+func parseIfMatch(r *http.Request) (*int64, error) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "*" {
+		return nil, nil
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	tag = strings.Trim(tag, `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
 }
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/catalog"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+)
+
+const (
+	defaultProductLimit = 50
+	maxProductLimit     = 200
+)
+
+// ProductDTO is the public shape of a product. It is kept separate from
+// repository.Product so schema changes do not leak into the API.
+type ProductDTO struct {
+	ProductID   int64           `json:"product_id"`
+	SKU         string          `json:"sku"`
+	ProductName string          `json:"product_name"`
+	Description string          `json:"description"`
+	PriceMinor  int64           `json:"price_minor"`
+	Currency    string          `json:"currency"`
+	Attributes  json.RawMessage `json:"attributes"`
+	Status      string          `json:"status"`
+	CategoryID  *int64          `json:"category_id"`
+	FamilyID    *int64          `json:"family_id,omitempty"`
+	Options     json.RawMessage `json:"options,omitempty"`
+	Stock       int             `json:"stock"`
+	Reserved    int             `json:"reserved"`
+	Available   int             `json:"available"`
+
+	ReorderThreshold int       `json:"reorder_threshold"`
+	StockState       string    `json:"stock_state"`
+	Version          int64     `json:"version"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func NewProductDTO(p *repository.Product) ProductDTO {
+	return ProductDTO{
+		ProductID:   p.ProductID,
+		SKU:         p.SKU,
+		ProductName: p.ProductName,
+		Description: p.Description,
+		PriceMinor:  p.PriceMinor,
+		Currency:    p.Currency,
+		Attributes:  p.Attributes,
+		Status:      p.Status,
+		CategoryID:  p.CategoryID,
+		FamilyID:    p.FamilyID,
+		Options:     variantOptions(p),
+		Stock:       p.Stock,
+		Reserved:    p.Reserved,
+		Available:   p.Available,
+
+		ReorderThreshold: p.ReorderThreshold,
+		StockState:       p.StockState,
+		Version:          p.Version,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+// variantOptions hides the empty options object of standalone products.
+func variantOptions(p *repository.Product) json.RawMessage {
+	if p.FamilyID == nil {
+		return nil
+	}
+	return p.Options
+}
+
+type productRequest struct {
+	SKU         string          `json:"sku"`
+	ProductName string          `json:"product_name"`
+	Description string          `json:"description"`
+	PriceMinor  int64           `json:"price_minor"`
+	Currency    string          `json:"currency"`
+	Attributes  json.RawMessage `json:"attributes"`
+	Status      string          `json:"status"`
+	CategoryID  *int64          `json:"category_id"`
+	Stock       int             `json:"stock"`
+
+	ReorderThreshold int `json:"reorder_threshold"`
+}
+
+func (req productRequest) toInput() repository.ProductInput {
+	return repository.ProductInput{
+		SKU:         req.SKU,
+		ProductName: req.ProductName,
+		Description: req.Description,
+		PriceMinor:  req.PriceMinor,
+		Currency:    req.Currency,
+		Attributes:  req.Attributes,
+		Status:      req.Status,
+		CategoryID:  req.CategoryID,
+		Stock:       req.Stock,
+
+		ReorderThreshold: req.ReorderThreshold,
+	}
+}
+
+type productPatchRequest struct {
+	ProductName *string         `json:"product_name"`
+	Description *string         `json:"description"`
+	PriceMinor  *int64          `json:"price_minor"`
+	Currency    *string         `json:"currency"`
+	Attributes  json.RawMessage `json:"attributes"`
+	Status      *string         `json:"status"`
+	CategoryID  optionalID      `json:"category_id"`
+
+	ReorderThreshold *int `json:"reorder_threshold"`
+}
+
+// optionalID tells a field left out of a PATCH body from one set to null.
+type optionalID struct {
+	Set bool
+	ID  *int64
+}
+
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	o.ID = nil
+	if string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, &o.ID)
+}
+
+func (req productPatchRequest) toPatch() (repository.ProductPatch, map[string]string) {
+	errs := make(map[string]string)
+	patch := repository.ProductPatch{Attributes: req.Attributes, CategoryID: req.CategoryID.ID}
+	patch.ClearCategory = req.CategoryID.Set && req.CategoryID.ID == nil
+
+	if req.ProductName != nil {
+		name := strings.TrimSpace(*req.ProductName)
+		if name == "" {
+			errs["product_name"] = "product_name must not be empty"
+		}
+		patch.ProductName = &name
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if len(description) > catalog.MaxDescriptionLength {
+			errs["description"] = "description must be at most 4000 characters"
+		}
+		patch.Description = &description
+	}
+	if req.PriceMinor != nil {
+		if *req.PriceMinor < 0 {
+			errs["price_minor"] = "price_minor must not be negative"
+		}
+		patch.PriceMinor = req.PriceMinor
+	}
+	if req.Currency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*req.Currency))
+		if !catalog.CurrencyPattern.MatchString(currency) {
+			errs["currency"] = "currency must be a 3-letter ISO 4217 code"
+		}
+		patch.Currency = &currency
+	}
+	if req.Attributes != nil && !catalog.IsJSONObject(req.Attributes) {
+		errs["attributes"] = "attributes must be a JSON object"
+	}
+	if req.Status != nil {
+		if !catalog.IsValidStatus(*req.Status) {
+			errs["status"] = "status must be active or archived"
+		}
+		patch.Status = req.Status
+	}
+	if req.ReorderThreshold != nil {
+		if *req.ReorderThreshold < 0 {
+			errs["reorder_threshold"] = "reorder_threshold must not be negative"
+		}
+		patch.ReorderThreshold = req.ReorderThreshold
+	}
+
+	if req.ProductName == nil && req.Description == nil && req.PriceMinor == nil &&
+		req.Currency == nil && req.Attributes == nil && req.Status == nil && !req.CategoryID.Set &&
+		req.ReorderThreshold == nil {
+		errs["body"] = "at least one field must be provided"
+	}
+
+	if len(errs) == 0 {
+		return patch, nil
+	}
+	return patch, errs
+}
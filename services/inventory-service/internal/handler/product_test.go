@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version *int64
+		wantErr bool
+	}{
+		{`"3"`, ptr(int64(3)), false},
+		{`W/"3"`, ptr(int64(3)), false},
+		{`*`, nil, false},
+		{`"v3"`, nil, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/products/1", nil)
+		req.Header.Set("If-Match", tt.header)
+		version, err := parseIfMatch(req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIfMatch(%s) error = %v, want error %v", tt.header, err, tt.wantErr)
+			continue
+		}
+		if (version == nil) != (tt.version == nil) || (version != nil && *version != *tt.version) {
+			t.Errorf("parseIfMatch(%s) = %v, want %v", tt.header, version, tt.version)
+		}
+	}
+}
+
+func TestPatchCategory(t *testing.T) {
+	tests := []struct {
+		body       string
+		categoryID *int64
+		clear      bool
+	}{
+		{`{"category_id": 4}`, ptr(int64(4)), false},
+		{`{"category_id": null}`, nil, true},
+		{`{"product_name": "Mug"}`, nil, false},
+	}
+	for _, tt := range tests {
+		var req productPatchRequest
+		if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		patch, fields := req.toPatch()
+		if fields != nil {
+			t.Errorf("%s: unexpected validation errors %v", tt.body, fields)
+			continue
+		}
+		if patch.ClearCategory != tt.clear || (patch.CategoryID == nil) != (tt.categoryID == nil) ||
+			(patch.CategoryID != nil && *patch.CategoryID != *tt.categoryID) {
+			t.Errorf("%s: category %v, clear %v; want %v, %v", tt.body, patch.CategoryID, patch.ClearCategory, tt.categoryID, tt.clear)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,404 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	defaultLookbackDays = 30
+	defaultCoverDays    = 30
+	defaultLeadTimeDays = 7
+	maxPlanningDays     = 365
+)
+
+var purchaseOrderStatuses = map[string]bool{
+	repository.PurchaseOrderStatusOpen:              true,
+	repository.PurchaseOrderStatusPartiallyReceived: true,
+	repository.PurchaseOrderStatusReceived:          true,
+	repository.PurchaseOrderStatusCancelled:         true,
+}
+
+type PurchaseOrderHandler struct {
+	Repo repository.PurchaseOrderRepository
+	Log  zerolog.Logger
+}
+
+func NewPurchaseOrderHandler(repo repository.PurchaseOrderRepository, log zerolog.Logger) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{Repo: repo, Log: log}
+}
+
+func (h *PurchaseOrderHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code         string `json:"code"`
+		Name         string `json:"name"`
+		Email        string `json:"email"`
+		LeadTimeDays *int   `json:"lead_time_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid supplier payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	in := repository.Supplier{
+		Code:         strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:         strings.TrimSpace(req.Name),
+		Email:        strings.TrimSpace(req.Email),
+		LeadTimeDays: defaultLeadTimeDays,
+	}
+	if req.LeadTimeDays != nil {
+		in.LeadTimeDays = *req.LeadTimeDays
+	}
+
+	fields := make(map[string]string)
+	if in.Code == "" {
+		fields["code"] = "code is required"
+	}
+	if in.Name == "" {
+		fields["name"] = "name is required"
+	}
+	if in.LeadTimeDays < 0 || in.LeadTimeDays > maxPlanningDays {
+		fields["lead_time_days"] = "lead_time_days must be between 0 and 365"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	supplier, err := h.Repo.CreateSupplier(r.Context(), in)
+	if errors.Is(err, repository.ErrDuplicateSupplier) {
+		utils.WriteError(w, http.StatusConflict, "Supplier code already exists")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Str("code", in.Code).Msg("Failed to create supplier")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create supplier")
+		return
+	}
+
+	h.Log.Info().Int64("supplier_id", supplier.ID).Str("code", supplier.Code).Msg("Supplier created")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(supplier)
+}
+
+func (h *PurchaseOrderHandler) ListSuppliers(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := h.Repo.ListSuppliers(r.Context())
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list suppliers")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list suppliers")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(suppliers)
+}
+
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SupplierID int64  `json:"supplier_id"`
+		ExpectedAt string `json:"expected_at"`
+		Note       string `json:"note"`
+		Lines      []struct {
+			ProductID     int64  `json:"product_id"`
+			Quantity      int    `json:"quantity"`
+			UnitCostMinor int64  `json:"unit_cost_minor"`
+			ExpectedAt    string `json:"expected_at"`
+		} `json:"lines"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid purchase order payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	fields := make(map[string]string)
+	in := repository.PurchaseOrderInput{SupplierID: req.SupplierID, Note: strings.TrimSpace(req.Note)}
+	if req.SupplierID <= 0 {
+		fields["supplier_id"] = "supplier_id must be positive"
+	}
+	var err error
+	if in.ExpectedAt, err = parseDate(req.ExpectedAt); err != nil {
+		fields["expected_at"] = "expected_at must be a date (YYYY-MM-DD)"
+	}
+	if len(req.Lines) == 0 {
+		fields["lines"] = "at least one line is required"
+	}
+
+	seen := make(map[int64]bool)
+	for i, line := range req.Lines {
+		key := "lines[" + strconv.Itoa(i) + "]"
+		expectedAt, err := parseDate(line.ExpectedAt)
+		switch {
+		case line.ProductID <= 0 || line.Quantity <= 0:
+			fields[key] = "product_id and quantity must be positive"
+		case seen[line.ProductID]:
+			fields[key] = "product_id appears on more than one line"
+		case line.UnitCostMinor < 0:
+			fields[key] = "unit_cost_minor must not be negative"
+		case err != nil:
+			fields[key] = "expected_at must be a date (YYYY-MM-DD)"
+		}
+		seen[line.ProductID] = true
+		in.Lines = append(in.Lines, repository.PurchaseOrderLineInput{
+			ProductID:     line.ProductID,
+			Quantity:      line.Quantity,
+			UnitCostMinor: line.UnitCostMinor,
+			ExpectedAt:    expectedAt,
+		})
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	po, err := h.Repo.CreatePurchaseOrder(r.Context(), in)
+	switch {
+	case errors.Is(err, repository.ErrSupplierNotFound):
+		utils.WriteValidationError(w, map[string]string{"supplier_id": "supplier does not exist"})
+		return
+	case errors.Is(err, repository.ErrProductNotFound):
+		utils.WriteValidationError(w, map[string]string{"lines": err.Error()})
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Int64("supplier_id", req.SupplierID).Msg("Failed to create purchase order")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create purchase order")
+		return
+	}
+
+	h.Log.Info().
+		Int64("purchase_order_id", po.ID).
+		Int64("supplier_id", po.SupplierID).
+		Int("lines", len(po.Lines)).
+		Msg("Purchase order created")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(po)
+}
+
+func (h *PurchaseOrderHandler) ListPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !purchaseOrderStatuses[status] {
+		utils.WriteError(w, http.StatusBadRequest, "status must be open, partially_received, received or cancelled")
+		return
+	}
+	supplierID, err := queryInt64Ptr(r, "supplier_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "supplier_id must be an integer")
+		return
+	}
+
+	list, err := h.Repo.ListPurchaseOrders(r.Context(), status, supplierID)
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list purchase orders")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list purchase orders")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (h *PurchaseOrderHandler) GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	po, err := h.Repo.GetPurchaseOrder(r.Context(), id)
+	if errors.Is(err, repository.ErrPurchaseOrderNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Purchase order not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("purchase_order_id", id).Msg("Failed to fetch purchase order")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch purchase order")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(po)
+}
+
+// ReceivePurchaseOrder books goods against open lines and adds them to stock.
+// Partial quantities are allowed; receiving more than is open is refused.
+func (h *PurchaseOrderHandler) ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Actor      string `json:"actor"`
+		Note       string `json:"note"`
+		LocationID *int64 `json:"location_id"`
+		Lines      []struct {
+			LineID    int64  `json:"line_id"`
+			Quantity  int    `json:"quantity"`
+			LotNumber string `json:"lot_number"`
+			ExpiresAt string `json:"expires_at"`
+		} `json:"lines"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid receipt payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	fields := make(map[string]string)
+	in := repository.ReceiptInput{
+		Actor:      strings.TrimSpace(req.Actor),
+		Note:       strings.TrimSpace(req.Note),
+		LocationID: req.LocationID,
+	}
+	if in.Actor == "" {
+		fields["actor"] = "actor is required"
+	}
+	if len(req.Lines) == 0 {
+		fields["lines"] = "at least one line is required"
+	}
+	for i, line := range req.Lines {
+		key := "lines[" + strconv.Itoa(i) + "]"
+		lot, msg := parseLotInput(line.LotNumber, line.ExpiresAt)
+		switch {
+		case line.LineID <= 0 || line.Quantity <= 0:
+			fields[key] = "line_id and quantity must be positive"
+		case msg != "":
+			fields[key] = msg
+		}
+		in.Lines = append(in.Lines, repository.ReceiptLineInput{LineID: line.LineID, Quantity: line.Quantity, Lot: lot})
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	po, receipts, err := h.Repo.ReceivePurchaseOrder(r.Context(), id, in)
+	switch {
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Purchase order not found")
+		return
+	case errors.Is(err, repository.ErrPurchaseOrderClosed):
+		utils.WriteError(w, http.StatusConflict, "Purchase order is already received or cancelled")
+		return
+	case errors.Is(err, repository.ErrLocationNotFound):
+		utils.WriteValidationError(w, map[string]string{"location_id": "location does not exist"})
+		return
+	case errors.Is(err, repository.ErrLineNotFound), errors.Is(err, repository.ErrOverReceipt),
+		errors.Is(err, repository.ErrLotQuarantined), errors.Is(err, repository.ErrLotExpiryMismatch):
+		utils.WriteValidationError(w, map[string]string{"lines": err.Error()})
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Int64("purchase_order_id", id).Msg("Failed to receive purchase order")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to receive purchase order")
+		return
+	}
+
+	h.Log.Info().
+		Int64("purchase_order_id", po.ID).
+		Str("status", po.Status).
+		Int("receipts", len(receipts)).
+		Str("actor", in.Actor).
+		Msg("Purchase order received")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"purchase_order": po,
+		"receipts":       receipts,
+	})
+}
+
+func (h *PurchaseOrderHandler) CancelPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.idParam(w, r)
+	if !ok {
+		return
+	}
+
+	po, err := h.Repo.CancelPurchaseOrder(r.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Purchase order not found")
+		return
+	case errors.Is(err, repository.ErrPurchaseOrderClosed):
+		utils.WriteError(w, http.StatusConflict, "Purchase order is already received or cancelled")
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Int64("purchase_order_id", id).Msg("Failed to cancel purchase order")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to cancel purchase order")
+		return
+	}
+
+	h.Log.Info().Int64("purchase_order_id", id).Msg("Purchase order cancelled")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(po)
+}
+
+// SuggestReorders lists products worth reordering, based on order
+// consumption over lookback_days and cover for lead time plus cover_days.
+func (h *PurchaseOrderHandler) SuggestReorders(w http.ResponseWriter, r *http.Request) {
+	params := repository.ReorderParams{}
+	var err error
+	fields := make(map[string]string)
+	planningParam := func(key string, fallback, lowest int) int {
+		v, err := queryInt(r, key, fallback)
+		if err != nil || v < lowest || v > maxPlanningDays {
+			fields[key] = key + " must be between " + strconv.Itoa(lowest) + " and 365"
+		}
+		return v
+	}
+	params.LookbackDays = planningParam("lookback_days", defaultLookbackDays, 1)
+	params.CoverDays = planningParam("cover_days", defaultCoverDays, 0)
+	params.DefaultLeadTimeDays = planningParam("lead_time_days", defaultLeadTimeDays, 0)
+	if params.SupplierID, err = queryInt64Ptr(r, "supplier_id"); err != nil {
+		fields["supplier_id"] = "supplier_id must be an integer"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	suggestions, err := h.Repo.SuggestReorders(r.Context(), params)
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to compute reorder suggestions")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to compute reorder suggestions")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"lookback_days": params.LookbackDays,
+		"cover_days":    params.CoverDays,
+		"items":         suggestions,
+	})
+}
+
+func (h *PurchaseOrderHandler) idParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := mux.Vars(r)["purchase_order_id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("purchase_order_id", idStr).Msg("Invalid purchase_order_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid purchase_order_id path param")
+		return 0, false
+	}
+	return id, true
+}
+
+func parseDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultReconciliationLimit = 20
+	maxReconciliationLimit     = 100
+)
+
+type ReconciliationHandler struct {
+	Repo repository.ReconciliationRepository
+	Log  zerolog.Logger
+}
+
+func NewReconciliationHandler(repo repository.ReconciliationRepository, log zerolog.Logger) *ReconciliationHandler {
+	return &ReconciliationHandler{Repo: repo, Log: log}
+}
+
+// RunReconciliation reconciles stock on demand and returns the run with
+// every drifted product.
+func (h *ReconciliationHandler) RunReconciliation(w http.ResponseWriter, r *http.Request) {
+	run, err := h.Repo.Reconcile(r.Context(), repository.ReconciliationTriggerManual)
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to reconcile stock")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to reconcile stock")
+		return
+	}
+
+	h.Log.Info().
+		Int64("run_id", run.ID).
+		Int("products_checked", run.ProductsChecked).
+		Int("drifted", run.Drifted).
+		Msg("Stock reconciled")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(run)
+}
+
+func (h *ReconciliationHandler) ListReconciliations(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultReconciliationLimit)
+	if err != nil || limit <= 0 || limit > maxReconciliationLimit {
+		utils.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	runs, err := h.Repo.ListRuns(r.Context(), limit)
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list reconciliation runs")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list reconciliation runs")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(runs)
+}
+
+func (h *ReconciliationHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["run_id"]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("run_id", idStr).Msg("Invalid run_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid run_id path param")
+		return
+	}
+
+	run, err := h.Repo.GetRun(r.Context(), id)
+	if errors.Is(err, repository.ErrReconciliationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Reconciliation run not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("run_id", id).Msg("Failed to fetch reconciliation run")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch reconciliation run")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(run)
+}
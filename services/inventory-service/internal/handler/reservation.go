@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+type ReservationHandler struct {
+	Repo       repository.ReservationRepository
+	DefaultTTL time.Duration
+	Log        zerolog.Logger
+}
+
+func NewReservationHandler(repo repository.ReservationRepository, defaultTTL time.Duration, log zerolog.Logger) *ReservationHandler {
+	return &ReservationHandler{Repo: repo, DefaultTTL: defaultTTL, Log: log}
+}
+
+func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OrderID    int64 `json:"order_id"`
+		ProductID  int64 `json:"product_id"`
+		Quantity   int   `json:"quantity"`
+		TTLSeconds int   `json:"ttl_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid reservation payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if req.OrderID <= 0 || req.ProductID <= 0 || req.Quantity <= 0 || req.TTLSeconds < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "order_id, product_id and quantity must be positive")
+		return
+	}
+
+	ttl := h.DefaultTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	reservation, err := h.Repo.Reserve(r.Context(), req.OrderID, req.ProductID, req.Quantity, ttl)
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		h.Log.Warn().Int64("order_id", req.OrderID).Int64("product_id", req.ProductID).Msg("Insufficient stock to reserve")
+		utils.WriteError(w, http.StatusConflict, "Insufficient stock")
+		return
+	}
+	if errors.Is(err, repository.ErrReservationSettled) {
+		h.Log.Warn().Err(err).Int64("order_id", req.OrderID).Int64("product_id", req.ProductID).Msg("Reservation already settled")
+		utils.WriteError(w, http.StatusConflict, "Reservation for this order is already expired, committed or released")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("order_id", req.OrderID).Msg("Failed to reserve stock")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to reserve stock")
+		return
+	}
+
+	h.Log.Info().
+		Int64("order_id", reservation.OrderID).
+		Int64("product_id", reservation.ProductID).
+		Int("quantity", reservation.Quantity).
+		Time("expires_at", reservation.ExpiresAt).
+		Msg("Stock reserved")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(reservation)
+}
+
+func (h *ReservationHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := h.orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	reservations, err := h.Repo.GetByOrderID(r.Context(), orderID)
+	if err != nil {
+		h.Log.Error().Err(err).Int64("order_id", orderID).Msg("Failed to fetch reservations")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch reservations")
+		return
+	}
+	if len(reservations) == 0 {
+		utils.WriteError(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(reservations)
+}
+
+func (h *ReservationHandler) CommitReservation(w http.ResponseWriter, r *http.Request) {
+	h.settle(w, r, "commit", h.Repo.Commit)
+}
+
+func (h *ReservationHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	h.settle(w, r, "release", h.Repo.Release)
+}
+
+func (h *ReservationHandler) settle(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	op func(ctx context.Context, orderID int64) ([]repository.Reservation, error),
+) {
+	orderID, ok := h.orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	reservations, err := op(r.Context(), orderID)
+	switch {
+	case errors.Is(err, repository.ErrReservationNotFound):
+		utils.WriteError(w, http.StatusNotFound, "No active reservation for order")
+		return
+	case errors.Is(err, repository.ErrAlreadyProcessed):
+		utils.WriteError(w, http.StatusConflict, "Stock already committed for order")
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Int64("order_id", orderID).Str("action", action).Msg("Failed to settle reservation")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to "+action+" reservation")
+		return
+	}
+
+	h.Log.Info().Int64("order_id", orderID).Str("action", action).Msg("Reservation settled")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(reservations)
+}
+
+func (h *ReservationHandler) orderIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := mux.Vars(r)["order_id"]
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("order_id", idStr).Msg("Invalid order_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid order_id path param")
+		return 0, false
+	}
+	return orderID, true
+}
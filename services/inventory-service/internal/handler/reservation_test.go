@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+type fakeReservations struct {
+	repository.ReservationRepository
+	reserve func() (*repository.Reservation, error)
+}
+
+func (f fakeReservations) Reserve(context.Context, int64, int64, int, time.Duration) (*repository.Reservation, error) {
+	return f.reserve()
+}
+
+func TestCreateReservationRetryAfterSettlement(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *repository.Reservation
+		err  error
+		want int
+	}{
+		{"new or active hold", &repository.Reservation{ID: 3, OrderID: 1042, Status: repository.ReservationStatusReserved}, nil, http.StatusCreated},
+		{"settled hold", nil, fmt.Errorf("%w: order 1042 is expired", repository.ErrReservationSettled), http.StatusConflict},
+		{"insufficient stock", nil, repository.ErrInsufficientStock, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewReservationHandler(fakeReservations{reserve: func() (*repository.Reservation, error) {
+				return tt.res, tt.err
+			}}, time.Minute, zerolog.Nop())
+
+			body := strings.NewReader(`{"order_id": 1042, "product_id": 12, "quantity": 2}`)
+			rec := httptest.NewRecorder()
+			h.CreateReservation(rec, httptest.NewRequest(http.MethodPost, "/reservations", body))
+
+			if rec.Code != tt.want {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+var adjustmentReasons = map[string]bool{
+	"receipt":          true,
+	"damage":           true,
+	"count_correction": true,
+	"return":           true,
+}
+
+type StockHandler struct {
+	Repo repository.InventoryRepository
+	Log  zerolog.Logger
+}
+
+func NewStockHandler(repo repository.InventoryRepository, log zerolog.Logger) *StockHandler {
+	return &StockHandler{Repo: repo, Log: log}
+}
+
+func (h *StockHandler) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.productIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Delta      int    `json:"delta"`
+		Reason     string `json:"reason"`
+		Actor      string `json:"actor"`
+		Note       string `json:"note"`
+		LocationID *int64 `json:"location_id"`
+		LotNumber  string `json:"lot_number"`
+		ExpiresAt  string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid stock adjustment payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if req.Delta == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "delta must not be zero")
+		return
+	}
+	if !adjustmentReasons[req.Reason] {
+		utils.WriteError(w, http.StatusBadRequest, "reason must be one of receipt, damage, count_correction, return")
+		return
+	}
+	if strings.TrimSpace(req.Actor) == "" {
+		utils.WriteError(w, http.StatusBadRequest, "actor is required")
+		return
+	}
+	lot, msg := parseLotInput(req.LotNumber, req.ExpiresAt)
+	if msg != "" {
+		utils.WriteError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	entry, err := h.Repo.AdjustStock(r.Context(), repository.StockChange{
+		ProductID:  productID,
+		Change:     req.Delta,
+		Reason:     "adjustment." + req.Reason,
+		LocationID: req.LocationID,
+		Lot:        lot,
+		Actor:      req.Actor,
+		Note:       req.Note,
+	})
+	if errors.Is(err, repository.ErrProductNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if errors.Is(err, repository.ErrLocationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		utils.WriteError(w, http.StatusConflict, "Adjustment exceeds available stock")
+		return
+	}
+	if errors.Is(err, repository.ErrLotNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Lot not found")
+		return
+	}
+	if errors.Is(err, repository.ErrLotQuarantined) || errors.Is(err, repository.ErrLotExpiryMismatch) {
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to adjust stock")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to adjust stock")
+		return
+	}
+
+	h.Log.Info().
+		Int64("product_id", productID).
+		Int("delta", req.Delta).
+		Str("reason", req.Reason).
+		Str("actor", req.Actor).
+		Msg("Stock adjusted")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+func (h *StockHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.productIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := queryInt(r, "limit", defaultHistoryLimit)
+	if err != nil || limit <= 0 || limit > maxHistoryLimit {
+		utils.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	history, total, err := h.Repo.GetStockHistory(r.Context(), productID, limit, offset)
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to fetch stock history")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch stock history")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":  history,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *StockHandler) productIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := mux.Vars(r)["product_id"]
+	productID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str("product_id", idStr).Msg("Invalid product_id path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid product_id path param")
+		return 0, false
+	}
+	return productID, true
+}
+
+func queryInt(r *http.Request, key string, fallback int) (int, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(val)
+}
+
+func queryInt64Ptr(r *http.Request, key string) (*int64, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
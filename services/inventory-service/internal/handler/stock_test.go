@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+type fakeInventory struct {
+	repository.InventoryRepository
+	err error
+}
+
+func (f fakeInventory) AdjustStock(context.Context, repository.StockChange) (*repository.StockLog, error) {
+	return nil, f.err
+}
+
+func TestCreateAdjustmentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unknown product", repository.ErrProductNotFound, http.StatusNotFound},
+		{"insufficient stock", repository.ErrInsufficientStock, http.StatusConflict},
+		{"unknown location", repository.ErrLocationNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewStockHandler(fakeInventory{err: tt.err}, zerolog.Nop())
+			body := strings.NewReader(`{"delta": -3, "reason": "damage", "actor": "ops@example.com"}`)
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/products/404/stock-adjustments", body), map[string]string{"product_id": "404"})
+			rec := httptest.NewRecorder()
+			h.CreateAdjustment(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
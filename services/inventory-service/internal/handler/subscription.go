@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+const maxSubscriptionTTLDays = 365
+
+type SubscriptionHandler struct {
+	Repo       repository.SubscriptionRepository
+	DefaultTTL time.Duration
+	Log        zerolog.Logger
+}
+
+func NewSubscriptionHandler(repo repository.SubscriptionRepository, defaultTTL time.Duration, log zerolog.Logger) *SubscriptionHandler {
+	return &SubscriptionHandler{Repo: repo, DefaultTTL: defaultTTL, Log: log}
+}
+
+// Subscribe registers a user for a one-off notification when the product
+// goes from out of stock back to available. Subscribing again renews the
+// expiry of the open subscription.
+func (h *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.pathID(w, r, "product_id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID  int64 `json:"user_id"`
+		TTLDays int   `json:"ttl_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid subscription payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	fields := make(map[string]string)
+	if req.UserID <= 0 {
+		fields["user_id"] = "user_id must be positive"
+	}
+	if req.TTLDays < 0 || req.TTLDays > maxSubscriptionTTLDays {
+		fields["ttl_days"] = "ttl_days must be between 1 and 365"
+	}
+	if len(fields) > 0 {
+		utils.WriteValidationError(w, fields)
+		return
+	}
+
+	ttl := h.DefaultTTL
+	if req.TTLDays > 0 {
+		ttl = time.Duration(req.TTLDays) * 24 * time.Hour
+	}
+
+	sub, err := h.Repo.Subscribe(r.Context(), productID, req.UserID, ttl)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Int64("user_id", req.UserID).Msg("Failed to subscribe")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to subscribe")
+		return
+	}
+
+	h.Log.Info().
+		Int64("product_id", productID).
+		Int64("user_id", req.UserID).
+		Time("expires_at", sub.ExpiresAt).
+		Msg("Back-in-stock subscription registered")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(sub)
+}
+
+func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.pathID(w, r, "product_id")
+	if !ok {
+		return
+	}
+
+	subs, err := h.Repo.ListByProduct(r.Context(), productID)
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to list subscriptions")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list subscriptions")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(subs)
+}
+
+func (h *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.pathID(w, r, "product_id")
+	if !ok {
+		return
+	}
+	userID, ok := h.pathID(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	err := h.Repo.Unsubscribe(r.Context(), productID, userID)
+	if errors.Is(err, repository.ErrSubscriptionNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "No active subscription")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Int64("user_id", userID).Msg("Failed to unsubscribe")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to unsubscribe")
+		return
+	}
+
+	h.Log.Info().Int64("product_id", productID).Int64("user_id", userID).Msg("Back-in-stock subscription cancelled")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SubscriptionHandler) pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	idStr := mux.Vars(r)[name]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str(name, idStr).Msgf("Invalid %s path param", name)
+		utils.WriteError(w, http.StatusBadRequest, "Invalid "+name+" path param")
+		return 0, false
+	}
+	return id, true
+}
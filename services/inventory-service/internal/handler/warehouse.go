@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+type WarehouseHandler struct {
+	Repo repository.WarehouseRepository
+	Log  zerolog.Logger
+}
+
+func NewWarehouseHandler(repo repository.WarehouseRepository, log zerolog.Logger) *WarehouseHandler {
+	return &WarehouseHandler{Repo: repo, Log: log}
+}
+
+func (h *WarehouseHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code     string `json:"code"`
+		Name     string `json:"name"`
+		Priority int    `json:"priority"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid warehouse payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" || strings.TrimSpace(req.Name) == "" {
+		utils.WriteError(w, http.StatusBadRequest, "code and name are required")
+		return
+	}
+
+	warehouse, err := h.Repo.CreateWarehouse(r.Context(), req.Code, req.Name, req.Priority)
+	if errors.Is(err, repository.ErrDuplicateWarehouse) {
+		utils.WriteError(w, http.StatusConflict, "A warehouse with this code already exists")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Str("code", req.Code).Msg("Failed to create warehouse")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create warehouse")
+		return
+	}
+
+	h.Log.Info().Int64("warehouse_id", warehouse.ID).Str("code", warehouse.Code).Msg("Warehouse created")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(warehouse)
+}
+
+func (h *WarehouseHandler) ListWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.Repo.ListWarehouses(r.Context())
+	if err != nil {
+		h.Log.Error().Err(err).Msg("Failed to list warehouses")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list warehouses")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(warehouses)
+}
+
+func (h *WarehouseHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	warehouseID, ok := h.idParam(w, r, "warehouse_id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid location payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" || strings.TrimSpace(req.Name) == "" {
+		utils.WriteError(w, http.StatusBadRequest, "code and name are required")
+		return
+	}
+
+	location, err := h.Repo.CreateLocation(r.Context(), warehouseID, req.Code, req.Name)
+	if errors.Is(err, repository.ErrWarehouseNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Warehouse not found")
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicateLocation) {
+		utils.WriteError(w, http.StatusConflict, "A location with this code already exists in the warehouse")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("warehouse_id", warehouseID).Msg("Failed to create location")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to create location")
+		return
+	}
+
+	h.Log.Info().Int64("location_id", location.ID).Int64("warehouse_id", warehouseID).Msg("Location created")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(location)
+}
+
+func (h *WarehouseHandler) ListLocations(w http.ResponseWriter, r *http.Request) {
+	warehouseID, ok := h.idParam(w, r, "warehouse_id")
+	if !ok {
+		return
+	}
+
+	locations, err := h.Repo.ListLocations(r.Context(), warehouseID)
+	if err != nil {
+		h.Log.Error().Err(err).Int64("warehouse_id", warehouseID).Msg("Failed to list locations")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list locations")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(locations)
+}
+
+func (h *WarehouseHandler) GetStockByLocation(w http.ResponseWriter, r *http.Request) {
+	productID, ok := h.idParam(w, r, "product_id")
+	if !ok {
+		return
+	}
+
+	stock, err := h.Repo.GetStockByLocation(r.Context(), productID)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		h.Log.Error().Err(err).Int64("product_id", productID).Msg("Failed to fetch stock by location")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch stock by location")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(stock)
+}
+
+func (h *WarehouseHandler) TransferStock(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProductID      int64  `json:"product_id"`
+		FromLocationID *int64 `json:"from_location_id"`
+		ToLocationID   *int64 `json:"to_location_id"`
+		Quantity       int    `json:"quantity"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Warn().Err(err).Msg("Invalid transfer payload")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if req.ProductID <= 0 || req.Quantity <= 0 {
+		utils.WriteError(w, http.StatusBadRequest, "product_id and quantity must be positive")
+		return
+	}
+	if req.FromLocationID == nil && req.ToLocationID == nil {
+		utils.WriteError(w, http.StatusBadRequest, "from_location_id or to_location_id is required")
+		return
+	}
+	if req.FromLocationID != nil && req.ToLocationID != nil && *req.FromLocationID == *req.ToLocationID {
+		utils.WriteError(w, http.StatusBadRequest, "from_location_id and to_location_id must differ")
+		return
+	}
+
+	transfer, err := h.Repo.Transfer(r.Context(), req.ProductID, req.FromLocationID, req.ToLocationID, req.Quantity)
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Product not found")
+		return
+	case errors.Is(err, repository.ErrLocationNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Location not found")
+		return
+	case errors.Is(err, repository.ErrInsufficientStock):
+		utils.WriteError(w, http.StatusConflict, "Insufficient stock at source location")
+		return
+	case err != nil:
+		h.Log.Error().Err(err).Int64("product_id", req.ProductID).Msg("Failed to transfer stock")
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to transfer stock")
+		return
+	}
+
+	h.Log.Info().
+		Int64("transfer_id", transfer.ID).
+		Int64("product_id", transfer.ProductID).
+		Int("quantity", transfer.Quantity).
+		Msg("Stock transferred")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(transfer)
+}
+
+func (h *WarehouseHandler) idParam(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	idStr := mux.Vars(r)[name]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.Log.Warn().Str(name, idStr).Msg("Invalid path param")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid "+name+" path param")
+		return 0, false
+	}
+	return id, true
+}
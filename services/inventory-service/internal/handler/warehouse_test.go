@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cemrezr/ecommerce-system/inventory-service/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+type fakeWarehouses struct {
+	repository.WarehouseRepository
+	err error
+}
+
+func (f fakeWarehouses) CreateWarehouse(_ context.Context, code, name string, priority int) (*repository.Warehouse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &repository.Warehouse{ID: 1, Code: code, Name: name, Priority: priority}, nil
+}
+
+func (f fakeWarehouses) CreateLocation(_ context.Context, warehouseID int64, code, name string) (*repository.Location, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &repository.Location{ID: 1, WarehouseID: warehouseID, Code: code, Name: name}, nil
+}
+
+func (f fakeWarehouses) Transfer(_ context.Context, productID int64, from, to *int64, quantity int) (*repository.StockTransfer, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &repository.StockTransfer{ID: 1, ProductID: productID, FromLocationID: from, ToLocationID: to, Quantity: quantity}, nil
+}
+
+func TestCreateWarehouseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"created", nil, http.StatusCreated},
+		{"duplicate code", repository.ErrDuplicateWarehouse, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewWarehouseHandler(fakeWarehouses{err: tt.err}, zerolog.Nop())
+			rec := httptest.NewRecorder()
+			h.CreateWarehouse(rec, httptest.NewRequest(http.MethodPost, "/warehouses", strings.NewReader(`{"code": "IST", "name": "Istanbul"}`)))
+			if rec.Code != tt.want {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateLocationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"created", nil, http.StatusCreated},
+		{"unknown warehouse", repository.ErrWarehouseNotFound, http.StatusNotFound},
+		{"duplicate code", repository.ErrDuplicateLocation, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewWarehouseHandler(fakeWarehouses{err: tt.err}, zerolog.Nop())
+			req := httptest.NewRequest(http.MethodPost, "/warehouses/9/locations", strings.NewReader(`{"code": "A-01", "name": "Aisle 1"}`))
+			req = mux.SetURLVars(req, map[string]string{"warehouse_id": "9"})
+			rec := httptest.NewRecorder()
+			h.CreateLocation(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferStockErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"transferred", nil, http.StatusCreated},
+		{"unknown product", repository.ErrProductNotFound, http.StatusNotFound},
+		{"unknown location", repository.ErrLocationNotFound, http.StatusNotFound},
+		{"insufficient stock", repository.ErrInsufficientStock, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewWarehouseHandler(fakeWarehouses{err: tt.err}, zerolog.Nop())
+			body := strings.NewReader(`{"product_id": 1, "from_location_id": 3, "to_location_id": 404, "quantity": 2}`)
+			rec := httptest.NewRecorder()
+			h.TransferStock(rec, httptest.NewRequest(http.MethodPost, "/stock-transfers", body))
+			if rec.Code != tt.want {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
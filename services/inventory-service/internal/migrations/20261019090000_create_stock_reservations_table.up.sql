@@ -0,0 +1,20 @@
+ALTER TABLE inventory
+    ADD COLUMN reserved INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+                                                  id SERIAL PRIMARY KEY,
+                                                  order_id BIGINT NOT NULL,
+                                                  product_id BIGINT NOT NULL,
+                                                  quantity INT NOT NULL,
+                                                  status TEXT NOT NULL DEFAULT 'reserved',
+                                                  expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                                                  created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                  updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX unique_order_reservation
+    ON stock_reservations (order_id, product_id);
+
+CREATE INDEX idx_stock_reservations_expiry
+    ON stock_reservations (expires_at)
+    WHERE status = 'reserved';
@@ -0,0 +1,8 @@
+ALTER TABLE stock_logs
+DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS order_allocations;
+DROP TABLE IF EXISTS stock_transfers;
+DROP TABLE IF EXISTS location_stock;
+DROP TABLE IF EXISTS locations;
+DROP TABLE IF EXISTS warehouses;
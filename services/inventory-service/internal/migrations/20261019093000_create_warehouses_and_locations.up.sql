@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+                                          id SERIAL PRIMARY KEY,
+                                          code TEXT NOT NULL UNIQUE,
+                                          name TEXT NOT NULL,
+                                          priority INT NOT NULL DEFAULT 0,
+                                          created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS locations (
+                                         id SERIAL PRIMARY KEY,
+                                         warehouse_id BIGINT NOT NULL REFERENCES warehouses (id),
+                                         code TEXT NOT NULL,
+                                         name TEXT NOT NULL,
+                                         created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                         UNIQUE (warehouse_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS location_stock (
+                                              location_id BIGINT NOT NULL REFERENCES locations (id),
+                                              product_id BIGINT NOT NULL,
+                                              quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+                                              updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                              PRIMARY KEY (location_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS stock_transfers (
+                                               id SERIAL PRIMARY KEY,
+                                               product_id BIGINT NOT NULL,
+                                               from_location_id BIGINT REFERENCES locations (id),
+                                               to_location_id BIGINT REFERENCES locations (id),
+                                               quantity INT NOT NULL CHECK (quantity > 0),
+                                               created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS order_allocations (
+                                                 id SERIAL PRIMARY KEY,
+                                                 order_id BIGINT NOT NULL,
+                                                 product_id BIGINT NOT NULL,
+                                                 location_id BIGINT NOT NULL REFERENCES locations (id),
+                                                 quantity INT NOT NULL,
+                                                 created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_allocations_order ON order_allocations (order_id, product_id);
+
+ALTER TABLE stock_logs
+    ADD COLUMN location_id BIGINT;
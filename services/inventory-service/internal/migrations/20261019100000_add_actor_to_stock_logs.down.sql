@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_stock_logs_product_history;
+
+ALTER TABLE stock_logs
+DROP COLUMN IF EXISTS note,
+DROP COLUMN IF EXISTS actor;
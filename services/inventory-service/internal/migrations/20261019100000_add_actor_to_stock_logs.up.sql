@@ -0,0 +1,6 @@
+ALTER TABLE stock_logs
+    ADD COLUMN actor TEXT,
+    ADD COLUMN note TEXT;
+
+CREATE INDEX idx_stock_logs_product_history
+    ON stock_logs (product_id, id DESC);
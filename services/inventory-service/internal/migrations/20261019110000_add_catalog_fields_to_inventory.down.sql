@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS unique_inventory_sku;
+
+ALTER TABLE inventory
+DROP COLUMN IF EXISTS status,
+DROP COLUMN IF EXISTS attributes,
+DROP COLUMN IF EXISTS currency,
+DROP COLUMN IF EXISTS price_minor,
+DROP COLUMN IF EXISTS description,
+DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,14 @@
+ALTER TABLE inventory
+    ADD COLUMN sku TEXT,
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN price_minor BIGINT NOT NULL DEFAULT 0 CHECK (price_minor >= 0),
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'archived'));
+
+UPDATE inventory SET sku = 'SKU-' || product_id WHERE sku IS NULL;
+
+ALTER TABLE inventory
+    ALTER COLUMN sku SET NOT NULL;
+
+CREATE UNIQUE INDEX unique_inventory_sku ON inventory (sku);
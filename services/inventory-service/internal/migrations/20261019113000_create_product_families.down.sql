@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS unique_variant_options;
+
+ALTER TABLE inventory
+DROP COLUMN IF EXISTS price_override_minor,
+DROP COLUMN IF EXISTS options,
+DROP COLUMN IF EXISTS family_id;
+
+DROP TABLE IF EXISTS product_families;
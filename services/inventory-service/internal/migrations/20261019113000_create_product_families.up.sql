@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS product_families (
+                                                id SERIAL PRIMARY KEY,
+                                                name TEXT NOT NULL,
+                                                description TEXT NOT NULL DEFAULT '',
+                                                option_names JSONB NOT NULL DEFAULT '[]'::jsonb,
+                                                base_price_minor BIGINT NOT NULL DEFAULT 0 CHECK (base_price_minor >= 0),
+                                                currency CHAR(3) NOT NULL DEFAULT 'USD',
+                                                status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'archived')),
+                                                created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A variant is an inventory row that belongs to a family. Its price_minor is
+-- price_override_minor when set and the family base price otherwise.
+ALTER TABLE inventory
+    ADD COLUMN family_id BIGINT REFERENCES product_families (id),
+    ADD COLUMN options JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN price_override_minor BIGINT CHECK (price_override_minor >= 0);
+
+CREATE UNIQUE INDEX unique_variant_options ON inventory (family_id, options) WHERE family_id IS NOT NULL;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_inventory_price;
+DROP INDEX IF EXISTS idx_inventory_search;
+DROP INDEX IF EXISTS idx_inventory_category;
+
+ALTER TABLE inventory
+DROP COLUMN IF EXISTS search_vector,
+DROP COLUMN IF EXISTS category_id;
+
+DROP INDEX IF EXISTS idx_categories_parent;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS categories (
+                                          id SERIAL PRIMARY KEY,
+                                          parent_id BIGINT REFERENCES categories (id),
+                                          name TEXT NOT NULL,
+                                          slug TEXT NOT NULL UNIQUE,
+                                          created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_categories_parent ON categories (parent_id);
+
+ALTER TABLE inventory
+    ADD COLUMN category_id BIGINT REFERENCES categories (id),
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(product_name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_inventory_category ON inventory (category_id);
+CREATE INDEX idx_inventory_search ON inventory USING GIN (search_vector);
+CREATE INDEX idx_inventory_price ON inventory (price_minor, product_id);
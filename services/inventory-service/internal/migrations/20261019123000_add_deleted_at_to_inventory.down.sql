@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_inventory_live;
+
+ALTER TABLE inventory
+DROP COLUMN IF EXISTS deleted_at;
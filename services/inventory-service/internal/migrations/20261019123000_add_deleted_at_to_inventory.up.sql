@@ -0,0 +1,6 @@
+-- Products are soft-deleted so that orders and stock logs referring to them
+-- keep resolving, e.g. when a cancellation restores stock later.
+ALTER TABLE inventory
+    ADD COLUMN deleted_at TIMESTAMP WITHOUT TIME ZONE;
+
+CREATE INDEX idx_inventory_live ON inventory (product_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS stock_alerts;
+
+ALTER TABLE inventory
+    DROP COLUMN IF EXISTS stock_state,
+    DROP COLUMN IF EXISTS reorder_threshold;
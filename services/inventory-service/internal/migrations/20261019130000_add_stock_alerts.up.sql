@@ -0,0 +1,23 @@
+-- stock_state remembers which side of the reorder threshold a product was last
+-- seen on, so an alert is queued only when a stock change crosses it.
+ALTER TABLE inventory
+    ADD COLUMN reorder_threshold INT NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0),
+    ADD COLUMN stock_state TEXT NOT NULL DEFAULT 'in_stock'
+        CHECK (stock_state IN ('in_stock', 'low_stock', 'out_of_stock'));
+
+UPDATE inventory SET stock_state = 'out_of_stock' WHERE stock <= 0;
+
+CREATE TABLE IF NOT EXISTS stock_alerts (
+                                            id SERIAL PRIMARY KEY,
+                                            product_id INT NOT NULL REFERENCES inventory (product_id),
+                                            previous_state TEXT NOT NULL,
+                                            state TEXT NOT NULL,
+                                            stock INT NOT NULL,
+                                            threshold INT NOT NULL,
+                                            created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                            published_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE INDEX idx_stock_alerts_pending
+    ON stock_alerts (id)
+    WHERE published_at IS NULL;
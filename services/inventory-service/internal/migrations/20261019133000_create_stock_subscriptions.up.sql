@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+                                                   id SERIAL PRIMARY KEY,
+                                                   product_id INT NOT NULL REFERENCES inventory (product_id),
+                                                   user_id BIGINT NOT NULL,
+                                                   status TEXT NOT NULL DEFAULT 'active'
+                                                       CHECK (status IN ('active', 'notified', 'cancelled')),
+                                                   alert_id BIGINT REFERENCES stock_alerts (id),
+                                                   expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                                                   notified_at TIMESTAMP WITHOUT TIME ZONE,
+                                                   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- One open subscription per user and product; subscribing again renews it.
+CREATE UNIQUE INDEX unique_active_stock_subscription
+    ON stock_subscriptions (product_id, user_id)
+    WHERE status = 'active';
+
+CREATE INDEX idx_stock_subscriptions_alert
+    ON stock_subscriptions (alert_id)
+    WHERE alert_id IS NOT NULL;
+
+-- event_outbox holds events written in the same transaction as the change
+-- they announce, such as inventory.back_in_stock, until the relay has
+-- published them. Stock alerts have their own table; every other event
+-- queued this way goes here, one row per event.
+CREATE TABLE IF NOT EXISTS event_outbox (
+                                            id BIGSERIAL PRIMARY KEY,
+                                            event_type TEXT NOT NULL,
+                                            event_version TEXT NOT NULL,
+                                            payload JSONB NOT NULL,
+                                            created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                            published_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE INDEX idx_event_outbox_pending
+    ON event_outbox (id)
+    WHERE published_at IS NULL;
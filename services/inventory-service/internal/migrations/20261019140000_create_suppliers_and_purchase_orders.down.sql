@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_stock_logs_consumption;
+DROP TABLE IF EXISTS purchase_order_receipts;
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS suppliers;
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+                                         id SERIAL PRIMARY KEY,
+                                         code TEXT NOT NULL UNIQUE,
+                                         name TEXT NOT NULL,
+                                         email TEXT NOT NULL DEFAULT '',
+                                         lead_time_days INT NOT NULL DEFAULT 7 CHECK (lead_time_days >= 0),
+                                         created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+                                               id SERIAL PRIMARY KEY,
+                                               supplier_id BIGINT NOT NULL REFERENCES suppliers (id),
+                                               status TEXT NOT NULL DEFAULT 'open'
+                                                   CHECK (status IN ('open', 'partially_received', 'received', 'cancelled')),
+                                               expected_at DATE,
+                                               note TEXT NOT NULL DEFAULT '',
+                                               created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                               updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+                                                    id SERIAL PRIMARY KEY,
+                                                    purchase_order_id BIGINT NOT NULL REFERENCES purchase_orders (id),
+                                                    product_id BIGINT NOT NULL,
+                                                    quantity_ordered INT NOT NULL CHECK (quantity_ordered > 0),
+                                                    quantity_received INT NOT NULL DEFAULT 0
+                                                        CHECK (quantity_received >= 0 AND quantity_received <= quantity_ordered),
+                                                    unit_cost_minor BIGINT NOT NULL DEFAULT 0 CHECK (unit_cost_minor >= 0),
+                                                    expected_at DATE,
+                                                    UNIQUE (purchase_order_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_receipts (
+                                                       id SERIAL PRIMARY KEY,
+                                                       purchase_order_id BIGINT NOT NULL REFERENCES purchase_orders (id),
+                                                       line_id BIGINT NOT NULL REFERENCES purchase_order_lines (id),
+                                                       product_id BIGINT NOT NULL,
+                                                       quantity INT NOT NULL CHECK (quantity > 0),
+                                                       location_id BIGINT REFERENCES locations (id),
+                                                       stock_log_id BIGINT NOT NULL,
+                                                       actor TEXT NOT NULL,
+                                                       received_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_purchase_order_lines_product
+    ON purchase_order_lines (product_id);
+
+CREATE INDEX idx_stock_logs_consumption
+    ON stock_logs (created_at, product_id)
+    WHERE reason IN ('order.created', 'order.cancelled');
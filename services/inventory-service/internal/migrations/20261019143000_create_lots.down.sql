@@ -0,0 +1,5 @@
+ALTER TABLE purchase_order_receipts
+    DROP COLUMN IF EXISTS lot_id;
+
+DROP TABLE IF EXISTS lot_allocations;
+DROP TABLE IF EXISTS lots;
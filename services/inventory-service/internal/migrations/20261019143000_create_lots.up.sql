@@ -0,0 +1,38 @@
+-- Lots break a product's on-hand stock down by batch, like location_stock
+-- does by place. Quarantined lots hold expired units that have already been
+-- taken out of inventory.stock.
+CREATE TABLE IF NOT EXISTS lots (
+                                    id SERIAL PRIMARY KEY,
+                                    product_id BIGINT NOT NULL,
+                                    lot_number TEXT NOT NULL,
+                                    expires_at DATE,
+                                    received_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                    quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+                                    status TEXT NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'quarantined')),
+                                    quarantined_at TIMESTAMP WITHOUT TIME ZONE,
+                                    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                    UNIQUE (product_id, lot_number)
+);
+
+CREATE INDEX idx_lots_fefo
+    ON lots (product_id, expires_at, received_at)
+    WHERE status = 'available';
+
+CREATE INDEX idx_lots_expiry
+    ON lots (expires_at)
+    WHERE status = 'available';
+
+CREATE TABLE IF NOT EXISTS lot_allocations (
+                                               id SERIAL PRIMARY KEY,
+                                               order_id BIGINT NOT NULL,
+                                               product_id BIGINT NOT NULL,
+                                               lot_id BIGINT NOT NULL REFERENCES lots (id),
+                                               quantity INT NOT NULL,
+                                               created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_lot_allocations_order ON lot_allocations (order_id, product_id);
+CREATE INDEX idx_lot_allocations_lot ON lot_allocations (lot_id);
+
+ALTER TABLE purchase_order_receipts
+    ADD COLUMN lot_id BIGINT REFERENCES lots (id);
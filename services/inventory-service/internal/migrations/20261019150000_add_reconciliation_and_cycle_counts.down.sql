@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS cycle_count_lines;
+DROP TABLE IF EXISTS cycle_counts;
+DROP TABLE IF EXISTS reconciliation_drifts;
+DROP TABLE IF EXISTS reconciliation_runs;
+DELETE FROM stock_logs WHERE reason = 'reconciliation.baseline';
@@ -0,0 +1,53 @@
+-- Products created before their initial stock was logged would all show up
+-- as drift. Book whatever stock_logs cannot explain today as an opening
+-- balance so reconciliation starts from a clean baseline.
+INSERT INTO stock_logs (product_id, change, reason, note)
+SELECT i.product_id, i.stock - COALESCE(l.total, 0), 'reconciliation.baseline', 'opening balance'
+FROM inventory i
+LEFT JOIN (SELECT product_id, SUM(change) AS total FROM stock_logs GROUP BY product_id) l
+    ON l.product_id = i.product_id
+WHERE i.stock <> COALESCE(l.total, 0);
+
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+                                                   id SERIAL PRIMARY KEY,
+                                                   trigger TEXT NOT NULL CHECK (trigger IN ('scheduled', 'manual')),
+                                                   products_checked INT NOT NULL DEFAULT 0,
+                                                   drifted INT NOT NULL DEFAULT 0,
+                                                   started_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                   finished_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_drifts (
+                                                     id SERIAL PRIMARY KEY,
+                                                     run_id BIGINT NOT NULL REFERENCES reconciliation_runs (id) ON DELETE CASCADE,
+                                                     product_id BIGINT NOT NULL,
+                                                     stock INT NOT NULL,
+                                                     expected_stock INT NOT NULL,
+                                                     drift INT NOT NULL
+);
+
+CREATE INDEX idx_reconciliation_drifts_run ON reconciliation_drifts (run_id);
+
+CREATE TABLE IF NOT EXISTS cycle_counts (
+                                            id SERIAL PRIMARY KEY,
+                                            status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'posted', 'cancelled')),
+                                            location_id BIGINT REFERENCES locations (id),
+                                            opened_by TEXT NOT NULL,
+                                            posted_by TEXT,
+                                            note TEXT NOT NULL DEFAULT '',
+                                            created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                            updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                            posted_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS cycle_count_lines (
+                                                 id SERIAL PRIMARY KEY,
+                                                 cycle_count_id BIGINT NOT NULL REFERENCES cycle_counts (id),
+                                                 product_id INT NOT NULL REFERENCES inventory (product_id),
+                                                 expected INT,
+                                                 counted INT CHECK (counted >= 0),
+                                                 counted_by TEXT,
+                                                 counted_at TIMESTAMP WITHOUT TIME ZONE,
+                                                 stock_log_id BIGINT,
+                                                 UNIQUE (cycle_count_id, product_id)
+);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_event_outbox_pending;
+CREATE INDEX idx_event_outbox_pending
+    ON event_outbox (id)
+    WHERE published_at IS NULL;
+
+DROP INDEX IF EXISTS idx_stock_alerts_pending;
+CREATE INDEX idx_stock_alerts_pending
+    ON stock_alerts (id)
+    WHERE published_at IS NULL;
+
+ALTER TABLE event_outbox DROP COLUMN IF EXISTS quarantined_at;
+ALTER TABLE stock_alerts DROP COLUMN IF EXISTS quarantined_at;
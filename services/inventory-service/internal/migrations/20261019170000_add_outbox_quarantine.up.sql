@@ -0,0 +1,14 @@
+-- A queued event that fails validation against its contract is quarantined
+-- instead of published and is not relayed again.
+ALTER TABLE stock_alerts ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMP WITHOUT TIME ZONE;
+ALTER TABLE event_outbox ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMP WITHOUT TIME ZONE;
+
+DROP INDEX IF EXISTS idx_stock_alerts_pending;
+CREATE INDEX idx_stock_alerts_pending
+    ON stock_alerts (id)
+    WHERE published_at IS NULL AND quarantined_at IS NULL;
+
+DROP INDEX IF EXISTS idx_event_outbox_pending;
+CREATE INDEX idx_event_outbox_pending
+    ON event_outbox (id)
+    WHERE published_at IS NULL AND quarantined_at IS NULL;
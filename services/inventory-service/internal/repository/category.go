@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrDuplicateSlug    = errors.New("category slug already exists")
+)
+
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, name, slug string, parentID *int64) (*Category, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+}
+
+type Category struct {
+	ID        int64     `db:"id" json:"id"`
+	ParentID  *int64    `db:"parent_id" json:"parent_id"`
+	Name      string    `db:"name" json:"name"`
+	Slug      string    `db:"slug" json:"slug"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type PostgresCategoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresCategoryRepository(db *sqlx.DB) *PostgresCategoryRepository {
+	return &PostgresCategoryRepository{db: db}
+}
+
+func (r *PostgresCategoryRepository) CreateCategory(ctx context.Context, name, slug string, parentID *int64) (*Category, error) {
+	if err := checkCategory(ctx, r.db, parentID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO categories (name, slug, parent_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING id, parent_id, name, slug, created_at
+	`
+
+	var category Category
+	err := r.db.GetContext(ctx, &category, query, name, slug, parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDuplicateSlug
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return &category, nil
+}
+
+func (r *PostgresCategoryRepository) ListCategories(ctx context.Context) ([]Category, error) {
+	categories := []Category{}
+	query := `SELECT id, parent_id, name, slug, created_at FROM categories ORDER BY name, id`
+	if err := r.db.SelectContext(ctx, &categories, query); err != nil {
+		return nil, fmt.Errorf("list categories failed: %w", err)
+	}
+	return categories, nil
+}
+
+// checkCategory returns ErrCategoryNotFound when categoryID is set but does
+// not exist. Categories are never deleted, so the check cannot go stale.
+func checkCategory(ctx context.Context, q sqlx.QueryerContext, categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	var exists bool
+	if err := sqlx.GetContext(ctx, q, &exists, `SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1)`, *categoryID); err != nil {
+		return fmt.Errorf("failed to check category: %w", err)
+	}
+	if !exists {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
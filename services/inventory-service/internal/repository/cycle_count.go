@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	CycleCountStatusOpen      = "open"
+	CycleCountStatusPosted    = "posted"
+	CycleCountStatusCancelled = "cancelled"
+)
+
+var (
+	ErrCycleCountNotFound   = errors.New("cycle count not found")
+	ErrCycleCountClosed     = errors.New("cycle count is already posted or cancelled")
+	ErrCycleCountIncomplete = errors.New("cycle count has uncounted lines")
+	ErrCountLineNotFound    = errors.New("product is not part of the cycle count")
+)
+
+type CycleCountRepository interface {
+	OpenCycleCount(ctx context.Context, in CycleCountInput) (*CycleCount, error)
+	GetCycleCount(ctx context.Context, id int64) (*CycleCount, error)
+	ListCycleCounts(ctx context.Context, status string) ([]CycleCount, error)
+	SubmitCounts(ctx context.Context, id int64, actor string, counts []CountInput) (*CycleCount, error)
+	PostCycleCount(ctx context.Context, id int64, actor string) (*CycleCount, []StockLog, error)
+	CancelCycleCount(ctx context.Context, id int64) (*CycleCount, error)
+}
+
+// CycleCountInput opens a count of ProductIDs. With a LocationID the count
+// covers only that location's stock; otherwise it covers the product total.
+type CycleCountInput struct {
+	Actor      string
+	Note       string
+	LocationID *int64
+	ProductIDs []int64
+}
+
+type CountInput struct {
+	ProductID int64
+	Counted   int
+}
+
+type CycleCount struct {
+	ID         int64            `db:"id" json:"id"`
+	Status     string           `db:"status" json:"status"`
+	LocationID *int64           `db:"location_id" json:"location_id"`
+	OpenedBy   string           `db:"opened_by" json:"opened_by"`
+	PostedBy   *string          `db:"posted_by" json:"posted_by,omitempty"`
+	Note       string           `db:"note" json:"note"`
+	CreatedAt  time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time        `db:"updated_at" json:"updated_at"`
+	PostedAt   *time.Time       `db:"posted_at" json:"posted_at,omitempty"`
+	Lines      []CycleCountLine `db:"-" json:"lines"`
+}
+
+// CycleCountLine compares a counted quantity with the system quantity read
+// when the count was submitted. Variance is Counted - Expected.
+type CycleCountLine struct {
+	ID          int64      `db:"id" json:"id"`
+	ProductID   int64      `db:"product_id" json:"product_id"`
+	SKU         string     `db:"sku" json:"sku"`
+	ProductName string     `db:"product_name" json:"product_name"`
+	Expected    *int       `db:"expected" json:"expected"`
+	Counted     *int       `db:"counted" json:"counted"`
+	Variance    *int       `db:"variance" json:"variance"`
+	CountedBy   *string    `db:"counted_by" json:"counted_by,omitempty"`
+	CountedAt   *time.Time `db:"counted_at" json:"counted_at,omitempty"`
+	StockLogID  *int64     `db:"stock_log_id" json:"stock_log_id,omitempty"`
+}
+
+const cycleCountColumns = `id, status, location_id, opened_by, posted_by, note, created_at, updated_at, posted_at`
+
+type PostgresCycleCountRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresCycleCountRepository(db *sqlx.DB) *PostgresCycleCountRepository {
+	return &PostgresCycleCountRepository{db: db}
+}
+
+func (r *PostgresCycleCountRepository) OpenCycleCount(ctx context.Context, in CycleCountInput) (*CycleCount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin cycle count transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if in.LocationID != nil {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM locations WHERE id = $1)`, *in.LocationID); err != nil {
+			return nil, fmt.Errorf("failed to check location: %w", err)
+		}
+		if !exists {
+			return nil, ErrLocationNotFound
+		}
+	}
+
+	var id int64
+	if err := tx.GetContext(ctx, &id, `
+		INSERT INTO cycle_counts (location_id, opened_by, note)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, in.LocationID, in.Actor, in.Note); err != nil {
+		return nil, fmt.Errorf("failed to insert cycle count: %w", err)
+	}
+
+	for _, productID := range in.ProductIDs {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO cycle_count_lines (cycle_count_id, product_id)
+			SELECT $1, product_id FROM inventory WHERE product_id = $2 AND deleted_at IS NULL
+		`, id, productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert cycle count line: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+		}
+	}
+
+	count, err := getCycleCount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit cycle count: %w", err)
+	}
+	return count, nil
+}
+
+func (r *PostgresCycleCountRepository) GetCycleCount(ctx context.Context, id int64) (*CycleCount, error) {
+	return getCycleCount(ctx, r.db, id)
+}
+
+func (r *PostgresCycleCountRepository) ListCycleCounts(ctx context.Context, status string) ([]CycleCount, error) {
+	query := `SELECT ` + cycleCountColumns + ` FROM cycle_counts WHERE ($1 = '' OR status = $1) ORDER BY id DESC`
+
+	list := []CycleCount{}
+	if err := r.db.SelectContext(ctx, &list, query, status); err != nil {
+		return nil, fmt.Errorf("list cycle counts failed: %w", err)
+	}
+	for i := range list {
+		lines, err := listCycleCountLines(ctx, r.db, list[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Lines = lines
+	}
+	return list, nil
+}
+
+// SubmitCounts records counted quantities and snapshots the system quantity
+// at the same moment, so the variance is not skewed by orders that ship
+// between counting and posting. Submitting a product again recounts it.
+func (r *PostgresCycleCountRepository) SubmitCounts(ctx context.Context, id int64, actor string, counts []CountInput) (*CycleCount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin count submission: %w", err)
+	}
+	defer tx.Rollback()
+
+	locationID, err := lockOpenCycleCount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range counts {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE cycle_count_lines l
+			SET counted = $1, counted_by = $2, counted_at = NOW(),
+				expected = CASE
+					WHEN $4::bigint IS NULL THEN (SELECT stock FROM inventory WHERE product_id = l.product_id)
+					ELSE COALESCE((
+						SELECT quantity FROM location_stock WHERE location_id = $4 AND product_id = l.product_id
+					), 0)
+				END
+			WHERE l.cycle_count_id = $3 AND l.product_id = $5
+		`, c.Counted, actor, id, locationID, c.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record count: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, fmt.Errorf("%w: %d", ErrCountLineNotFound, c.ProductID)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE cycle_counts SET updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to touch cycle count: %w", err)
+	}
+
+	count, err := getCycleCount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit count submission: %w", err)
+	}
+	return count, nil
+}
+
+// PostCycleCount books every non-zero variance as an audited
+// adjustment.count_correction, all or nothing, and queues inventory.adjusted
+// for each. Every line must be counted.
+func (r *PostgresCycleCountRepository) PostCycleCount(ctx context.Context, id int64, actor string) (*CycleCount, []StockLog, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin cycle count posting: %w", err)
+	}
+	defer tx.Rollback()
+
+	locationID, err := lockOpenCycleCount(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines, err := listCycleCountLines(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := []StockLog{}
+	for _, line := range lines {
+		if line.Variance == nil {
+			return nil, nil, fmt.Errorf("%w: product %d", ErrCycleCountIncomplete, line.ProductID)
+		}
+		if *line.Variance == 0 {
+			continue
+		}
+
+		entry, err := adjustStock(ctx, tx, StockChange{
+			ProductID:  line.ProductID,
+			Change:     *line.Variance,
+			Reason:     "adjustment.count_correction",
+			LocationID: locationID,
+			Actor:      actor,
+			Note:       fmt.Sprintf("cycle count %d", id),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE cycle_count_lines SET stock_log_id = $1 WHERE id = $2`, entry.ID, line.ID,
+		); err != nil {
+			return nil, nil, fmt.Errorf("failed to link count adjustment: %w", err)
+		}
+		if err := enqueueEvent(ctx, tx, events.TypeStockAdjusted, events.StockAdjusted(*entry)); err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE cycle_counts
+		SET status = 'posted', posted_by = $1, posted_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`, actor, id); err != nil {
+		return nil, nil, fmt.Errorf("failed to post cycle count: %w", err)
+	}
+
+	count, err := getCycleCount(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit cycle count posting: %w", err)
+	}
+	return count, entries, nil
+}
+
+func (r *PostgresCycleCountRepository) CancelCycleCount(ctx context.Context, id int64) (*CycleCount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin cancel transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOpenCycleCount(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE cycle_counts SET status = 'cancelled', updated_at = NOW() WHERE id = $1`, id,
+	); err != nil {
+		return nil, fmt.Errorf("failed to cancel cycle count: %w", err)
+	}
+
+	count, err := getCycleCount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit cycle count cancel: %w", err)
+	}
+	return count, nil
+}
+
+// lockOpenCycleCount locks an open count and returns the location it covers.
+func lockOpenCycleCount(ctx context.Context, tx *sqlx.Tx, id int64) (*int64, error) {
+	var status string
+	var locationID *int64
+	err := tx.QueryRowContext(ctx,
+		`SELECT status, location_id FROM cycle_counts WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&status, &locationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCycleCountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cycle count: %w", err)
+	}
+	if status != CycleCountStatusOpen {
+		return nil, ErrCycleCountClosed
+	}
+	return locationID, nil
+}
+
+func getCycleCount(ctx context.Context, q sqlx.QueryerContext, id int64) (*CycleCount, error) {
+	var count CycleCount
+	err := sqlx.GetContext(ctx, q, &count, `SELECT `+cycleCountColumns+` FROM cycle_counts WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCycleCountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cycle count failed: %w", err)
+	}
+	if count.Lines, err = listCycleCountLines(ctx, q, id); err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+func listCycleCountLines(ctx context.Context, q sqlx.QueryerContext, id int64) ([]CycleCountLine, error) {
+	lines := []CycleCountLine{}
+	query := `
+		SELECT l.id, l.product_id, i.sku, i.product_name, l.expected, l.counted, l.counted - l.expected AS variance,
+			l.counted_by, l.counted_at, l.stock_log_id
+		FROM cycle_count_lines l
+		JOIN inventory i ON i.product_id = l.product_id
+		WHERE l.cycle_count_id = $1
+		ORDER BY l.id ASC
+	`
+	if err := sqlx.SelectContext(ctx, q, &lines, query, id); err != nil {
+		return nil, fmt.Errorf("list cycle count lines failed: %w", err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrFamilyNotFound   = errors.New("product family not found")
+	ErrDuplicateVariant = errors.New("variant with these options already exists")
+	ErrInvalidOptions   = errors.New("variant options do not match family option names")
+)
+
+type FamilyRepository interface {
+	CreateFamily(ctx context.Context, in FamilyInput) (*Family, error)
+	PatchFamily(ctx context.Context, familyID int64, patch FamilyPatch) (*Family, error)
+	ListFamilies(ctx context.Context) ([]Family, error)
+	GetFamily(ctx context.Context, familyID int64) (*Family, error)
+	CreateVariant(ctx context.Context, familyID int64, in VariantInput) (*Product, error)
+}
+
+// Family groups variants that share a name, description and option names
+// (e.g. size and colour). Stock lives on the variants, which are inventory rows.
+type Family struct {
+	ID             int64
+	Name           string
+	Description    string
+	OptionNames    []string
+	BasePriceMinor int64
+	Currency       string
+	Status         string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Variants       []Product
+}
+
+type FamilyInput struct {
+	Name           string
+	Description    string
+	OptionNames    []string
+	BasePriceMinor int64
+	Currency       string
+	Status         string
+}
+
+type FamilyPatch struct {
+	Name           *string
+	Description    *string
+	BasePriceMinor *int64
+	Currency       *string
+	Status         *string
+}
+
+// VariantInput describes a new variant. Options must have exactly the
+// family's option names as keys. A nil PriceOverrideMinor inherits the
+// family base price.
+type VariantInput struct {
+	SKU                string
+	ProductName        string
+	Options            map[string]string
+	PriceOverrideMinor *int64
+	Attributes         json.RawMessage
+	Stock              int
+}
+
+const familyColumns = `id, name, description, option_names, base_price_minor, currency, status, created_at, updated_at`
+
+func scanFamily(row rowScanner) (*Family, error) {
+	var f Family
+	var optionNames []byte
+	err := row.Scan(
+		&f.ID, &f.Name, &f.Description, &optionNames, &f.BasePriceMinor, &f.Currency, &f.Status, &f.CreatedAt, &f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(optionNames, &f.OptionNames); err != nil {
+		return nil, fmt.Errorf("failed to decode option names: %w", err)
+	}
+	return &f, nil
+}
+
+type PostgresFamilyRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresFamilyRepository(db *sqlx.DB) *PostgresFamilyRepository {
+	return &PostgresFamilyRepository{db: db}
+}
+
+func (r *PostgresFamilyRepository) CreateFamily(ctx context.Context, in FamilyInput) (*Family, error) {
+	optionNames, err := json.Marshal(in.OptionNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode option names: %w", err)
+	}
+
+	query := `
+		INSERT INTO product_families (name, description, option_names, base_price_minor, currency, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + familyColumns
+
+	family, err := scanFamily(r.db.QueryRowContext(ctx, query,
+		in.Name, in.Description, string(optionNames), in.BasePriceMinor, in.Currency, in.Status,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product family: %w", err)
+	}
+	family.Variants = []Product{}
+	return family, nil
+}
+
+// PatchFamily updates family fields. A new base price or currency is pushed
+// down to every variant without a price override in the same transaction, so
+// inventory.price_minor always holds the price an order should snapshot.
+// A status change applies to all variants.
+func (r *PostgresFamilyRepository) PatchFamily(ctx context.Context, familyID int64, patch FamilyPatch) (*Family, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin family transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE product_families
+		SET name = COALESCE($1, name),
+			description = COALESCE($2, description),
+			base_price_minor = COALESCE($3, base_price_minor),
+			currency = COALESCE($4, currency),
+			status = COALESCE($5, status),
+			updated_at = NOW()
+		WHERE id = $6
+		RETURNING ` + familyColumns
+
+	family, err := scanFamily(tx.QueryRowContext(ctx, query,
+		patch.Name, patch.Description, patch.BasePriceMinor, patch.Currency, patch.Status, familyID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFamilyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch product family: %w", err)
+	}
+
+	if patch.BasePriceMinor != nil || patch.Currency != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE inventory
+			SET price_minor = COALESCE(price_override_minor, $1), currency = $2,
+				version = version + 1, updated_at = NOW()
+			WHERE family_id = $3
+		`, family.BasePriceMinor, family.Currency, familyID); err != nil {
+			return nil, fmt.Errorf("failed to reprice variants: %w", err)
+		}
+	}
+	if patch.Status != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE inventory SET status = $1, version = version + 1, updated_at = NOW()
+			WHERE family_id = $2
+		`, *patch.Status, familyID); err != nil {
+			return nil, fmt.Errorf("failed to update variant status: %w", err)
+		}
+	}
+
+	family.Variants, err = listVariants(ctx, tx, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit family patch: %w", err)
+	}
+	return family, nil
+}
+
+func (r *PostgresFamilyRepository) ListFamilies(ctx context.Context) ([]Family, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+familyColumns+` FROM product_families ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list product families failed: %w", err)
+	}
+	defer rows.Close()
+
+	families := []Family{}
+	for rows.Next() {
+		f, err := scanFamily(rows)
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, *f)
+	}
+	return families, rows.Err()
+}
+
+// GetFamily returns the family together with all of its variants.
+func (r *PostgresFamilyRepository) GetFamily(ctx context.Context, familyID int64) (*Family, error) {
+	family, err := scanFamily(r.db.QueryRowContext(ctx,
+		`SELECT `+familyColumns+` FROM product_families WHERE id = $1`, familyID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFamilyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get product family failed: %w", err)
+	}
+
+	family.Variants, err = listVariants(ctx, r.db, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return family, nil
+}
+
+// CreateVariant inserts a variant as an inventory row of the family. The
+// family row is locked so a concurrent price change or a variant with the same
+// options cannot slip in between the checks and the insert.
+func (r *PostgresFamilyRepository) CreateVariant(ctx context.Context, familyID int64, in VariantInput) (*Product, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin variant transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	family, err := scanFamily(tx.QueryRowContext(ctx,
+		`SELECT `+familyColumns+` FROM product_families WHERE id = $1 FOR UPDATE`, familyID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFamilyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock product family: %w", err)
+	}
+
+	if !matchesOptionNames(in.Options, family.OptionNames) {
+		return nil, ErrInvalidOptions
+	}
+	options, err := json.Marshal(in.Options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode variant options: %w", err)
+	}
+
+	var exists bool
+	if err := tx.GetContext(ctx, &exists,
+		`SELECT EXISTS (SELECT 1 FROM inventory WHERE family_id = $1 AND options = $2)`, familyID, string(options),
+	); err != nil {
+		return nil, fmt.Errorf("failed to check variant options: %w", err)
+	}
+	if exists {
+		return nil, ErrDuplicateVariant
+	}
+
+	name := in.ProductName
+	if name == "" {
+		name = variantName(family, in.Options)
+	}
+	price := family.BasePriceMinor
+	if in.PriceOverrideMinor != nil {
+		price = *in.PriceOverrideMinor
+	}
+
+	query := `
+		INSERT INTO inventory (sku, product_name, description, price_minor, currency, attributes, status,
+			family_id, options, price_override_minor, stock, stock_state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (sku) DO NOTHING
+		RETURNING ` + productColumns
+
+	variant, err := scanProduct(tx.QueryRowContext(ctx, query,
+		in.SKU, name, family.Description, price, family.Currency, string(in.Attributes), family.Status,
+		familyID, string(options), in.PriceOverrideMinor, in.Stock, StockStateFor(in.Stock, 0),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDuplicateSKU
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert variant: %w", err)
+	}
+	if err := logInitialStock(ctx, tx, variant); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit variant: %w", err)
+	}
+	return variant, nil
+}
+
+func listVariants(ctx context.Context, q sqlx.QueryerContext, familyID int64) ([]Product, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+productColumns+` FROM inventory WHERE family_id = $1 AND deleted_at IS NULL ORDER BY product_id`, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("list variants failed: %w", err)
+	}
+	defer rows.Close()
+
+	variants := []Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, *p)
+	}
+	return variants, rows.Err()
+}
+
+func matchesOptionNames(options map[string]string, names []string) bool {
+	if len(options) != len(names) {
+		return false
+	}
+	for _, name := range names {
+		if strings.TrimSpace(options[name]) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// variantName builds a default name such as "T-Shirt (M / Red)" following the
+// family's option order.
+func variantName(family *Family, options map[string]string) string {
+	if len(family.OptionNames) == 0 {
+		return family.Name
+	}
+
+	values := make([]string, 0, len(family.OptionNames))
+	for _, name := range family.OptionNames {
+		values = append(values, options[name])
+	}
+	return fmt.Sprintf("%s (%s)", family.Name, strings.Join(values, " / "))
+}
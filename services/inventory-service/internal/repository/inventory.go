@@ -2,18 +2,57 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
 	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+var (
+	ErrAlreadyProcessed  = errors.New("stock change already processed")
+	ErrInsufficientStock = errors.New("stock insufficient or product not found")
 )
 
 type InventoryRepository interface {
-	DecreaseStock(ctx context.Context, productID int64, quantity int) error
-	IncreaseStock(ctx context.Context, productID int64, quantity int) error
-	LogStockChange(ctx context.Context, productID int64, change int, reason string, orderID *int64) error
-	HasAlreadyProcessed(orderID int64, productID int64, reason string) bool
+	ApplyStockChange(ctx context.Context, change StockChange) error
+	AdjustStock(ctx context.Context, change StockChange) (*StockLog, error)
+	GetStockHistory(ctx context.Context, productID int64, limit, offset int) ([]StockLog, int, error)
+	FulfilOrder(ctx context.Context, orderID, productID int64, quantity int) ([]Allocation, error)
+	RestoreOrder(ctx context.Context, orderID, productID int64, quantity int) error
 	HasOrderCreatedLog(orderID int64, productID int64) bool
+	RecordFulfilmentFailure(ctx context.Context, failure events.FulfilmentFailed) error
+}
+
+// StockChange is a single audited stock mutation. A non-nil OrderID makes the
+// change idempotent per (product, reason, order) through unique_inventory_event.
+// Lot is only honoured by AdjustStock.
+type StockChange struct {
+	ProductID  int64
+	Change     int
+	Reason     string
+	OrderID    *int64
+	LocationID *int64
+	Lot        *LotInput
+	Actor      string
+	Note       string
 }
 
+type StockLog struct {
+	ID         int64     `db:"id" json:"id"`
+	ProductID  int64     `db:"product_id" json:"product_id"`
+	Change     int       `db:"change" json:"change"`
+	Reason     string    `db:"reason" json:"reason"`
+	OrderID    *int64    `db:"order_id" json:"order_id,omitempty"`
+	LocationID *int64    `db:"location_id" json:"location_id,omitempty"`
+	Actor      *string   `db:"actor" json:"actor,omitempty"`
+	Note       *string   `db:"note" json:"note,omitempty"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+const stockLogColumns = `id, product_id, change, reason, order_id, location_id, actor, note, created_at`
+
 type PostgresInventoryRepository struct {
 	db *sqlx.DB
 }
@@ -22,62 +61,236 @@ func NewPostgresInventoryRepository(db *sqlx.DB) *PostgresInventoryRepository {
 	return &PostgresInventoryRepository{db: db}
 }
 
-func (r *PostgresInventoryRepository) DecreaseStock(ctx context.Context, productID int64, quantity int) error {
-	query := `UPDATE inventory SET stock = stock - $1, updated_at = NOW() WHERE product_id = $2 AND stock >= $1`
-	res, err := r.db.ExecContext(ctx, query, quantity, productID)
+// ApplyStockChange writes the stock log and updates the stock level in one
+// transaction. The log insert runs first so that a redelivered event hits the
+// unique index and returns ErrAlreadyProcessed without touching stock.
+func (r *PostgresInventoryRepository) ApplyStockChange(ctx context.Context, change StockChange) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := applyStockChange(ctx, tx, change)
+		return err
+	})
+}
+
+// AdjustStock applies a manual correction. When a location is given its
+// per-location level moves with the total; otherwise the unassigned pool
+// absorbs the change and must not go negative. Lots work the same way: a
+// named lot moves with the total, otherwise the untracked pool absorbs it.
+// inventory.adjusted is queued in the outbox with the change.
+func (r *PostgresInventoryRepository) AdjustStock(ctx context.Context, change StockChange) (*StockLog, error) {
+	var entry *StockLog
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		if entry, err = adjustStock(ctx, tx, change); err != nil {
+			return err
+		}
+		return enqueueEvent(ctx, tx, events.TypeStockAdjusted, events.StockAdjusted(*entry))
+	})
+	return entry, err
+}
+
+func (r *PostgresInventoryRepository) GetStockHistory(ctx context.Context, productID int64, limit, offset int) ([]StockLog, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM stock_logs WHERE product_id = $1`, productID); err != nil {
+		return nil, 0, fmt.Errorf("count stock logs failed: %w", err)
+	}
+
+	query := `SELECT ` + stockLogColumns + ` FROM stock_logs WHERE product_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+
+	history := []StockLog{}
+	if err := r.db.SelectContext(ctx, &history, query, productID, limit, offset); err != nil {
+		return nil, 0, fmt.Errorf("get stock history failed: %w", err)
+	}
+	return history, total, nil
+}
+
+// FulfilOrder decrements stock for an order and allocates the quantity to
+// warehouse locations and lots in the same transaction as the idempotency record.
+func (r *PostgresInventoryRepository) FulfilOrder(ctx context.Context, orderID, productID int64, quantity int) ([]Allocation, error) {
+	var allocations []Allocation
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		allocations, err = fulfilOrder(ctx, tx, orderID, productID, quantity)
+		return err
+	})
+	return allocations, err
+}
+
+// RecordFulfilmentFailure queues inventory.fulfilment_failed in the outbox
+// for an order whose stock could not be taken.
+func (r *PostgresInventoryRepository) RecordFulfilmentFailure(ctx context.Context, failure events.FulfilmentFailed) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		return enqueueEvent(ctx, tx, events.TypeFulfilmentFailed, failure)
+	})
+}
+
+// RestoreOrder returns a cancelled order's stock to the locations and lots it
+// was allocated from.
+func (r *PostgresInventoryRepository) RestoreOrder(ctx context.Context, orderID, productID int64, quantity int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := applyStockChange(ctx, tx, StockChange{
+			ProductID: productID,
+			Change:    quantity,
+			Reason:    "order.cancelled",
+			OrderID:   &orderID,
+		}); err != nil {
+			return err
+		}
+		if err := restoreAllocations(ctx, tx, orderID, productID); err != nil {
+			return err
+		}
+		return restoreLotAllocations(ctx, tx, orderID, productID)
+	})
+}
+
+func (r *PostgresInventoryRepository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to decrease stock: %w", err)
+		return fmt.Errorf("failed to begin stock transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("stock insufficient or product not found")
+	if err := fn(tx); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock transaction: %w", err)
+	}
 	return nil
 }
 
-func (r *PostgresInventoryRepository) LogStockChange(ctx context.Context, productID int64, change int, reason string, orderID *int64) error {
-	query := `
-		INSERT INTO stock_logs (product_id, change, reason, order_id)
-		VALUES ($1, $2, $3, $4)
-	`
-	_, err := r.db.ExecContext(ctx, query, productID, change, reason, orderID)
+func fulfilOrder(ctx context.Context, tx *sqlx.Tx, orderID, productID int64, quantity int) ([]Allocation, error) {
+	if _, err := applyStockChange(ctx, tx, StockChange{
+		ProductID: productID,
+		Change:    -quantity,
+		Reason:    "order.created",
+		OrderID:   &orderID,
+	}); err != nil {
+		return nil, err
+	}
+	allocations, err := allocateLocations(ctx, tx, orderID, productID, quantity)
 	if err != nil {
-		return fmt.Errorf("failed to insert stock log: %w", err)
+		return nil, err
 	}
-	return nil
+	if _, err := allocateLots(ctx, tx, orderID, productID, quantity); err != nil {
+		return nil, err
+	}
+	return allocations, nil
 }
 
-func (r *PostgresInventoryRepository) IncreaseStock(ctx context.Context, productID int64, quantity int) error {
-	query := `
+// adjustStock is AdjustStock inside a caller's transaction.
+func adjustStock(ctx context.Context, tx *sqlx.Tx, change StockChange) (*StockLog, error) {
+	// Checked up front, so that an unknown product is not reported as
+	// insufficient stock by the update below.
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM inventory WHERE product_id = $1)`, change.ProductID); err != nil {
+		return nil, fmt.Errorf("failed to check product: %w", err)
+	}
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	entry, err := applyStockChange(ctx, tx, change)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case change.Lot != nil && change.Change > 0:
+		if _, err := addLotStock(ctx, tx, change.ProductID, *change.Lot, change.Change); err != nil {
+			return nil, err
+		}
+	case change.Lot != nil:
+		if err := removeLotStock(ctx, tx, change.ProductID, change.Lot.Number, -change.Change); err != nil {
+			return nil, err
+		}
+	case change.Change < 0:
+		untracked, err := untrackedStock(ctx, tx, change.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if untracked < 0 {
+			return nil, ErrInsufficientStock
+		}
+	}
+
+	if change.LocationID != nil {
+		if change.Change > 0 {
+			return entry, addLocationStock(ctx, tx, *change.LocationID, change.ProductID, change.Change)
+		}
+		res, err := tx.ExecContext(ctx, `
+			UPDATE location_stock
+			SET quantity = quantity + $1, updated_at = NOW()
+			WHERE location_id = $2 AND product_id = $3 AND quantity + $1 >= 0
+		`, change.Change, *change.LocationID, change.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to adjust location stock: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, shortLocation(ctx, tx, *change.LocationID)
+		}
+		return entry, nil
+	}
+
+	var unassigned int
+	if err := tx.GetContext(ctx, &unassigned, `
+		SELECT i.stock - COALESCE((SELECT SUM(quantity) FROM location_stock WHERE product_id = i.product_id), 0)
+		FROM inventory i
+		WHERE i.product_id = $1
+	`, change.ProductID); err != nil {
+		return nil, fmt.Errorf("failed to check unassigned stock: %w", err)
+	}
+	if unassigned < 0 {
+		return nil, ErrInsufficientStock
+	}
+	return entry, nil
+}
+
+func applyStockChange(ctx context.Context, tx *sqlx.Tx, change StockChange) (*StockLog, error) {
+	entry, err := insertStockLog(ctx, tx, change)
+	if err != nil {
+		return nil, err
+	}
+
+	stockQuery := `
 		UPDATE inventory
-		SET stock = stock + $1, updated_at = NOW()
-		WHERE product_id = $2;
+		SET stock = stock + $1, version = version + 1, updated_at = NOW()
+		WHERE product_id = $2 AND stock + $1 >= reserved
 	`
-	res, err := r.db.ExecContext(ctx, query, quantity, productID)
+	res, err := tx.ExecContext(ctx, stockQuery, change.Change, change.ProductID)
 	if err != nil {
-		return fmt.Errorf("increase stock failed: %w", err)
+		return nil, fmt.Errorf("failed to update stock: %w", err)
 	}
 
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("product not found to increase stock")
+		return nil, ErrInsufficientStock
 	}
 
-	return nil
+	if err := syncStockState(ctx, tx, change.ProductID); err != nil {
+		return nil, err
+	}
+	return entry, nil
 }
 
-func (r *PostgresInventoryRepository) HasAlreadyProcessed(orderID int64, productID int64, reason string) bool {
-	var exists bool
+func insertStockLog(ctx context.Context, tx *sqlx.Tx, change StockChange) (*StockLog, error) {
 	query := `
-		SELECT EXISTS (
-			SELECT 1 FROM stock_logs
-			WHERE order_id = $1 AND product_id = $2 AND reason = $3
-		)
-	`
-	err := r.db.QueryRow(query, orderID, productID, reason).Scan(&exists)
-	return err == nil && exists
+		INSERT INTO stock_logs (product_id, change, reason, order_id, location_id, actor, note)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+		ON CONFLICT (product_id, reason, order_id) WHERE order_id IS NOT NULL DO NOTHING
+		RETURNING ` + stockLogColumns
+
+	var entry StockLog
+	err := tx.GetContext(ctx, &entry, query,
+		change.ProductID, change.Change, change.Reason, change.OrderID, change.LocationID, change.Actor, change.Note,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlreadyProcessed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert stock log: %w", err)
+	}
+	return &entry, nil
 }
 
 func (r *PostgresInventoryRepository) HasOrderCreatedLog(orderID int64, productID int64) bool {
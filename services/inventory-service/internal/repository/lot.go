@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	LotStatusAvailable   = "available"
+	LotStatusQuarantined = "quarantined"
+)
+
+var (
+	ErrLotNotFound       = errors.New("lot not found")
+	ErrLotQuarantined    = errors.New("lot is quarantined")
+	ErrLotExpiryMismatch = errors.New("lot already exists with a different expiry date")
+	ErrLotExpired        = errors.New("only expired lots are left to allocate")
+)
+
+type LotRepository interface {
+	ListLots(ctx context.Context, productID int64) ([]Lot, error)
+	GetLotAllocations(ctx context.Context, lotID int64) ([]LotAllocation, error)
+	QuarantineExpired(ctx context.Context, afterID int64, limit int) ([]Lot, []Lot, error)
+}
+
+type Lot struct {
+	ID            int64      `db:"id" json:"id"`
+	ProductID     int64      `db:"product_id" json:"product_id"`
+	LotNumber     string     `db:"lot_number" json:"lot_number"`
+	ExpiresAt     *time.Time `db:"expires_at" json:"expires_at"`
+	ReceivedAt    time.Time  `db:"received_at" json:"received_at"`
+	Quantity      int        `db:"quantity" json:"quantity"`
+	Status        string     `db:"status" json:"status"`
+	QuarantinedAt *time.Time `db:"quarantined_at" json:"quarantined_at,omitempty"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type LotAllocation struct {
+	ID        int64     `db:"id" json:"id"`
+	OrderID   int64     `db:"order_id" json:"order_id"`
+	ProductID int64     `db:"product_id" json:"product_id"`
+	LotID     int64     `db:"lot_id" json:"lot_id"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// LotInput names the lot a stock receipt or removal applies to. ExpiresAt is
+// only used when the lot is new; an existing lot keeps its date.
+type LotInput struct {
+	Number    string
+	ExpiresAt *time.Time
+}
+
+const (
+	lotColumns           = `id, product_id, lot_number, expires_at, received_at, quantity, status, quarantined_at, updated_at`
+	lotAllocationColumns = `id, order_id, product_id, lot_id, quantity, created_at`
+)
+
+type PostgresLotRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresLotRepository(db *sqlx.DB) *PostgresLotRepository {
+	return &PostgresLotRepository{db: db}
+}
+
+// ListLots returns the product's lots in FEFO order, quarantined ones last.
+func (r *PostgresLotRepository) ListLots(ctx context.Context, productID int64) ([]Lot, error) {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM lots
+		WHERE product_id = $1
+		ORDER BY status ASC, expires_at ASC NULLS LAST, received_at ASC, id ASC
+	`
+	list := []Lot{}
+	if err := r.db.SelectContext(ctx, &list, query, productID); err != nil {
+		return nil, fmt.Errorf("list lots failed: %w", err)
+	}
+	return list, nil
+}
+
+// GetLotAllocations lists the orders a lot shipped to, e.g. for a recall.
+func (r *PostgresLotRepository) GetLotAllocations(ctx context.Context, lotID int64) ([]LotAllocation, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM lots WHERE id = $1)`, lotID); err != nil {
+		return nil, fmt.Errorf("failed to check lot: %w", err)
+	}
+	if !exists {
+		return nil, ErrLotNotFound
+	}
+
+	list := []LotAllocation{}
+	query := `SELECT ` + lotAllocationColumns + ` FROM lot_allocations WHERE lot_id = $1 ORDER BY id ASC`
+	if err := r.db.SelectContext(ctx, &list, query, lotID); err != nil {
+		return nil, fmt.Errorf("get lot allocations failed: %w", err)
+	}
+	return list, nil
+}
+
+// QuarantineExpired takes up to limit lots past their expiry date (with IDs
+// above afterID) out of stock, marks them quarantined and queues
+// inventory.lot_quarantined for each. A lot whose units are still needed by
+// reservations is deferred and returned separately; it is retried on a later
+// sweep once the holds settle.
+func (r *PostgresLotRepository) QuarantineExpired(ctx context.Context, afterID int64, limit int) ([]Lot, []Lot, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin quarantine transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + lotColumns + `
+		FROM lots
+		WHERE status = 'available' AND expires_at < CURRENT_DATE AND id > $1
+		ORDER BY id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	var expired []Lot
+	if err := tx.SelectContext(ctx, &expired, query, afterID, limit); err != nil {
+		return nil, nil, fmt.Errorf("failed to load expired lots: %w", err)
+	}
+
+	var quarantined, deferred []Lot
+	for _, lot := range expired {
+		// Each lot gets a savepoint so a deferred one does not undo the rest.
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT quarantine_lot`); err != nil {
+			return nil, nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		if lot.Quantity > 0 {
+			_, err := applyStockChange(ctx, tx, StockChange{
+				ProductID: lot.ProductID,
+				Change:    -lot.Quantity,
+				Reason:    "lot.quarantined",
+				Note:      "lot " + lot.LotNumber,
+			})
+			if errors.Is(err, ErrInsufficientStock) {
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT quarantine_lot`); err != nil {
+					return nil, nil, fmt.Errorf("failed to roll back savepoint: %w", err)
+				}
+				deferred = append(deferred, lot)
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var updated Lot
+		if err := tx.GetContext(ctx, &updated, `
+			UPDATE lots
+			SET status = 'quarantined', quarantined_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+lotColumns, lot.ID,
+		); err != nil {
+			return nil, nil, fmt.Errorf("failed to quarantine lot: %w", err)
+		}
+		if err := enqueueEvent(ctx, tx, events.TypeLotQuarantined, events.LotQuarantined{
+			LotID:         updated.ID,
+			ProductID:     updated.ProductID,
+			LotNumber:     updated.LotNumber,
+			ExpiresAt:     updated.ExpiresAt,
+			Quantity:      updated.Quantity,
+			QuarantinedAt: updated.QuarantinedAt,
+		}); err != nil {
+			return nil, nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT quarantine_lot`); err != nil {
+			return nil, nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		quarantined = append(quarantined, updated)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit lot quarantine: %w", err)
+	}
+	return quarantined, deferred, nil
+}
+
+// addLotStock puts quantity into the named lot, creating it on first use.
+func addLotStock(ctx context.Context, tx *sqlx.Tx, productID int64, in LotInput, quantity int) (*Lot, error) {
+	var lot Lot
+	err := tx.GetContext(ctx, &lot,
+		`SELECT `+lotColumns+` FROM lots WHERE product_id = $1 AND lot_number = $2 FOR UPDATE`, productID, in.Number,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.GetContext(ctx, &lot, `
+			INSERT INTO lots (product_id, lot_number, expires_at, quantity)
+			VALUES ($1, $2, $3, $4)
+			RETURNING `+lotColumns,
+			productID, in.Number, in.ExpiresAt, quantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert lot: %w", err)
+		}
+		return &lot, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock lot: %w", err)
+	}
+	if lot.Status == LotStatusQuarantined {
+		return nil, ErrLotQuarantined
+	}
+	if in.ExpiresAt != nil && (lot.ExpiresAt == nil || !lot.ExpiresAt.Equal(*in.ExpiresAt)) {
+		return nil, ErrLotExpiryMismatch
+	}
+
+	if err := tx.GetContext(ctx, &lot, `
+		UPDATE lots SET quantity = quantity + $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING `+lotColumns, quantity, lot.ID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to add lot stock: %w", err)
+	}
+	return &lot, nil
+}
+
+// removeLotStock takes quantity out of an available lot, e.g. for damage.
+func removeLotStock(ctx context.Context, tx *sqlx.Tx, productID int64, lotNumber string, quantity int) error {
+	var status string
+	var available int
+	err := tx.QueryRowContext(ctx,
+		`SELECT status, quantity FROM lots WHERE product_id = $1 AND lot_number = $2 FOR UPDATE`, productID, lotNumber,
+	).Scan(&status, &available)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLotNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock lot: %w", err)
+	}
+	if status == LotStatusQuarantined {
+		return ErrLotQuarantined
+	}
+	if available < quantity {
+		return ErrInsufficientStock
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE lots SET quantity = quantity - $1, updated_at = NOW()
+		WHERE product_id = $2 AND lot_number = $3
+	`, quantity, productID, lotNumber); err != nil {
+		return fmt.Errorf("failed to remove lot stock: %w", err)
+	}
+	return nil
+}
+
+// untrackedStock is the on-hand stock not held in any available lot.
+func untrackedStock(ctx context.Context, tx *sqlx.Tx, productID int64) (int, error) {
+	var untracked int
+	err := tx.GetContext(ctx, &untracked, `
+		SELECT i.stock - COALESCE((
+			SELECT SUM(quantity) FROM lots WHERE product_id = i.product_id AND status = 'available'
+		), 0)
+		FROM inventory i
+		WHERE i.product_id = $1
+	`, productID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check untracked stock: %w", err)
+	}
+	return untracked, nil
+}
+
+// checkStockCovered fails with ErrInsufficientStock when the product's stock,
+// after an absolute overwrite, is less than its located or lot stock.
+func checkStockCovered(ctx context.Context, tx *sqlx.Tx, productID int64) error {
+	var covered bool
+	err := tx.GetContext(ctx, &covered, `
+		SELECT i.stock >= COALESCE((SELECT SUM(quantity) FROM location_stock WHERE product_id = i.product_id), 0)
+			AND i.stock >= COALESCE((
+				SELECT SUM(quantity) FROM lots WHERE product_id = i.product_id AND status = 'available'
+			), 0)
+		FROM inventory i
+		WHERE i.product_id = $1
+	`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to check located and lot stock: %w", err)
+	}
+	if !covered {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// allocateLots records which lots an order ships from, first-expired-first-out.
+// It runs after the stock decrement, so the untracked pool available to the
+// order is what is left untracked plus the order's own quantity. Lots past
+// their expiry date are never picked; if only they could cover the order it
+// fails with ErrLotExpired until the sweeper quarantines them.
+func allocateLots(ctx context.Context, tx *sqlx.Tx, orderID, productID int64, quantity int) ([]LotAllocation, error) {
+	var candidates []Lot
+	if err := tx.SelectContext(ctx, &candidates, `
+		SELECT `+lotColumns+`
+		FROM lots
+		WHERE product_id = $1 AND status = 'available' AND quantity > 0
+			AND (expires_at IS NULL OR expires_at >= CURRENT_DATE)
+		ORDER BY expires_at ASC NULLS LAST, received_at ASC, id ASC
+		FOR UPDATE
+	`, productID); err != nil {
+		return nil, fmt.Errorf("failed to load lots: %w", err)
+	}
+	if len(candidates) == 0 {
+		untracked, err := untrackedStock(ctx, tx, productID)
+		if err != nil {
+			return nil, err
+		}
+		if untracked < 0 {
+			return nil, ErrLotExpired
+		}
+		return nil, nil
+	}
+
+	var allocations []LotAllocation
+	remaining := quantity
+	for _, lot := range candidates {
+		if remaining == 0 {
+			break
+		}
+		take := min(lot.Quantity, remaining)
+		remaining -= take
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE lots SET quantity = quantity - $1, updated_at = NOW() WHERE id = $2`, take, lot.ID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to allocate lot stock: %w", err)
+		}
+
+		var a LotAllocation
+		if err := tx.GetContext(ctx, &a, `
+			INSERT INTO lot_allocations (order_id, product_id, lot_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			RETURNING `+lotAllocationColumns,
+			orderID, productID, lot.ID, take,
+		); err != nil {
+			return nil, fmt.Errorf("failed to record lot allocation: %w", err)
+		}
+		allocations = append(allocations, a)
+	}
+
+	untracked, err := untrackedStock(ctx, tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if untracked < 0 {
+		return nil, ErrLotExpired
+	}
+	return allocations, nil
+}
+
+// restoreLotAllocations returns a cancelled order's units to their lots. Units
+// going back to a lot that was quarantined meanwhile leave stock again at once.
+func restoreLotAllocations(ctx context.Context, tx *sqlx.Tx, orderID, productID int64) error {
+	var allocations []LotAllocation
+	if err := tx.SelectContext(ctx, &allocations, `
+		SELECT `+lotAllocationColumns+`
+		FROM lot_allocations
+		WHERE order_id = $1 AND product_id = $2
+	`, orderID, productID); err != nil {
+		return fmt.Errorf("failed to load lot allocations: %w", err)
+	}
+
+	for _, a := range allocations {
+		var lot Lot
+		if err := tx.GetContext(ctx, &lot, `
+			UPDATE lots SET quantity = quantity + $1, updated_at = NOW()
+			WHERE id = $2
+			RETURNING `+lotColumns, a.Quantity, a.LotID,
+		); err != nil {
+			return fmt.Errorf("failed to restore lot stock: %w", err)
+		}
+		if lot.Status != LotStatusQuarantined {
+			continue
+		}
+		if _, err := applyStockChange(ctx, tx, StockChange{
+			ProductID: productID,
+			Change:    -a.Quantity,
+			Reason:    "lot.quarantined",
+			Note:      "lot " + lot.LotNumber + " returned after quarantine",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
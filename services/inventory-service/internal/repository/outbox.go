@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+type OutboxRepository interface {
+	PublishPending(ctx context.Context, limit int, publish func(OutboxEvent) error) (int, error)
+}
+
+// OutboxEvent is an event queued in event_outbox. Payload is the encoded
+// event, of version EventVersion of its contract.
+type OutboxEvent struct {
+	ID           int64     `db:"id"`
+	EventType    string    `db:"event_type"`
+	EventVersion string    `db:"event_version"`
+	Payload      []byte    `db:"payload"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type PostgresOutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresOutboxRepository(db *sqlx.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db}
+}
+
+// PublishPending hands up to limit pending events to publish in the order
+// they were queued, marks each one published or quarantined as relayBatch
+// decides, and returns how many it marked. SKIP LOCKED lets several instances relay in parallel.
+func (r *PostgresOutboxRepository) PublishPending(ctx context.Context, limit int, publish func(OutboxEvent) error) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, event_type, event_version, payload, created_at
+		FROM event_outbox
+		WHERE published_at IS NULL AND quarantined_at IS NULL
+		ORDER BY id ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	var pending []OutboxEvent
+	if err := tx.SelectContext(ctx, &pending, query, limit); err != nil {
+		return 0, fmt.Errorf("failed to load pending events: %w", err)
+	}
+
+	relayed, publishErr := relayBatch(pending, publish, func(event OutboxEvent, column string) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE event_outbox SET `+column+` = NOW() WHERE id = $1`, event.ID); err != nil {
+			return fmt.Errorf("failed to mark event %s: %w", column, err)
+		}
+		return nil
+	})
+	if errors.Is(publishErr, errMarkFailed) {
+		return 0, publishErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox: %w", err)
+	}
+	return relayed, publishErr
+}
+
+// enqueueEvent queues payload, the latest version of eventType, in the
+// outbox. Called inside the transaction making the change the event
+// announces, so the event is published if and only if the change commits.
+func enqueueEvent(ctx context.Context, tx *sqlx.Tx, eventType string, payload interface{}) error {
+	version, err := events.Latest(eventType)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", eventType, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO event_outbox (event_type, event_version, payload) VALUES ($1, $2, $3)`,
+		eventType, version, string(body))
+	if err != nil {
+		return fmt.Errorf("failed to queue %s: %w", eventType, err)
+	}
+	return nil
+}
+
+// errMarkFailed wraps a failure to record a row's outcome, which fails the
+// whole batch.
+var errMarkFailed = errors.New("failed to mark relayed row")
+
+// relayBatch hands rows to publish in order and marks each outcome with mark,
+// naming the timestamp column to set. A row whose event fails validation
+// against its contract is final: publishing it again would fail the same
+// way, so it is marked quarantined_at (Publisher has already sent it to the
+// quarantine queue) and the batch carries on. Any other publish error stops
+// the batch so the rest are retried in order on the next call. It returns the
+// number of rows marked and the publish error that stopped it.
+func relayBatch[T any](rows []T, publish func(T) error, mark func(row T, column string) error) (int, error) {
+	for i, row := range rows {
+		column := "published_at"
+		var verr *events.ValidationError
+		if err := publish(row); errors.As(err, &verr) {
+			column = "quarantined_at"
+		} else if err != nil {
+			return i, err
+		}
+		if err := mark(row, column); err != nil {
+			return i, fmt.Errorf("%w: %w", errMarkFailed, err)
+		}
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+)
+
+func TestRelayBatch(t *testing.T) {
+	invalid := &events.ValidationError{Problems: []events.Problem{{Path: "$.sku", Message: "is required"}}}
+	brokerDown := errors.New("channel closed")
+
+	tests := []struct {
+		name    string
+		errs    map[int64]error
+		relayed int
+		err     error
+		marked  []string
+	}{
+		{
+			name:    "all published",
+			relayed: 4,
+			marked:  []string{"1 published_at", "2 published_at", "3 published_at", "4 published_at"},
+		},
+		{
+			name:    "invalid row among valid ones is quarantined",
+			errs:    map[int64]error{2: fmt.Errorf("publish: %w", invalid)},
+			relayed: 4,
+			marked:  []string{"1 published_at", "2 quarantined_at", "3 published_at", "4 published_at"},
+		},
+		{
+			name:    "publish failure stops the batch",
+			errs:    map[int64]error{2: invalid, 3: brokerDown},
+			relayed: 2,
+			err:     brokerDown,
+			marked:  []string{"1 published_at", "2 quarantined_at"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := []StockAlert{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+			var marked []string
+			relayed, err := relayBatch(rows, func(a StockAlert) error {
+				return tt.errs[a.ID]
+			}, func(a StockAlert, column string) error {
+				marked = append(marked, fmt.Sprintf("%d %s", a.ID, column))
+				return nil
+			})
+			if relayed != tt.relayed || !errors.Is(err, tt.err) {
+				t.Errorf("relayBatch = %d, %v; want %d, %v", relayed, err, tt.relayed, tt.err)
+			}
+			if !reflect.DeepEqual(marked, tt.marked) {
+				t.Errorf("marked %v, want %v", marked, tt.marked)
+			}
+		})
+	}
+}
+
+func TestRelayBatchMarkFailure(t *testing.T) {
+	rows := []StockAlert{{ID: 1}, {ID: 2}}
+	relayed, err := relayBatch(rows, func(StockAlert) error { return nil }, func(a StockAlert, _ string) error {
+		if a.ID == 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+	if relayed != 1 || !errors.Is(err, errMarkFailed) {
+		t.Errorf("relayBatch = %d, %v; want 1, errMarkFailed", relayed, err)
+	}
+}
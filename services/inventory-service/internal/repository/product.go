@@ -2,24 +2,115 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"time"
 )
 
+const (
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrVersionMismatch = errors.New("product version mismatch")
+	ErrDuplicateSKU    = errors.New("sku already exists")
+	ErrProductInUse    = errors.New("product has active reservations")
+	ErrProductDeleted  = errors.New("product is deleted")
+)
+
 type ProductRepository interface {
-	InsertProduct(ctx context.Context, name string, stock int) (*Product, error)
-	UpdateProduct(ctx context.Context, productID int64, name string, stock int) (*Product, error)
+	InsertProduct(ctx context.Context, in ProductInput) (*Product, error)
+	UpdateProduct(ctx context.Context, productID int64, in ProductInput, expectedVersion *int64) (*Product, error)
+	UpsertProductBySKU(ctx context.Context, sku string, create *ProductInput, update ProductPatch, dryRun bool) (*Product, bool, error)
+	PatchProduct(ctx context.Context, productID int64, patch ProductPatch, expectedVersion *int64) (*Product, error)
 	DeleteProduct(ctx context.Context, productID int64) error
-	GetAllProducts(ctx context.Context) ([]Product, error)
+	RestoreProduct(ctx context.Context, productID int64) (*Product, error)
+	ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error)
 	GetByID(ctx context.Context, id int64) (*Product, error)
 }
 
+// Product is the stored inventory row. HTTP responses go through
+// handler.ProductDTO so the API shape does not follow schema changes.
 type Product struct {
-	ProductName string    `json:"product_name"`
-	ProductID   int64     `json:"product_id"`
-	Stock       int       `json:"stock"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ProductName string
+	ProductID   int64
+	SKU         string
+	Description string
+	PriceMinor  int64
+	Currency    string
+	Attributes  json.RawMessage
+	Status      string
+	CategoryID  *int64
+	FamilyID    *int64
+	Options     json.RawMessage
+	Stock       int
+	Reserved    int
+	Available   int
+	// ReorderThreshold is the on-hand level at or below which the product
+	// counts as low on stock; StockState is its current classification.
+	ReorderThreshold int
+	StockState       string
+	Version          int64
+	UpdatedAt        time.Time
+}
+
+// ProductInput carries every writable catalog field. SKU is only used on
+// insert; it identifies the product and is not changed afterwards.
+type ProductInput struct {
+	SKU         string
+	ProductName string
+	Description string
+	PriceMinor  int64
+	Currency    string
+	Attributes  json.RawMessage
+	Status      string
+	CategoryID  *int64
+	Stock       int
+
+	ReorderThreshold int
+}
+
+// ProductPatch holds the catalog fields a PATCH may change. Nil fields are
+// left as they are; ClearCategory removes the product from its category.
+type ProductPatch struct {
+	ProductName   *string
+	Description   *string
+	PriceMinor    *int64
+	Currency      *string
+	Attributes    json.RawMessage
+	Status        *string
+	CategoryID    *int64
+	ClearCategory bool
+
+	ReorderThreshold *int
+}
+
+const productColumns = `product_id, product_name, sku, description, price_minor, currency, attributes, status,
+	category_id, family_id, options, stock, reserved, stock - reserved, reorder_threshold, stock_state, version, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner) (*Product, error) {
+	var p Product
+	var attributes, options []byte
+	err := row.Scan(
+		&p.ProductID, &p.ProductName, &p.SKU, &p.Description, &p.PriceMinor, &p.Currency, &attributes, &p.Status,
+		&p.CategoryID, &p.FamilyID, &options, &p.Stock, &p.Reserved, &p.Available,
+		&p.ReorderThreshold, &p.StockState, &p.Version, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.Attributes = attributes
+	p.Options = options
+	return &p, nil
 }
 
 type PostgresProductRepository struct {
@@ -30,88 +121,343 @@ func NewPostgresProductRepository(db *sqlx.DB) *PostgresProductRepository {
 	return &PostgresProductRepository{db: db}
 }
 
-func (r *PostgresProductRepository) InsertProduct(ctx context.Context, name string, stock int) (*Product, error) {
+func (r *PostgresProductRepository) InsertProduct(ctx context.Context, in ProductInput) (*Product, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin insert transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategory(ctx, tx, in.CategoryID); err != nil {
+		return nil, err
+	}
+
+	product, err := insertProduct(ctx, tx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit product insert: %w", err)
+	}
+	return product, nil
+}
+
+// UpdateProduct overwrites every writable field if the stored version still matches
+// expectedVersion, or unconditionally when it is nil. Any stock difference is
+// written to stock_logs as product.updated so the overwrite stays auditable.
+// Stock cannot go below what is reserved, held at locations or held in lots.
+func (r *PostgresProductRepository) UpdateProduct(ctx context.Context, productID int64, in ProductInput, expectedVersion *int64) (*Product, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin update transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous, reserved int
+	if err := lockProductVersion(ctx, tx, productID, expectedVersion, &previous, &reserved); err != nil {
+		return nil, err
+	}
+	if in.Stock < reserved {
+		return nil, ErrInsufficientStock
+	}
+	if err := checkCategory(ctx, tx, in.CategoryID); err != nil {
+		return nil, err
+	}
+
+	product, err := overwriteProduct(ctx, tx, productID, in, previous, "product.updated")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStockCovered(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit product update: %w", err)
+	}
+	return product, nil
+}
+
+// UpsertProductBySKU creates the product from create when sku is new, or
+// applies update to the existing one, each call in its own transaction. The
+// update never writes stock: an import carries a snapshot that may predate
+// orders taken since, so stock only changes through adjustments. A new sku
+// with a nil create is ErrProductNotFound. With dryRun every check still
+// runs but the transaction is rolled back, so the result shows what a real
+// import would do without changing anything.
+func (r *PostgresProductRepository) UpsertProductBySKU(ctx context.Context, sku string, create *ProductInput, update ProductPatch, dryRun bool) (*Product, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin upsert transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategory(ctx, tx, update.CategoryID); err != nil {
+		return nil, false, err
+	}
+
+	var productID int64
+	var deleted bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT product_id, deleted_at IS NOT NULL FROM inventory WHERE sku = $1 FOR UPDATE`, sku,
+	).Scan(&productID, &deleted)
+
+	created := errors.Is(err, sql.ErrNoRows)
+	var product *Product
+	switch {
+	case created && create == nil:
+		return nil, false, ErrProductNotFound
+	case created:
+		product, err = insertProduct(ctx, tx, *create)
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to lock product by sku: %w", err)
+	case deleted:
+		return nil, false, ErrProductDeleted
+	default:
+		product, err = patchProduct(ctx, tx, productID, update)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if dryRun {
+		return product, created, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit product upsert: %w", err)
+	}
+	return product, created, nil
+}
+
+// insertProduct creates the product and logs its initial stock as
+// product.created, so that stock_logs alone add up to inventory.stock.
+func insertProduct(ctx context.Context, tx *sqlx.Tx, in ProductInput) (*Product, error) {
 	query := `
-		INSERT INTO inventory (product_name, stock)
-		VALUES ($1, $2)
-		RETURNING product_id, product_name, stock, updated_at;
-	`
+		INSERT INTO inventory (sku, product_name, description, price_minor, currency, attributes, status, category_id,
+			stock, reorder_threshold, stock_state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (sku) DO NOTHING
+		RETURNING ` + productColumns
 
-	var product Product
-	err := r.db.QueryRowContext(ctx, query, name, stock).Scan(
-		&product.ProductID,
-		&product.ProductName,
-		&product.Stock,
-		&product.UpdatedAt,
-	)
+	// The initial state is set directly: a new product has not crossed
+	// anything, so no alert is queued for it.
+	product, err := scanProduct(tx.QueryRowxContext(ctx, query,
+		in.SKU, in.ProductName, in.Description, in.PriceMinor, in.Currency, string(in.Attributes), in.Status,
+		in.CategoryID, in.Stock, in.ReorderThreshold, StockStateFor(in.Stock, in.ReorderThreshold),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDuplicateSKU
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert product: %w", err)
 	}
 
-	return &product, nil
+	if err := logInitialStock(ctx, tx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func logInitialStock(ctx context.Context, tx *sqlx.Tx, product *Product) error {
+	if product.Stock == 0 {
+		return nil
+	}
+	_, err := insertStockLog(ctx, tx, StockChange{
+		ProductID: product.ProductID,
+		Change:    product.Stock,
+		Reason:    "product.created",
+	})
+	return err
 }
 
-func (r *PostgresProductRepository) UpdateProduct(ctx context.Context, productID int64, name string, stock int) (*Product, error) {
+// overwriteProduct writes every field of in to a locked product row, logs
+// the stock difference against previousStock under reason and queues a stock
+// alert if the new stock or threshold moved the product across it. A changed
+// variant price becomes the variant's override, as in PatchProduct.
+func overwriteProduct(ctx context.Context, tx *sqlx.Tx, productID int64, in ProductInput, previousStock int, reason string) (*Product, error) {
 	query := `
 		UPDATE inventory
-		SET stock = $1, product_name = $2, updated_at = NOW()
-		WHERE product_id = $3
-		RETURNING product_id, product_name, stock, updated_at;
+		SET stock = $1, product_name = $2, description = $3, price_minor = $4, currency = $5,
+			price_override_minor = CASE WHEN family_id IS NULL OR $4 = price_minor THEN price_override_minor ELSE $4 END,
+			attributes = $6, status = $7, category_id = $8, reorder_threshold = $9, version = version + 1, updated_at = NOW()
+		WHERE product_id = $10
 	`
+	if _, err := tx.ExecContext(ctx, query,
+		in.Stock, in.ProductName, in.Description, in.PriceMinor, in.Currency, string(in.Attributes), in.Status,
+		in.CategoryID, in.ReorderThreshold, productID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update product: %w", err)
+	}
 
-	var product Product
-	err := r.db.QueryRowContext(ctx, query, stock, name, productID).Scan(
-		&product.ProductID,
-		&product.ProductName,
-		&product.Stock,
-		&product.UpdatedAt,
-	)
+	if delta := in.Stock - previousStock; delta != 0 {
+		if _, err := insertStockLog(ctx, tx, StockChange{
+			ProductID: productID,
+			Change:    delta,
+			Reason:    reason,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return syncedProduct(ctx, tx, productID)
+}
+
+// syncedProduct refreshes the stock state of a locked product and returns the
+// row as it now stands.
+func syncedProduct(ctx context.Context, tx *sqlx.Tx, productID int64) (*Product, error) {
+	if err := syncStockState(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+	product, err := scanProduct(tx.QueryRowContext(ctx, `SELECT `+productColumns+` FROM inventory WHERE product_id = $1`, productID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to update product: %w", err)
+		return nil, fmt.Errorf("failed to reload product: %w", err)
 	}
+	return product, nil
+}
 
-	return &product, nil
+// PatchProduct updates catalog fields and the reorder threshold without
+// touching stock. The version check is optional because these fields cannot
+// lose a concurrent stock change. On a variant, a changed price is also
+// stored as price_override_minor, so that repricing the family later does not
+// revert it; a price equal to the current one keeps following the family.
+func (r *PostgresProductRepository) PatchProduct(ctx context.Context, productID int64, patch ProductPatch, expectedVersion *int64) (*Product, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin patch transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var stock, reserved int
+	if err := lockProductVersion(ctx, tx, productID, expectedVersion, &stock, &reserved); err != nil {
+		return nil, err
+	}
+	if err := checkCategory(ctx, tx, patch.CategoryID); err != nil {
+		return nil, err
+	}
+
+	product, err := patchProduct(ctx, tx, productID, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit product patch: %w", err)
+	}
+	return product, nil
 }
 
-func (r *PostgresProductRepository) DeleteProduct(ctx context.Context, productID int64) error {
+// patchProduct writes the fields set in patch to a locked product row and
+// refreshes its stock state.
+func patchProduct(ctx context.Context, tx *sqlx.Tx, productID int64, patch ProductPatch) (*Product, error) {
 	query := `
-		DELETE FROM inventory
-		WHERE product_id = $1;
+		UPDATE inventory
+		SET product_name = COALESCE($1, product_name),
+			description = COALESCE($2, description),
+			price_minor = COALESCE($3, price_minor),
+			price_override_minor = CASE WHEN family_id IS NULL OR $3 IS NULL OR $3 = price_minor
+				THEN price_override_minor ELSE $3 END,
+			currency = COALESCE($4, currency),
+			attributes = COALESCE($5, attributes),
+			status = COALESCE($6, status),
+			category_id = CASE WHEN $9 THEN NULL ELSE COALESCE($7, category_id) END,
+			reorder_threshold = COALESCE($8, reorder_threshold),
+			version = version + 1, updated_at = NOW()
+		WHERE product_id = $10
 	`
-	_, err := r.db.ExecContext(ctx, query, productID)
+
+	var attributes *string
+	if patch.Attributes != nil {
+		raw := string(patch.Attributes)
+		attributes = &raw
+	}
+	if _, err := tx.ExecContext(ctx, query,
+		patch.ProductName, patch.Description, patch.PriceMinor, patch.Currency, attributes, patch.Status, patch.CategoryID,
+		patch.ReorderThreshold, patch.ClearCategory, productID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to patch product: %w", err)
+	}
+
+	return syncedProduct(ctx, tx, productID)
+}
+
+func lockProductVersion(ctx context.Context, tx *sqlx.Tx, productID int64, expectedVersion *int64, stock, reserved *int) error {
+	var version int64
+	err := tx.QueryRowContext(ctx,
+		`SELECT stock, reserved, version FROM inventory WHERE product_id = $1 AND deleted_at IS NULL FOR UPDATE`, productID,
+	).Scan(stock, reserved, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("delete product failed: %w", err)
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		return ErrVersionMismatch
 	}
 	return nil
 }
 
-func (r *PostgresProductRepository) GetAllProducts(ctx context.Context) ([]Product, error) {
-	query := `SELECT product_id, product_name, stock, updated_at FROM inventory ORDER BY product_id ASC;`
-
-	rows, err := r.db.QueryContext(ctx, query)
+// DeleteProduct soft-deletes and archives a product. The row stays so that
+// stock logs, allocations and later cancellations of existing orders still
+// resolve it. Deletion is refused while stock is reserved for the product,
+// since those reservations would otherwise commit against a hidden product.
+func (r *PostgresProductRepository) DeleteProduct(ctx context.Context, productID int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("get all products failed: %w", err)
+		return fmt.Errorf("failed to begin delete transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var products []Product
-	for rows.Next() {
-		var p Product
-		if err := rows.Scan(&p.ProductID, &p.ProductName, &p.Stock, &p.UpdatedAt); err != nil {
-			return nil, err
-		}
-		products = append(products, p)
+	var stock, reserved int
+	if err := lockProductVersion(ctx, tx, productID, nil, &stock, &reserved); err != nil {
+		return err
+	}
+	if reserved > 0 {
+		return ErrProductInUse
+	}
+
+	query := `
+		UPDATE inventory
+		SET deleted_at = NOW(), status = $1, version = version + 1, updated_at = NOW()
+		WHERE product_id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, ProductStatusArchived, productID); err != nil {
+		return fmt.Errorf("delete product failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit product delete: %w", err)
 	}
-	return products, nil
+	return nil
+}
+
+// RestoreProduct undoes a soft delete and makes the product active again.
+func (r *PostgresProductRepository) RestoreProduct(ctx context.Context, productID int64) (*Product, error) {
+	query := `
+		UPDATE inventory
+		SET deleted_at = NULL, status = $1, version = version + 1, updated_at = NOW()
+		WHERE product_id = $2 AND deleted_at IS NOT NULL
+		RETURNING ` + productColumns
+
+	p, err := scanProduct(r.db.QueryRowContext(ctx, query, ProductStatusActive, productID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore product failed: %w", err)
+	}
+	return p, nil
 }
 
 func (r *PostgresProductRepository) GetByID(ctx context.Context, id int64) (*Product, error) {
-	query := `SELECT product_id, product_name, stock, updated_at FROM inventory WHERE product_id = $1`
+	query := `SELECT ` + productColumns + ` FROM inventory WHERE product_id = $1 AND deleted_at IS NULL`
 
-	var p Product
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&p.ProductID, &p.ProductName, &p.Stock, &p.UpdatedAt)
+	p, err := scanProduct(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("get product by ID failed: %w", err)
 	}
-	return &p, nil
+	return p, nil
 }
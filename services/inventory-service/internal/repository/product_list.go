@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// ProductFilter narrows and orders GET /products. Zero values mean "no
+// filter"; soft-deleted products are never listed. Cursor is the opaque
+// NextCursor of the previous page.
+type ProductFilter struct {
+	Query      string
+	CategoryID *int64
+	InStock    bool
+	MinPrice   *int64
+	MaxPrice   *int64
+	Status     string
+	Sort       string
+	Limit      int
+	Cursor     string
+}
+
+type ProductPage struct {
+	Items      []Product
+	NextCursor string
+}
+
+type productSort struct {
+	expr string
+	cast string
+	desc bool
+}
+
+// productSorts maps the public sort names to an ORDER BY expression. Every
+// sort is tie-broken by product_id so the keyset cursor is stable.
+var productSorts = map[string]productSort{
+	"id":          {expr: "product_id", cast: "bigint"},
+	"name":        {expr: "product_name", cast: "text"},
+	"-name":       {expr: "product_name", cast: "text", desc: true},
+	"price":       {expr: "price_minor", cast: "bigint"},
+	"-price":      {expr: "price_minor", cast: "bigint", desc: true},
+	"updated_at":  {expr: "updated_at", cast: "timestamp"},
+	"-updated_at": {expr: "updated_at", cast: "timestamp", desc: true},
+	"relevance":   {expr: "ts_rank(search_vector, websearch_to_tsquery('english', $1))::float8", cast: "float8", desc: true},
+}
+
+type productCursor struct {
+	Key string `json:"k"`
+	ID  int64  `json:"id"`
+}
+
+// sortKeyScanner appends the sort key column to the destinations of
+// scanProduct so the last row of a page can be turned into a cursor.
+type sortKeyScanner struct {
+	row rowScanner
+	key *string
+}
+
+func (s sortKeyScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.key)...)
+}
+
+// ListProducts returns one page of products using keyset pagination on
+// (sort key, product_id). Search matches product name and description.
+func (r *PostgresProductRepository) ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error) {
+	sortName := filter.Sort
+	if sortName == "" {
+		sortName = "id"
+		if filter.Query != "" {
+			sortName = "relevance"
+		}
+	}
+	sort, ok := productSorts[sortName]
+	if !ok || (sortName == "relevance" && filter.Query == "") {
+		return nil, ErrInvalidSort
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// The search query is always $1 so the relevance expression can refer to it.
+	where := []string{"deleted_at IS NULL"}
+	if filter.Query != "" {
+		where = append(where, "search_vector @@ websearch_to_tsquery('english', "+arg(filter.Query)+")")
+	}
+	if filter.CategoryID != nil {
+		where = append(where, `category_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM categories WHERE id = `+arg(*filter.CategoryID)+`
+				UNION ALL
+				SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+			)
+			SELECT id FROM tree
+		)`)
+	}
+	if filter.InStock {
+		where = append(where, "stock - reserved > 0")
+	}
+	if filter.MinPrice != nil {
+		where = append(where, "price_minor >= "+arg(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		where = append(where, "price_minor <= "+arg(*filter.MaxPrice))
+	}
+	if filter.Status != "" {
+		where = append(where, "status = "+arg(filter.Status))
+	}
+
+	direction, comparison := "ASC", ">"
+	if sort.desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeProductCursor(filter.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("(%s, product_id) %s (%s::%s, %s)",
+			sort.expr, comparison, arg(cursor.Key), sort.cast, arg(cursor.ID)))
+	}
+
+	query := `SELECT ` + productColumns + `, (` + sort.expr + `)::text FROM inventory WHERE ` + strings.Join(where, " AND ")
+	query += fmt.Sprintf(" ORDER BY %s %s, product_id %s LIMIT %s", sort.expr, direction, direction, arg(filter.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list products failed: %w", err)
+	}
+	defer rows.Close()
+
+	page := &ProductPage{Items: []Product{}}
+	var lastKey string
+	for rows.Next() {
+		var key string
+		p, err := scanProduct(sortKeyScanner{row: rows, key: &key})
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Items) == filter.Limit {
+			last := page.Items[len(page.Items)-1]
+			page.NextCursor = encodeProductCursor(productCursor{Key: lastKey, ID: last.ProductID})
+			break
+		}
+		page.Items = append(page.Items, *p)
+		lastKey = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list products failed: %w", err)
+	}
+	return page, nil
+}
+
+func encodeProductCursor(c productCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeProductCursor also checks that the cursor's key is a value of the
+// sort's type. A tampered cursor, or one from a listing with another sort,
+// would otherwise fail the cast in Postgres.
+func decodeProductCursor(s string, sort productSort) (productCursor, error) {
+	var c productCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID <= 0 {
+		return c, ErrInvalidCursor
+	}
+
+	switch sort.cast {
+	case "bigint":
+		_, err = strconv.ParseInt(c.Key, 10, 64)
+	case "float8":
+		_, err = strconv.ParseFloat(c.Key, 64)
+	case "timestamp":
+		_, err = time.Parse("2006-01-02 15:04:05.999999999", c.Key)
+	}
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestDecodeProductCursor(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		sort   string
+		cursor string
+		ok     bool
+	}{
+		{"id", "id", encodeProductCursor(productCursor{Key: "42", ID: 42}), true},
+		{"name", "name", encodeProductCursor(productCursor{Key: "Black Mug", ID: 12}), true},
+		{"price", "-price", encodeProductCursor(productCursor{Key: "129900", ID: 12}), true},
+		{"updated_at", "updated_at", encodeProductCursor(productCursor{Key: "2026-10-19 14:05:00.123456", ID: 12}), true},
+		{"relevance", "relevance", encodeProductCursor(productCursor{Key: "0.0607927", ID: 12}), true},
+		{"not base64", "id", "%%%", false},
+		{"not JSON", "id", raw("k=42"), false},
+		{"no id", "id", raw(`{"k": "42"}`), false},
+		{"text key for a number sort", "price", encodeProductCursor(productCursor{Key: "Black Mug", ID: 12}), false},
+		{"number key for a time sort", "-updated_at", encodeProductCursor(productCursor{Key: "129900", ID: 12}), false},
+		{"tampered key", "id", raw(`{"k": "1; DROP TABLE inventory", "id": 1}`), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeProductCursor(tt.cursor, productSorts[tt.sort])
+			if tt.ok && err != nil {
+				t.Fatalf("got %v, want the cursor accepted", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("got %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	PurchaseOrderStatusOpen              = "open"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusCancelled         = "cancelled"
+)
+
+var (
+	ErrSupplierNotFound      = errors.New("supplier not found")
+	ErrDuplicateSupplier     = errors.New("supplier code already exists")
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	ErrPurchaseOrderClosed   = errors.New("purchase order is no longer open")
+	ErrLineNotFound          = errors.New("purchase order line not found")
+	ErrOverReceipt           = errors.New("received quantity exceeds open quantity")
+)
+
+type PurchaseOrderRepository interface {
+	CreateSupplier(ctx context.Context, in Supplier) (*Supplier, error)
+	ListSuppliers(ctx context.Context) ([]Supplier, error)
+	CreatePurchaseOrder(ctx context.Context, in PurchaseOrderInput) (*PurchaseOrder, error)
+	GetPurchaseOrder(ctx context.Context, id int64) (*PurchaseOrder, error)
+	ListPurchaseOrders(ctx context.Context, status string, supplierID *int64) ([]PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, id int64, in ReceiptInput) (*PurchaseOrder, []Receipt, error)
+	CancelPurchaseOrder(ctx context.Context, id int64) (*PurchaseOrder, error)
+	SuggestReorders(ctx context.Context, params ReorderParams) ([]ReorderSuggestion, error)
+}
+
+type Supplier struct {
+	ID           int64     `db:"id" json:"id"`
+	Code         string    `db:"code" json:"code"`
+	Name         string    `db:"name" json:"name"`
+	Email        string    `db:"email" json:"email"`
+	LeadTimeDays int       `db:"lead_time_days" json:"lead_time_days"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+type PurchaseOrder struct {
+	ID         int64               `db:"id" json:"id"`
+	SupplierID int64               `db:"supplier_id" json:"supplier_id"`
+	Status     string              `db:"status" json:"status"`
+	ExpectedAt *time.Time          `db:"expected_at" json:"expected_at"`
+	Note       string              `db:"note" json:"note"`
+	CreatedAt  time.Time           `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time           `db:"updated_at" json:"updated_at"`
+	Lines      []PurchaseOrderLine `db:"-" json:"lines"`
+}
+
+// PurchaseOrderLine orders one product. ExpectedAt overrides the order's
+// date when the supplier ships the line separately.
+type PurchaseOrderLine struct {
+	ID               int64      `db:"id" json:"id"`
+	PurchaseOrderID  int64      `db:"purchase_order_id" json:"purchase_order_id"`
+	ProductID        int64      `db:"product_id" json:"product_id"`
+	QuantityOrdered  int        `db:"quantity_ordered" json:"quantity_ordered"`
+	QuantityReceived int        `db:"quantity_received" json:"quantity_received"`
+	UnitCostMinor    int64      `db:"unit_cost_minor" json:"unit_cost_minor"`
+	ExpectedAt       *time.Time `db:"expected_at" json:"expected_at"`
+}
+
+type PurchaseOrderInput struct {
+	SupplierID int64
+	ExpectedAt *time.Time
+	Note       string
+	Lines      []PurchaseOrderLineInput
+}
+
+type PurchaseOrderLineInput struct {
+	ProductID     int64
+	Quantity      int
+	UnitCostMinor int64
+	ExpectedAt    *time.Time
+}
+
+// ReceiptInput books goods arriving against a purchase order. All lines are
+// put away to LocationID, or to the unassigned pool when it is nil.
+type ReceiptInput struct {
+	Actor      string
+	Note       string
+	LocationID *int64
+	Lines      []ReceiptLineInput
+}
+
+// ReceiptLineInput receives Quantity against one line. A non-nil Lot books
+// the units into that lot; without one they stay untracked.
+type ReceiptLineInput struct {
+	LineID   int64
+	Quantity int
+	Lot      *LotInput
+}
+
+type Receipt struct {
+	ID              int64     `db:"id" json:"id"`
+	PurchaseOrderID int64     `db:"purchase_order_id" json:"purchase_order_id"`
+	LineID          int64     `db:"line_id" json:"line_id"`
+	ProductID       int64     `db:"product_id" json:"product_id"`
+	Quantity        int       `db:"quantity" json:"quantity"`
+	LocationID      *int64    `db:"location_id" json:"location_id,omitempty"`
+	LotID           *int64    `db:"lot_id" json:"lot_id,omitempty"`
+	StockLogID      int64     `db:"stock_log_id" json:"stock_log_id"`
+	Actor           string    `db:"actor" json:"actor"`
+	ReceivedAt      time.Time `db:"received_at" json:"received_at"`
+}
+
+const (
+	supplierColumns      = `id, code, name, email, lead_time_days, created_at`
+	purchaseOrderColumns = `id, supplier_id, status, expected_at, note, created_at, updated_at`
+	poLineColumns        = `id, purchase_order_id, product_id, quantity_ordered, quantity_received, unit_cost_minor, expected_at`
+	receiptColumns       = `id, purchase_order_id, line_id, product_id, quantity, location_id, lot_id, stock_log_id, actor, received_at`
+)
+
+type PostgresPurchaseOrderRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresPurchaseOrderRepository(db *sqlx.DB) *PostgresPurchaseOrderRepository {
+	return &PostgresPurchaseOrderRepository{db: db}
+}
+
+func (r *PostgresPurchaseOrderRepository) CreateSupplier(ctx context.Context, in Supplier) (*Supplier, error) {
+	query := `
+		INSERT INTO suppliers (code, name, email, lead_time_days)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING ` + supplierColumns
+
+	var s Supplier
+	err := r.db.GetContext(ctx, &s, query, in.Code, in.Name, in.Email, in.LeadTimeDays)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDuplicateSupplier
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert supplier: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *PostgresPurchaseOrderRepository) ListSuppliers(ctx context.Context) ([]Supplier, error) {
+	list := []Supplier{}
+	if err := r.db.SelectContext(ctx, &list, `SELECT `+supplierColumns+` FROM suppliers ORDER BY code ASC`); err != nil {
+		return nil, fmt.Errorf("list suppliers failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresPurchaseOrderRepository) CreatePurchaseOrder(ctx context.Context, in PurchaseOrderInput) (*PurchaseOrder, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin purchase order transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM suppliers WHERE id = $1)`, in.SupplierID); err != nil {
+		return nil, fmt.Errorf("failed to check supplier: %w", err)
+	}
+	if !exists {
+		return nil, ErrSupplierNotFound
+	}
+
+	var po PurchaseOrder
+	err = tx.GetContext(ctx, &po, `
+		INSERT INTO purchase_orders (supplier_id, expected_at, note)
+		VALUES ($1, $2, $3)
+		RETURNING `+purchaseOrderColumns,
+		in.SupplierID, in.ExpectedAt, in.Note,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert purchase order: %w", err)
+	}
+
+	po.Lines = make([]PurchaseOrderLine, 0, len(in.Lines))
+	for _, line := range in.Lines {
+		if err := tx.GetContext(ctx, &exists,
+			`SELECT EXISTS (SELECT 1 FROM inventory WHERE product_id = $1 AND deleted_at IS NULL)`, line.ProductID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to check product: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: %d", ErrProductNotFound, line.ProductID)
+		}
+
+		var l PurchaseOrderLine
+		err := tx.GetContext(ctx, &l, `
+			INSERT INTO purchase_order_lines (purchase_order_id, product_id, quantity_ordered, unit_cost_minor, expected_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+poLineColumns,
+			po.ID, line.ProductID, line.Quantity, line.UnitCostMinor, line.ExpectedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert purchase order line: %w", err)
+		}
+		po.Lines = append(po.Lines, l)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purchase order: %w", err)
+	}
+	return &po, nil
+}
+
+func (r *PostgresPurchaseOrderRepository) GetPurchaseOrder(ctx context.Context, id int64) (*PurchaseOrder, error) {
+	return getPurchaseOrder(ctx, r.db, id)
+}
+
+func (r *PostgresPurchaseOrderRepository) ListPurchaseOrders(ctx context.Context, status string, supplierID *int64) ([]PurchaseOrder, error) {
+	query := `
+		SELECT ` + purchaseOrderColumns + `
+		FROM purchase_orders
+		WHERE ($1 = '' OR status = $1) AND ($2::bigint IS NULL OR supplier_id = $2)
+		ORDER BY id DESC
+	`
+	list := []PurchaseOrder{}
+	if err := r.db.SelectContext(ctx, &list, query, status, supplierID); err != nil {
+		return nil, fmt.Errorf("list purchase orders failed: %w", err)
+	}
+	for i := range list {
+		lines, err := listPurchaseOrderLines(ctx, r.db, list[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Lines = lines
+	}
+	return list, nil
+}
+
+// ReceivePurchaseOrder books a (possibly partial) delivery. Each line goes
+// through applyStockChange like any other stock movement, so it is logged in
+// stock_logs and can trigger stock alerts. The order becomes received once
+// every line is complete. inventory.received is queued in the outbox with the
+// receipts, in the same transaction.
+func (r *PostgresPurchaseOrderRepository) ReceivePurchaseOrder(ctx context.Context, id int64, in ReceiptInput) (*PurchaseOrder, []Receipt, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin receipt transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenPurchaseOrder(ctx, tx, id); err != nil {
+		return nil, nil, err
+	}
+	if in.LocationID != nil {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM locations WHERE id = $1)`, *in.LocationID); err != nil {
+			return nil, nil, fmt.Errorf("failed to check location: %w", err)
+		}
+		if !exists {
+			return nil, nil, ErrLocationNotFound
+		}
+	}
+
+	receipts := make([]Receipt, 0, len(in.Lines))
+	for _, line := range in.Lines {
+		var productID int64
+		var open int
+		err := tx.QueryRowContext(ctx, `
+			SELECT product_id, quantity_ordered - quantity_received
+			FROM purchase_order_lines
+			WHERE id = $1 AND purchase_order_id = $2
+			FOR UPDATE
+		`, line.LineID, id).Scan(&productID, &open)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("%w: %d", ErrLineNotFound, line.LineID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to lock purchase order line: %w", err)
+		}
+		if line.Quantity > open {
+			return nil, nil, fmt.Errorf("%w: line %d has %d open", ErrOverReceipt, line.LineID, open)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE purchase_order_lines SET quantity_received = quantity_received + $1 WHERE id = $2`, line.Quantity, line.LineID,
+		); err != nil {
+			return nil, nil, fmt.Errorf("failed to update purchase order line: %w", err)
+		}
+
+		entry, err := applyStockChange(ctx, tx, StockChange{
+			ProductID:  productID,
+			Change:     line.Quantity,
+			Reason:     "purchase_order.received",
+			LocationID: in.LocationID,
+			Actor:      in.Actor,
+			Note:       receiptNote(id, in.Note),
+		})
+		if errors.Is(err, ErrInsufficientStock) {
+			return nil, nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if in.LocationID != nil {
+			if err := addLocationStock(ctx, tx, *in.LocationID, productID, line.Quantity); err != nil {
+				return nil, nil, err
+			}
+		}
+		var lotID *int64
+		if line.Lot != nil {
+			lot, err := addLotStock(ctx, tx, productID, *line.Lot, line.Quantity)
+			if err != nil {
+				return nil, nil, err
+			}
+			lotID = &lot.ID
+		}
+
+		var receipt Receipt
+		err = tx.GetContext(ctx, &receipt, `
+			INSERT INTO purchase_order_receipts (purchase_order_id, line_id, product_id, quantity, location_id, lot_id, stock_log_id, actor)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING `+receiptColumns,
+			id, line.LineID, productID, line.Quantity, in.LocationID, lotID, entry.ID, in.Actor,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to insert receipt: %w", err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE purchase_orders
+		SET status = CASE
+				WHEN NOT EXISTS (
+					SELECT 1 FROM purchase_order_lines
+					WHERE purchase_order_id = $1 AND quantity_received < quantity_ordered
+				) THEN 'received'
+				ELSE 'partially_received'
+			END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update purchase order status: %w", err)
+	}
+
+	po, err := getPurchaseOrder(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	received := events.Received{
+		PurchaseOrderID: po.ID,
+		SupplierID:      po.SupplierID,
+		Status:          po.Status,
+		Receipts:        make([]events.GoodsReceipt, len(receipts)),
+	}
+	for i, receipt := range receipts {
+		received.Receipts[i] = events.GoodsReceipt(receipt)
+	}
+	if err := enqueueEvent(ctx, tx, events.TypeReceived, received); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit receipt: %w", err)
+	}
+	return po, receipts, nil
+}
+
+// CancelPurchaseOrder closes an order that is still waiting for goods. What
+// was already received stays in stock.
+func (r *PostgresPurchaseOrderRepository) CancelPurchaseOrder(ctx context.Context, id int64) (*PurchaseOrder, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin cancel transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenPurchaseOrder(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE purchase_orders SET status = 'cancelled', updated_at = NOW() WHERE id = $1`, id,
+	); err != nil {
+		return nil, fmt.Errorf("failed to cancel purchase order: %w", err)
+	}
+
+	po, err := getPurchaseOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purchase order cancel: %w", err)
+	}
+	return po, nil
+}
+
+func lockOpenPurchaseOrder(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	var status string
+	err := tx.GetContext(ctx, &status, `SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock purchase order: %w", err)
+	}
+	if status != PurchaseOrderStatusOpen && status != PurchaseOrderStatusPartiallyReceived {
+		return ErrPurchaseOrderClosed
+	}
+	return nil
+}
+
+func getPurchaseOrder(ctx context.Context, q sqlx.QueryerContext, id int64) (*PurchaseOrder, error) {
+	var po PurchaseOrder
+	err := sqlx.GetContext(ctx, q, &po, `SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get purchase order failed: %w", err)
+	}
+	if po.Lines, err = listPurchaseOrderLines(ctx, q, id); err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+func listPurchaseOrderLines(ctx context.Context, q sqlx.QueryerContext, id int64) ([]PurchaseOrderLine, error) {
+	lines := []PurchaseOrderLine{}
+	query := `SELECT ` + poLineColumns + ` FROM purchase_order_lines WHERE purchase_order_id = $1 ORDER BY id ASC`
+	if err := sqlx.SelectContext(ctx, q, &lines, query, id); err != nil {
+		return nil, fmt.Errorf("list purchase order lines failed: %w", err)
+	}
+	return lines, nil
+}
+
+func receiptNote(purchaseOrderID int64, note string) string {
+	if note == "" {
+		return fmt.Sprintf("PO-%d", purchaseOrderID)
+	}
+	return fmt.Sprintf("PO-%d: %s", purchaseOrderID, note)
+}
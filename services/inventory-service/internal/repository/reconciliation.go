@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	ReconciliationTriggerScheduled = "scheduled"
+	ReconciliationTriggerManual    = "manual"
+)
+
+var ErrReconciliationNotFound = errors.New("reconciliation run not found")
+
+type ReconciliationRepository interface {
+	Reconcile(ctx context.Context, trigger string) (*ReconciliationRun, error)
+	GetRun(ctx context.Context, id int64) (*ReconciliationRun, error)
+	ListRuns(ctx context.Context, limit int) ([]ReconciliationRun, error)
+}
+
+type ReconciliationRun struct {
+	ID              int64        `db:"id" json:"id"`
+	Trigger         string       `db:"trigger" json:"trigger"`
+	ProductsChecked int          `db:"products_checked" json:"products_checked"`
+	Drifted         int          `db:"drifted" json:"drifted"`
+	StartedAt       time.Time    `db:"started_at" json:"started_at"`
+	FinishedAt      *time.Time   `db:"finished_at" json:"finished_at"`
+	Drifts          []StockDrift `db:"-" json:"drifts,omitempty"`
+}
+
+// StockDrift is a product whose inventory.stock differs from the net of its
+// stock_logs. A positive Drift means there is more stock than the log explains.
+type StockDrift struct {
+	ProductID     int64  `db:"product_id" json:"product_id"`
+	SKU           string `db:"sku" json:"sku"`
+	ProductName   string `db:"product_name" json:"product_name"`
+	Stock         int    `db:"stock" json:"stock"`
+	ExpectedStock int    `db:"expected_stock" json:"expected_stock"`
+	Drift         int    `db:"drift" json:"drift"`
+}
+
+const reconciliationRunColumns = `id, trigger, products_checked, drifted, started_at, finished_at`
+
+type PostgresReconciliationRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresReconciliationRepository(db *sqlx.DB) *PostgresReconciliationRepository {
+	return &PostgresReconciliationRepository{db: db}
+}
+
+// Reconcile recomputes every product's expected stock from stock_logs and
+// records the products that drifted. Expectation and drift are computed in a
+// single statement, so both sides come from the same snapshot even while
+// orders keep moving stock. A run that found drift queues inventory.stock_drift.
+func (r *PostgresReconciliationRepository) Reconcile(ctx context.Context, trigger string) (*ReconciliationRun, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reconciliation transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.GetContext(ctx, &id, `INSERT INTO reconciliation_runs (trigger) VALUES ($1) RETURNING id`, trigger); err != nil {
+		return nil, fmt.Errorf("failed to insert reconciliation run: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH expected AS (
+			SELECT i.product_id, i.stock, COALESCE(l.total, 0) AS expected_stock
+			FROM inventory i
+			LEFT JOIN (
+				SELECT product_id, SUM(change) AS total FROM stock_logs GROUP BY product_id
+			) l ON l.product_id = i.product_id
+		), drifts AS (
+			INSERT INTO reconciliation_drifts (run_id, product_id, stock, expected_stock, drift)
+			SELECT $1, product_id, stock, expected_stock, stock - expected_stock
+			FROM expected
+			WHERE stock <> expected_stock
+			RETURNING 1
+		)
+		UPDATE reconciliation_runs
+		SET products_checked = (SELECT COUNT(*) FROM expected),
+			drifted = (SELECT COUNT(*) FROM drifts),
+			finished_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile stock: %w", err)
+	}
+
+	run, err := getReconciliationRun(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Drifted > 0 {
+		if err := enqueueEvent(ctx, tx, events.TypeStockDrift, stockDriftEvent(run)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reconciliation: %w", err)
+	}
+	return run, nil
+}
+
+// stockDriftEvent is the inventory.stock_drift payload of a run that found
+// products whose stock disagrees with stock_logs.
+func stockDriftEvent(run *ReconciliationRun) events.StockDrift {
+	drifts := make([]events.ProductStockDrift, len(run.Drifts))
+	for i, d := range run.Drifts {
+		drifts[i] = events.ProductStockDrift(d)
+	}
+	return events.StockDrift{RunID: run.ID, Trigger: run.Trigger, Drifted: run.Drifted, Drifts: drifts}
+}
+
+func (r *PostgresReconciliationRepository) GetRun(ctx context.Context, id int64) (*ReconciliationRun, error) {
+	return getReconciliationRun(ctx, r.db, id)
+}
+
+// ListRuns returns the most recent runs without their drift details.
+func (r *PostgresReconciliationRepository) ListRuns(ctx context.Context, limit int) ([]ReconciliationRun, error) {
+	query := `SELECT ` + reconciliationRunColumns + ` FROM reconciliation_runs ORDER BY id DESC LIMIT $1`
+
+	list := []ReconciliationRun{}
+	if err := r.db.SelectContext(ctx, &list, query, limit); err != nil {
+		return nil, fmt.Errorf("list reconciliation runs failed: %w", err)
+	}
+	return list, nil
+}
+
+func getReconciliationRun(ctx context.Context, q sqlx.QueryerContext, id int64) (*ReconciliationRun, error) {
+	var run ReconciliationRun
+	err := sqlx.GetContext(ctx, q, &run, `SELECT `+reconciliationRunColumns+` FROM reconciliation_runs WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReconciliationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliation run failed: %w", err)
+	}
+
+	run.Drifts = []StockDrift{}
+	query := `
+		SELECT d.product_id, i.sku, i.product_name, d.stock, d.expected_stock, d.drift
+		FROM reconciliation_drifts d
+		JOIN inventory i ON i.product_id = d.product_id
+		WHERE d.run_id = $1
+		ORDER BY ABS(d.drift) DESC, d.product_id ASC
+	`
+	if err := sqlx.SelectContext(ctx, q, &run.Drifts, query, id); err != nil {
+		return nil, fmt.Errorf("list reconciliation drifts failed: %w", err)
+	}
+	return &run, nil
+}
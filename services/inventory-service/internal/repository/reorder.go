@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ReorderParams tunes SuggestReorders. Consumption is averaged over
+// LookbackDays; the suggestion covers the supplier's lead time plus
+// CoverDays. DefaultLeadTimeDays applies to products never ordered before.
+type ReorderParams struct {
+	LookbackDays        int
+	CoverDays           int
+	DefaultLeadTimeDays int
+	SupplierID          *int64
+}
+
+type ReorderSuggestion struct {
+	ProductID         int64   `db:"product_id" json:"product_id"`
+	SKU               string  `db:"sku" json:"sku"`
+	ProductName       string  `db:"product_name" json:"product_name"`
+	Stock             int     `db:"stock" json:"stock"`
+	Available         int     `db:"available" json:"available"`
+	OnOrder           int     `db:"on_order" json:"on_order"`
+	ReorderThreshold  int     `db:"reorder_threshold" json:"reorder_threshold"`
+	Consumed          int     `db:"consumed" json:"consumed"`
+	DailyConsumption  float64 `db:"-" json:"daily_consumption"`
+	SupplierID        *int64  `db:"supplier_id" json:"supplier_id"`
+	LeadTimeDays      *int    `db:"lead_time_days" json:"lead_time_days"`
+	SuggestedQuantity int     `db:"-" json:"suggested_quantity"`
+}
+
+// SuggestReorders proposes order quantities for active products from their
+// net order consumption in stock_logs (orders minus cancellations). Products
+// whose available stock and open purchase orders already cover the demand
+// are left out. The supplier is the one the product was last ordered from.
+func (r *PostgresPurchaseOrderRepository) SuggestReorders(ctx context.Context, params ReorderParams) ([]ReorderSuggestion, error) {
+	query := `
+		WITH consumption AS (
+			SELECT product_id, SUM(-change) AS consumed
+			FROM stock_logs
+			WHERE reason IN ('order.created', 'order.cancelled')
+				AND created_at >= NOW() - make_interval(days => $1)
+			GROUP BY product_id
+		), on_order AS (
+			SELECT l.product_id, SUM(l.quantity_ordered - l.quantity_received) AS quantity
+			FROM purchase_order_lines l
+			JOIN purchase_orders po ON po.id = l.purchase_order_id
+			WHERE po.status IN ('open', 'partially_received')
+			GROUP BY l.product_id
+		)
+		SELECT i.product_id, i.sku, i.product_name, i.stock, i.stock - i.reserved AS available,
+			COALESCE(o.quantity, 0) AS on_order, i.reorder_threshold,
+			GREATEST(COALESCE(c.consumed, 0), 0) AS consumed,
+			s.supplier_id, s.lead_time_days
+		FROM inventory i
+		LEFT JOIN consumption c ON c.product_id = i.product_id
+		LEFT JOIN on_order o ON o.product_id = i.product_id
+		LEFT JOIN LATERAL (
+			SELECT po.supplier_id, su.lead_time_days
+			FROM purchase_order_lines l
+			JOIN purchase_orders po ON po.id = l.purchase_order_id
+			JOIN suppliers su ON su.id = po.supplier_id
+			WHERE l.product_id = i.product_id
+			ORDER BY po.created_at DESC, po.id DESC
+			LIMIT 1
+		) s ON TRUE
+		WHERE i.deleted_at IS NULL AND i.status = 'active'
+			AND (c.consumed > 0 OR i.stock - i.reserved <= i.reorder_threshold)
+			AND ($2::bigint IS NULL OR s.supplier_id = $2)
+	`
+	var candidates []ReorderSuggestion
+	if err := r.db.SelectContext(ctx, &candidates, query, params.LookbackDays, params.SupplierID); err != nil {
+		return nil, fmt.Errorf("suggest reorders failed: %w", err)
+	}
+
+	suggestions := []ReorderSuggestion{}
+	for _, s := range candidates {
+		leadTime := params.DefaultLeadTimeDays
+		if s.LeadTimeDays != nil {
+			leadTime = *s.LeadTimeDays
+		}
+		s.DailyConsumption = float64(s.Consumed) / float64(params.LookbackDays)
+		s.SuggestedQuantity = suggestQuantity(s.DailyConsumption, leadTime+params.CoverDays, s.ReorderThreshold, s.Available, s.OnOrder)
+		if s.SuggestedQuantity > 0 {
+			suggestions = append(suggestions, s)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].SuggestedQuantity > suggestions[j].SuggestedQuantity
+	})
+	return suggestions, nil
+}
+
+// suggestQuantity tops stock up to the expected demand over horizonDays plus
+// the reorder threshold, which acts as safety stock, minus what is available
+// or already on order. Without demand it still lifts a product above its
+// threshold so it leaves the low-stock state.
+func suggestQuantity(daily float64, horizonDays, threshold, available, onOrder int) int {
+	target := int(math.Ceil(daily*float64(horizonDays))) + threshold
+	if target <= threshold {
+		target = threshold + 1
+	}
+	if need := target - available - onOrder; need > 0 {
+		return need
+	}
+	return 0
+}
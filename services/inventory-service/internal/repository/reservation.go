@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	ReservationStatusReserved  = "reserved"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+	ReservationStatusExpired   = "expired"
+)
+
+var (
+	ErrReservationNotFound = errors.New("no active reservation for order")
+	ErrReservationSettled  = errors.New("reservation for order is no longer active")
+)
+
+type ReservationRepository interface {
+	Reserve(ctx context.Context, orderID, productID int64, quantity int, ttl time.Duration) (*Reservation, error)
+	Commit(ctx context.Context, orderID int64) ([]Reservation, error)
+	Release(ctx context.Context, orderID int64) ([]Reservation, error)
+	ExpireDue(ctx context.Context, limit int) ([]Reservation, error)
+	GetByOrderID(ctx context.Context, orderID int64) ([]Reservation, error)
+}
+
+type Reservation struct {
+	ID        int64     `db:"id" json:"id"`
+	OrderID   int64     `db:"order_id" json:"order_id"`
+	ProductID int64     `db:"product_id" json:"product_id"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	Status    string    `db:"status" json:"status"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type PostgresReservationRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresReservationRepository(db *sqlx.DB) *PostgresReservationRepository {
+	return &PostgresReservationRepository{db: db}
+}
+
+const reservationColumns = `id, order_id, product_id, quantity, status, expires_at, created_at, updated_at`
+
+// Reserve holds quantity against the product's available stock until ttl
+// elapses. Reserving the same order and product twice returns the existing
+// hold while it is active; once it has expired or been committed or released,
+// ErrReservationSettled is returned instead.
+func (r *PostgresReservationRepository) Reserve(ctx context.Context, orderID, productID int64, quantity int, ttl time.Duration) (*Reservation, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reservation transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+		INSERT INTO stock_reservations (order_id, product_id, quantity, status, expires_at)
+		VALUES ($1, $2, $3, 'reserved', NOW() + make_interval(secs => $4))
+		ON CONFLICT (order_id, product_id) DO NOTHING
+		RETURNING ` + reservationColumns
+
+	var res Reservation
+	err = tx.GetContext(ctx, &res, insertQuery, orderID, productID, quantity, ttl.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		query := `SELECT ` + reservationColumns + ` FROM stock_reservations WHERE order_id = $1 AND product_id = $2`
+		if err := tx.GetContext(ctx, &res, query, orderID, productID); err != nil {
+			return nil, fmt.Errorf("failed to load existing reservation: %w", err)
+		}
+		return activeReservation(res, time.Now())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert reservation: %w", err)
+	}
+
+	holdQuery := `
+		UPDATE inventory
+		SET reserved = reserved + $1, version = version + 1, updated_at = NOW()
+		WHERE product_id = $2 AND stock - reserved >= $1 AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, holdQuery, quantity, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hold stock: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	return &res, nil
+}
+
+// activeReservation returns res if it still holds stock at now. A hold past
+// its expiry that the sweeper has not reached yet no longer counts.
+func activeReservation(res Reservation, now time.Time) (*Reservation, error) {
+	if res.Status != ReservationStatusReserved || !res.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: order %d is %s", ErrReservationSettled, res.OrderID, res.Status)
+	}
+	return &res, nil
+}
+
+// Commit turns the order's active holds into real stock decrements, logged as
+// order.created so the consumer's idempotency guard covers both paths.
+func (r *PostgresReservationRepository) Commit(ctx context.Context, orderID int64) ([]Reservation, error) {
+	return r.settle(ctx, orderID, ReservationStatusCommitted)
+}
+
+// Release drops the order's active holds without touching on-hand stock.
+func (r *PostgresReservationRepository) Release(ctx context.Context, orderID int64) ([]Reservation, error) {
+	return r.settle(ctx, orderID, ReservationStatusReleased)
+}
+
+func (r *PostgresReservationRepository) settle(ctx context.Context, orderID int64, status string) ([]Reservation, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reservation transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE stock_reservations
+		SET status = $1, updated_at = NOW()
+		WHERE order_id = $2 AND status = 'reserved'
+		RETURNING ` + reservationColumns
+
+	var settled []Reservation
+	if err := tx.SelectContext(ctx, &settled, query, status, orderID); err != nil {
+		return nil, fmt.Errorf("failed to update reservation: %w", err)
+	}
+	if len(settled) == 0 {
+		return nil, ErrReservationNotFound
+	}
+
+	for _, res := range settled {
+		if err := releaseHold(ctx, tx, res); err != nil {
+			return nil, err
+		}
+		if status != ReservationStatusCommitted {
+			continue
+		}
+		if _, err := fulfilOrder(ctx, tx, res.OrderID, res.ProductID, res.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reservation settlement: %w", err)
+	}
+	return settled, nil
+}
+
+// ExpireDue marks up to limit overdue holds as expired, returns their stock
+// to the available pool and queues inventory.reservation_expired for each.
+// SKIP LOCKED lets several instances sweep in parallel.
+func (r *PostgresReservationRepository) ExpireDue(ctx context.Context, limit int) ([]Reservation, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin expiry transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE stock_reservations
+		SET status = 'expired', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM stock_reservations
+			WHERE status = 'reserved' AND expires_at <= NOW()
+			ORDER BY expires_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + reservationColumns
+
+	var expired []Reservation
+	if err := tx.SelectContext(ctx, &expired, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+
+	for _, res := range expired {
+		if err := releaseHold(ctx, tx, res); err != nil {
+			return nil, err
+		}
+		if err := enqueueEvent(ctx, tx, events.TypeReservationExpired, events.ReservationExpired{
+			ReservationID: res.ID,
+			OrderID:       res.OrderID,
+			ProductID:     res.ProductID,
+			Quantity:      res.Quantity,
+			ExpiredAt:     res.UpdatedAt,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reservation expiry: %w", err)
+	}
+	return expired, nil
+}
+
+func (r *PostgresReservationRepository) GetByOrderID(ctx context.Context, orderID int64) ([]Reservation, error) {
+	query := `SELECT ` + reservationColumns + ` FROM stock_reservations WHERE order_id = $1 ORDER BY id ASC`
+
+	var list []Reservation
+	if err := r.db.SelectContext(ctx, &list, query, orderID); err != nil {
+		return nil, fmt.Errorf("get reservations by order failed: %w", err)
+	}
+	return list, nil
+}
+
+func releaseHold(ctx context.Context, tx *sqlx.Tx, res Reservation) error {
+	query := `
+		UPDATE inventory
+		SET reserved = reserved - $1, version = version + 1, updated_at = NOW()
+		WHERE product_id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, res.Quantity, res.ProductID); err != nil {
+		return fmt.Errorf("failed to release held stock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActiveReservation(t *testing.T) {
+	now := time.Date(2026, 10, 19, 14, 5, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		status    string
+		expiresAt time.Time
+		active    bool
+	}{
+		{"reserved", ReservationStatusReserved, now.Add(time.Minute), true},
+		{"reserved past expiry", ReservationStatusReserved, now.Add(-time.Second), false},
+		{"expired", ReservationStatusExpired, now.Add(-time.Minute), false},
+		{"committed", ReservationStatusCommitted, now.Add(time.Minute), false},
+		{"released", ReservationStatusReleased, now.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := Reservation{ID: 3, OrderID: 1042, ProductID: 12, Quantity: 2, Status: tt.status, ExpiresAt: tt.expiresAt}
+			res, err := activeReservation(existing, now)
+			if tt.active {
+				if err != nil || res == nil || res.ID != existing.ID {
+					t.Fatalf("got %+v, %v; want the existing reservation", res, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrReservationSettled) {
+				t.Fatalf("got %+v, %v; want ErrReservationSettled", res, err)
+			}
+		})
+	}
+}
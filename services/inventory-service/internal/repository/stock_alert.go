@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	StockStateInStock    = "in_stock"
+	StockStateLowStock   = "low_stock"
+	StockStateOutOfStock = "out_of_stock"
+)
+
+// stockStateExpr mirrors StockStateFor for use inside UPDATE statements.
+const stockStateExpr = `CASE
+		WHEN stock <= 0 THEN 'out_of_stock'
+		WHEN stock <= reorder_threshold THEN 'low_stock'
+		ELSE 'in_stock'
+	END`
+
+// StockStateFor classifies on-hand stock against a reorder threshold. A
+// threshold of zero disables low-stock alerts; out-of-stock always applies.
+func StockStateFor(stock, threshold int) string {
+	switch {
+	case stock <= 0:
+		return StockStateOutOfStock
+	case stock <= threshold:
+		return StockStateLowStock
+	default:
+		return StockStateInStock
+	}
+}
+
+type StockAlertRepository interface {
+	PublishPending(ctx context.Context, limit int, publish func(StockAlert) error) (int, error)
+}
+
+// StockAlert is a queued change of a product's stock state.
+type StockAlert struct {
+	ID            int64     `db:"id"`
+	ProductID     int64     `db:"product_id"`
+	SKU           string    `db:"sku"`
+	ProductName   string    `db:"product_name"`
+	PreviousState string    `db:"previous_state"`
+	State         string    `db:"state"`
+	Stock         int       `db:"stock"`
+	Threshold     int       `db:"threshold"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// BackInStock reports whether the alert marks stock going from zero to
+// positive.
+func (a StockAlert) BackInStock() bool {
+	return a.PreviousState == StockStateOutOfStock && a.State != StockStateOutOfStock
+}
+
+// EventType names the event announcing the new state. Moving from
+// out_of_stock to low_stock is reported as low_stock; restocked is reserved
+// for stock climbing back above the threshold.
+func (a StockAlert) EventType() string {
+	switch a.State {
+	case StockStateOutOfStock:
+		return events.TypeOutOfStock
+	case StockStateLowStock:
+		return events.TypeLowStock
+	default:
+		return events.TypeRestocked
+	}
+}
+
+type PostgresStockAlertRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresStockAlertRepository(db *sqlx.DB) *PostgresStockAlertRepository {
+	return &PostgresStockAlertRepository{db: db}
+}
+
+// PublishPending hands up to limit pending alerts to publish in the order
+// they were queued, marks each one published or quarantined as relayBatch
+// decides, and returns how many it marked. SKIP LOCKED lets several instances relay in parallel.
+func (r *PostgresStockAlertRepository) PublishPending(ctx context.Context, limit int, publish func(StockAlert) error) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin stock alert transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT a.id, a.product_id, i.sku, i.product_name, a.previous_state, a.state, a.stock, a.threshold, a.created_at
+		FROM stock_alerts a
+		JOIN inventory i ON i.product_id = a.product_id
+		WHERE a.published_at IS NULL AND a.quarantined_at IS NULL
+		ORDER BY a.id ASC
+		LIMIT $1
+		FOR UPDATE OF a SKIP LOCKED
+	`
+	var pending []StockAlert
+	if err := tx.SelectContext(ctx, &pending, query, limit); err != nil {
+		return 0, fmt.Errorf("failed to load pending stock alerts: %w", err)
+	}
+
+	relayed, publishErr := relayBatch(pending, publish, func(alert StockAlert, column string) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE stock_alerts SET `+column+` = NOW() WHERE id = $1`, alert.ID); err != nil {
+			return fmt.Errorf("failed to mark stock alert %s: %w", column, err)
+		}
+		return nil
+	})
+	if errors.Is(publishErr, errMarkFailed) {
+		return 0, publishErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit stock alerts: %w", err)
+	}
+	return relayed, publishErr
+}
+
+// syncStockState recomputes a product's stock state after its stock or
+// threshold changed and queues an alert when the state moved. Comparing with
+// the stored state is what debounces alerts: further decrements below the
+// threshold leave the state unchanged and queue nothing. When the product
+// comes back from zero its back-in-stock subscriptions are claimed by the
+// alert and inventory.back_in_stock is queued in the outbox, in the same
+// transaction but as a row of its own, so each event is relayed on its own.
+func syncStockState(ctx context.Context, tx *sqlx.Tx, productID int64) error {
+	query := `
+		WITH changed AS (
+			UPDATE inventory i
+			SET stock_state = s.state
+			FROM (
+				SELECT product_id, stock_state AS previous_state, ` + stockStateExpr + ` AS state
+				FROM inventory
+				WHERE product_id = $1
+			) s
+			WHERE i.product_id = s.product_id AND s.state <> s.previous_state
+			RETURNING i.product_id, s.previous_state, s.state, i.stock, i.reorder_threshold
+		)
+		INSERT INTO stock_alerts (product_id, previous_state, state, stock, threshold)
+		SELECT product_id, previous_state, state, stock, reorder_threshold FROM changed
+		RETURNING id, product_id, previous_state, state, stock, created_at
+	`
+	var alert StockAlert
+	err := tx.GetContext(ctx, &alert, query, productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update stock state: %w", err)
+	}
+
+	if !alert.BackInStock() {
+		return nil
+	}
+	userIDs, err := claimSubscribers(ctx, tx, alert.ID, alert.ProductID)
+	if err != nil {
+		return err
+	}
+	if err := tx.GetContext(ctx, &alert, `SELECT sku, product_name FROM inventory WHERE product_id = $1`, productID); err != nil {
+		return fmt.Errorf("failed to load product for back-in-stock event: %w", err)
+	}
+	return enqueueEvent(ctx, tx, events.TypeBackInStock, events.BackInStock{
+		ProductID:   alert.ProductID,
+		SKU:         alert.SKU,
+		ProductName: alert.ProductName,
+		Stock:       alert.Stock,
+		UserIDs:     userIDs,
+		OccurredAt:  alert.CreatedAt,
+	})
+}
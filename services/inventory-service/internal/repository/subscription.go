@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	SubscriptionStatusActive    = "active"
+	SubscriptionStatusNotified  = "notified"
+	SubscriptionStatusCancelled = "cancelled"
+)
+
+var ErrSubscriptionNotFound = errors.New("no active subscription for user and product")
+
+type SubscriptionRepository interface {
+	Subscribe(ctx context.Context, productID, userID int64, ttl time.Duration) (*Subscription, error)
+	Unsubscribe(ctx context.Context, productID, userID int64) error
+	ListByProduct(ctx context.Context, productID int64) ([]Subscription, error)
+}
+
+// Subscription asks for a single back-in-stock notification. It is used up
+// when the product comes back and ignored once expires_at has passed.
+type Subscription struct {
+	ID         int64      `db:"id" json:"id"`
+	ProductID  int64      `db:"product_id" json:"product_id"`
+	UserID     int64      `db:"user_id" json:"user_id"`
+	Status     string     `db:"status" json:"status"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	NotifiedAt *time.Time `db:"notified_at" json:"notified_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+const subscriptionColumns = `id, product_id, user_id, status, expires_at, notified_at, created_at, updated_at`
+
+type PostgresSubscriptionRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresSubscriptionRepository(db *sqlx.DB) *PostgresSubscriptionRepository {
+	return &PostgresSubscriptionRepository{db: db}
+}
+
+// Subscribe registers userID for the product's next return to stock. A user
+// with an open subscription gets its expiry renewed instead of a second one.
+func (r *PostgresSubscriptionRepository) Subscribe(ctx context.Context, productID, userID int64, ttl time.Duration) (*Subscription, error) {
+	query := `
+		INSERT INTO stock_subscriptions (product_id, user_id, expires_at)
+		SELECT product_id, $2, NOW() + make_interval(secs => $3)
+		FROM inventory
+		WHERE product_id = $1 AND deleted_at IS NULL
+		ON CONFLICT (product_id, user_id) WHERE status = 'active'
+		DO UPDATE SET expires_at = EXCLUDED.expires_at, updated_at = NOW()
+		RETURNING ` + subscriptionColumns
+
+	var sub Subscription
+	err := r.db.GetContext(ctx, &sub, query, productID, userID, ttl.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	return &sub, nil
+}
+
+func (r *PostgresSubscriptionRepository) Unsubscribe(ctx context.Context, productID, userID int64) error {
+	query := `
+		UPDATE stock_subscriptions
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE product_id = $1 AND user_id = $2 AND status = 'active'
+	`
+	res, err := r.db.ExecContext(ctx, query, productID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// ListByProduct returns the subscriptions that would be notified if the
+// product came back now.
+func (r *PostgresSubscriptionRepository) ListByProduct(ctx context.Context, productID int64) ([]Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM stock_subscriptions
+		WHERE product_id = $1 AND status = 'active' AND expires_at > NOW()
+		ORDER BY id ASC
+	`
+	list := []Subscription{}
+	if err := r.db.SelectContext(ctx, &list, query, productID); err != nil {
+		return nil, fmt.Errorf("list subscriptions failed: %w", err)
+	}
+	return list, nil
+}
+
+// claimSubscribers uses up the product's live subscriptions for a
+// back-in-stock alert, so each subscriber is notified once, and returns the
+// subscribed users.
+func claimSubscribers(ctx context.Context, tx *sqlx.Tx, alertID, productID int64) ([]int64, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE stock_subscriptions
+			SET status = 'notified', alert_id = $1, notified_at = NOW(), updated_at = NOW()
+			WHERE product_id = $2 AND status = 'active' AND expires_at > NOW()
+			RETURNING user_id
+		)
+		SELECT user_id FROM claimed ORDER BY user_id
+	`
+	userIDs := []int64{}
+	if err := tx.SelectContext(ctx, &userIDs, query, alertID, productID); err != nil {
+		return nil, fmt.Errorf("failed to claim stock subscriptions: %w", err)
+	}
+	return userIDs, nil
+}
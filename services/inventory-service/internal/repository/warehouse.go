@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrLocationNotFound   = errors.New("location not found")
+	ErrWarehouseNotFound  = errors.New("warehouse not found")
+	ErrDuplicateWarehouse = errors.New("warehouse code already exists")
+	ErrDuplicateLocation  = errors.New("location code already exists in this warehouse")
+)
+
+// Postgres error codes the warehouse inserts translate.
+const (
+	pqForeignKeyViolation = "23503"
+	pqUniqueViolation     = "23505"
+)
+
+func pqErrorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+type WarehouseRepository interface {
+	CreateWarehouse(ctx context.Context, code, name string, priority int) (*Warehouse, error)
+	ListWarehouses(ctx context.Context) ([]Warehouse, error)
+	CreateLocation(ctx context.Context, warehouseID int64, code, name string) (*Location, error)
+	ListLocations(ctx context.Context, warehouseID int64) ([]Location, error)
+	GetStockByLocation(ctx context.Context, productID int64) (*ProductLocationStock, error)
+	Transfer(ctx context.Context, productID int64, fromLocationID, toLocationID *int64, quantity int) (*StockTransfer, error)
+}
+
+type Warehouse struct {
+	ID        int64     `db:"id" json:"id"`
+	Code      string    `db:"code" json:"code"`
+	Name      string    `db:"name" json:"name"`
+	Priority  int       `db:"priority" json:"priority"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type Location struct {
+	ID          int64     `db:"id" json:"id"`
+	WarehouseID int64     `db:"warehouse_id" json:"warehouse_id"`
+	Code        string    `db:"code" json:"code"`
+	Name        string    `db:"name" json:"name"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+type LocationStock struct {
+	LocationID    int64  `db:"location_id" json:"location_id"`
+	LocationCode  string `db:"location_code" json:"location_code"`
+	WarehouseID   int64  `db:"warehouse_id" json:"warehouse_id"`
+	WarehouseCode string `db:"warehouse_code" json:"warehouse_code"`
+	Quantity      int    `db:"quantity" json:"quantity"`
+}
+
+// ProductLocationStock breaks a product's on-hand stock down by location.
+// Unassigned is stock that has not been put away to any location yet.
+type ProductLocationStock struct {
+	ProductID  int64           `json:"product_id"`
+	OnHand     int             `json:"on_hand"`
+	Unassigned int             `json:"unassigned"`
+	Locations  []LocationStock `json:"locations"`
+}
+
+type StockTransfer struct {
+	ID             int64     `db:"id" json:"id"`
+	ProductID      int64     `db:"product_id" json:"product_id"`
+	FromLocationID *int64    `db:"from_location_id" json:"from_location_id"`
+	ToLocationID   *int64    `db:"to_location_id" json:"to_location_id"`
+	Quantity       int       `db:"quantity" json:"quantity"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+type Allocation struct {
+	OrderID     int64 `db:"order_id" json:"order_id"`
+	ProductID   int64 `db:"product_id" json:"product_id"`
+	LocationID  int64 `db:"location_id" json:"location_id"`
+	WarehouseID int64 `db:"warehouse_id" json:"warehouse_id"`
+	Quantity    int   `db:"quantity" json:"quantity"`
+}
+
+type PostgresWarehouseRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresWarehouseRepository(db *sqlx.DB) *PostgresWarehouseRepository {
+	return &PostgresWarehouseRepository{db: db}
+}
+
+func (r *PostgresWarehouseRepository) CreateWarehouse(ctx context.Context, code, name string, priority int) (*Warehouse, error) {
+	query := `
+		INSERT INTO warehouses (code, name, priority)
+		VALUES ($1, $2, $3)
+		RETURNING id, code, name, priority, created_at
+	`
+	var w Warehouse
+	err := r.db.GetContext(ctx, &w, query, code, name, priority)
+	if pqErrorCode(err) == pqUniqueViolation {
+		return nil, ErrDuplicateWarehouse
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert warehouse: %w", err)
+	}
+	return &w, nil
+}
+
+func (r *PostgresWarehouseRepository) ListWarehouses(ctx context.Context) ([]Warehouse, error) {
+	query := `SELECT id, code, name, priority, created_at FROM warehouses ORDER BY priority ASC, id ASC`
+
+	var list []Warehouse
+	if err := r.db.SelectContext(ctx, &list, query); err != nil {
+		return nil, fmt.Errorf("list warehouses failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresWarehouseRepository) CreateLocation(ctx context.Context, warehouseID int64, code, name string) (*Location, error) {
+	query := `
+		INSERT INTO locations (warehouse_id, code, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, warehouse_id, code, name, created_at
+	`
+	var l Location
+	err := r.db.GetContext(ctx, &l, query, warehouseID, code, name)
+	switch pqErrorCode(err) {
+	case pqForeignKeyViolation:
+		return nil, ErrWarehouseNotFound
+	case pqUniqueViolation:
+		return nil, ErrDuplicateLocation
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert location: %w", err)
+	}
+	return &l, nil
+}
+
+func (r *PostgresWarehouseRepository) ListLocations(ctx context.Context, warehouseID int64) ([]Location, error) {
+	query := `SELECT id, warehouse_id, code, name, created_at FROM locations WHERE warehouse_id = $1 ORDER BY id ASC`
+
+	var list []Location
+	if err := r.db.SelectContext(ctx, &list, query, warehouseID); err != nil {
+		return nil, fmt.Errorf("list locations failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresWarehouseRepository) GetStockByLocation(ctx context.Context, productID int64) (*ProductLocationStock, error) {
+	result := &ProductLocationStock{ProductID: productID, Locations: []LocationStock{}}
+
+	err := r.db.QueryRowContext(ctx, `SELECT stock FROM inventory WHERE product_id = $1`, productID).Scan(&result.OnHand)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get product stock failed: %w", err)
+	}
+
+	query := `
+		SELECT ls.location_id, l.code AS location_code, w.id AS warehouse_id, w.code AS warehouse_code, ls.quantity
+		FROM location_stock ls
+		JOIN locations l ON l.id = ls.location_id
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE ls.product_id = $1 AND ls.quantity > 0
+		ORDER BY w.priority ASC, l.id ASC
+	`
+	if err := r.db.SelectContext(ctx, &result.Locations, query, productID); err != nil {
+		return nil, fmt.Errorf("get stock by location failed: %w", err)
+	}
+
+	result.Unassigned = result.OnHand
+	for _, ls := range result.Locations {
+		result.Unassigned -= ls.Quantity
+	}
+	return result, nil
+}
+
+// Transfer moves stock between two locations. A nil from or to location stands
+// for the product's unassigned pool, so putaway and un-slotting use the same
+// path. On-hand stock is unchanged; the move is recorded as a transfer.out and
+// transfer.in pair in stock_logs, and inventory.transferred is queued in the outbox.
+func (r *PostgresWarehouseRepository) Transfer(ctx context.Context, productID int64, fromLocationID, toLocationID *int64, quantity int) (*StockTransfer, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transfer transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var onHand int
+	err = tx.QueryRowContext(ctx, `SELECT stock FROM inventory WHERE product_id = $1 FOR UPDATE`, productID).Scan(&onHand)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	if fromLocationID != nil {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE location_stock
+			SET quantity = quantity - $1, updated_at = NOW()
+			WHERE location_id = $2 AND product_id = $3 AND quantity >= $1
+		`, quantity, *fromLocationID, productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take stock from location: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, shortLocation(ctx, tx, *fromLocationID)
+		}
+	} else {
+		var located int
+		if err := tx.GetContext(ctx, &located,
+			`SELECT COALESCE(SUM(quantity), 0) FROM location_stock WHERE product_id = $1`, productID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to sum located stock: %w", err)
+		}
+		if onHand-located < quantity {
+			return nil, ErrInsufficientStock
+		}
+	}
+
+	if toLocationID != nil {
+		if err := addLocationStock(ctx, tx, *toLocationID, productID, quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	var transfer StockTransfer
+	if err := tx.GetContext(ctx, &transfer, `
+		INSERT INTO stock_transfers (product_id, from_location_id, to_location_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, product_id, from_location_id, to_location_id, quantity, created_at
+	`, productID, fromLocationID, toLocationID, quantity); err != nil {
+		return nil, fmt.Errorf("failed to insert stock transfer: %w", err)
+	}
+
+	if _, err := insertStockLog(ctx, tx, StockChange{
+		ProductID: productID, Change: -quantity, Reason: "transfer.out", LocationID: fromLocationID,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := insertStockLog(ctx, tx, StockChange{
+		ProductID: productID, Change: quantity, Reason: "transfer.in", LocationID: toLocationID,
+	}); err != nil {
+		return nil, err
+	}
+	if err := enqueueEvent(ctx, tx, events.TypeStockTransferred, events.StockTransferred(transfer)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock transfer: %w", err)
+	}
+	return &transfer, nil
+}
+
+func addLocationStock(ctx context.Context, tx *sqlx.Tx, locationID, productID int64, quantity int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO location_stock (location_id, product_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (location_id, product_id)
+		DO UPDATE SET quantity = location_stock.quantity + EXCLUDED.quantity, updated_at = NOW()
+	`, locationID, productID, quantity)
+	if pqErrorCode(err) == pqForeignKeyViolation {
+		return ErrLocationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add stock to location: %w", err)
+	}
+	return nil
+}
+
+// shortLocation explains why taking stock from a location matched no row:
+// the location does not exist, or it holds too little of the product.
+func shortLocation(ctx context.Context, tx *sqlx.Tx, locationID int64) error {
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM locations WHERE id = $1)`, locationID); err != nil {
+		return fmt.Errorf("failed to check location: %w", err)
+	}
+	if !exists {
+		return ErrLocationNotFound
+	}
+	return ErrInsufficientStock
+}
+
+// allocateLocations picks where an order ships from. The highest-priority
+// warehouse that can cover the whole quantity wins; otherwise the order is
+// split across locations in priority order. Whatever the located stock cannot
+// cover comes out of the unassigned pool and is not recorded as an allocation.
+func allocateLocations(ctx context.Context, tx *sqlx.Tx, orderID, productID int64, quantity int) ([]Allocation, error) {
+	query := `
+		SELECT ls.location_id, l.warehouse_id, ls.quantity
+		FROM location_stock ls
+		JOIN locations l ON l.id = ls.location_id
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE ls.product_id = $1 AND ls.quantity > 0
+		ORDER BY w.priority ASC, w.id ASC, ls.quantity DESC
+		FOR UPDATE OF ls
+	`
+	var candidates []Allocation
+	if err := tx.SelectContext(ctx, &candidates, query, productID); err != nil {
+		return nil, fmt.Errorf("failed to load location stock: %w", err)
+	}
+
+	allocations := pickAllocations(candidates, quantity)
+	for i := range allocations {
+		a := &allocations[i]
+		a.OrderID = orderID
+		a.ProductID = productID
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE location_stock
+			SET quantity = quantity - $1, updated_at = NOW()
+			WHERE location_id = $2 AND product_id = $3
+		`, a.Quantity, a.LocationID, productID); err != nil {
+			return nil, fmt.Errorf("failed to allocate location stock: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO order_allocations (order_id, product_id, location_id, quantity)
+			VALUES ($1, $2, $3, $4)
+		`, orderID, productID, a.LocationID, a.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to record order allocation: %w", err)
+		}
+	}
+	return allocations, nil
+}
+
+func pickAllocations(candidates []Allocation, quantity int) []Allocation {
+	totals := make(map[int64]int)
+	var order []int64
+	for _, c := range candidates {
+		if _, seen := totals[c.WarehouseID]; !seen {
+			order = append(order, c.WarehouseID)
+		}
+		totals[c.WarehouseID] += c.Quantity
+	}
+
+	pool := candidates
+	for _, warehouseID := range order {
+		if totals[warehouseID] >= quantity {
+			pool = nil
+			for _, c := range candidates {
+				if c.WarehouseID == warehouseID {
+					pool = append(pool, c)
+				}
+			}
+			break
+		}
+	}
+
+	var picked []Allocation
+	remaining := quantity
+	for _, c := range pool {
+		if remaining == 0 {
+			break
+		}
+		take := min(c.Quantity, remaining)
+		picked = append(picked, Allocation{LocationID: c.LocationID, WarehouseID: c.WarehouseID, Quantity: take})
+		remaining -= take
+	}
+	return picked
+}
+
+func restoreAllocations(ctx context.Context, tx *sqlx.Tx, orderID, productID int64) error {
+	var allocations []Allocation
+	if err := tx.SelectContext(ctx, &allocations, `
+		SELECT order_id, product_id, location_id, quantity
+		FROM order_allocations
+		WHERE order_id = $1 AND product_id = $2
+	`, orderID, productID); err != nil {
+		return fmt.Errorf("failed to load order allocations: %w", err)
+	}
+
+	for _, a := range allocations {
+		if err := addLocationStock(ctx, tx, a.LocationID, productID, a.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
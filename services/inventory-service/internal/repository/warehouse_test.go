@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestPQErrorCode(t *testing.T) {
+	wrapped := fmt.Errorf("insert: %w", &pq.Error{Code: pqUniqueViolation})
+	if got := pqErrorCode(wrapped); got != pqUniqueViolation {
+		t.Fatalf("got %q, want %q", got, pqUniqueViolation)
+	}
+	if got := pqErrorCode(fmt.Errorf("connection refused")); got != "" {
+		t.Fatalf("got %q for a non-Postgres error, want none", got)
+	}
+	if got := pqErrorCode(nil); got != "" {
+		t.Fatalf("got %q for nil, want none", got)
+	}
+}
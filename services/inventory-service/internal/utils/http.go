@@ -17,3 +17,16 @@ func WriteError(w http.ResponseWriter, statusCode int, message string) {
 	w.WriteHeader(statusCode)
 	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: message})
 }
+
+type ValidationErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+// WriteValidationError reports per-field validation failures with a 400.
+func WriteValidationError(w http.ResponseWriter, fields map[string]string) {
+	log.Warn().Interface("fields", fields).Msg("Validation failed")
+
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Validation failed", Fields: fields})
+}
@@ -2,9 +2,22 @@ package main
 
 import (
 	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // quiet hours need time zones even in images without tzdata
 
 	"github.com/cemrezr/ecommerce-system/notification-service/internal/config"
 	"github.com/cemrezr/ecommerce-system/notification-service/internal/event"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/handler"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/preference"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/templates"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/webhook"
+	"github.com/cemrezr/ecommerce-system/pkg/database"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
 	"github.com/cemrezr/ecommerce-system/pkg/logger"
 	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
 )
@@ -15,6 +28,22 @@ func main() {
 
 	log.Info().Msg("Starting notification-service")
 
+	if cfg.UnsubscribeSecret == "" {
+		log.Fatal().Msg("UNSUBSCRIBE_SECRET must be set")
+	}
+
+	ctx := context.Background()
+
+	db := database.Connect(cfg.DBDSN, log)
+	defer db.Close()
+	notificationRepo := repository.NewPostgresNotificationRepository(db)
+	preferenceRepo := repository.NewPostgresPreferenceRepository(db)
+	heldRepo := repository.NewPostgresHeldRepository(db)
+	dedupRepo := repository.NewPostgresDedupRepository(db, cfg.DedupTTL)
+	webhookRepo := repository.NewPostgresWebhookRepository(db)
+	digestRepo := repository.NewPostgresDigestRepository(db)
+	backInStockRepo := repository.NewPostgresBackInStockRepository(db)
+
 	conn, ch, err := rabbitmq.Connect(cfg.RabbitMQURL, log)
 	if err != nil {
 		log.Fatal().Err(err).Msg("RabbitMQ connection failed")
@@ -23,16 +52,138 @@ func main() {
 	defer ch.Close()
 
 	if err := rabbitmq.SetupBasicQueue(ch, cfg.RabbitMQExchange, cfg.RabbitMQQueue, []string{
-		"order.created",
-		"order.cancelled",
+		events.TypeOrderCreated,
+		events.TypeOrderCancelled,
+		events.TypeLowStock,
+		events.TypeOutOfStock,
+		events.TypeRestocked,
+		events.TypeBackInStock,
 	}, log); err != nil {
 		log.Fatal().Err(err).Msg("Failed to declare queue and bindings")
 	}
+	// Partner webhooks get their own queue, so that they see every event
+	// they may subscribe to whatever the notification routing is.
+	if err := rabbitmq.SetupBasicQueue(ch, cfg.RabbitMQExchange, cfg.WebhookQueue, strings.Split(cfg.WebhookRoutingKeys, ","), log); err != nil {
+		log.Fatal().Err(err).Msg("Failed to declare webhook queue and bindings")
+	}
+	if err := rabbitmq.SetupQuarantineQueue(ch, log); err != nil {
+		log.Fatal().Err(err).Msg("Failed to declare quarantine queue")
+	}
+	quarantine := rabbitmq.NewQuarantine(ch, "notification-service", log)
+
+	// Notification channels; NOTIFY_ROUTES decides which events use which
+	channels := map[string]notifier.Notifier{
+		"log": notifier.NewLogNotifier(log),
+		"smtp": notifier.NewSMTPNotifier(notifier.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			Timeout:  cfg.SMTPTimeout,
+		}),
+	}
+	if cfg.NotifyFilePath != "" {
+		fileNotifier, f, err := notifier.OpenFileNotifier(cfg.NotifyFilePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open notification file sink")
+		}
+		defer f.Close()
+		channels["file"] = fileNotifier
+	}
+	if cfg.WebhookURL != "" {
+		channels["webhook"] = notifier.NewWebhookNotifier(cfg.WebhookURL, &http.Client{Timeout: cfg.WebhookTimeout})
+	}
+
+	store, err := templates.NewStore(cfg.TemplateDir, cfg.DefaultLocale, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load notification templates")
+	}
+	go store.Watch(ctx, cfg.TemplateReloadInterval)
+
+	routes, err := notifier.ParseRoutes(cfg.NotifyRoutes)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid NOTIFY_ROUTES")
+	}
+	router, err := notifier.NewRouter(routes, channels, store, notificationRepo, dedupRepo, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid notification routing")
+	}
+	log.Info().Str("routes", cfg.NotifyRoutes).Msg("Notification routing configured")
+
+	channelNames := make([]string, 0, len(channels))
+	for name := range channels {
+		channelNames = append(channelNames, name)
+	}
+	sort.Strings(channelNames)
+
+	signer := preference.NewSigner(cfg.UnsubscribeSecret, cfg.PublicURL)
+	notificationHandler := handler.NewNotificationHandler(
+		log, router, preferenceRepo, heldRepo, digestRepo, backInStockRepo, signer, cfg.OpsAlertEmail, cfg.UserEmailDomain, cfg.DigestMaxItems,
+	)
+
+	templateHandler := handler.NewTemplateHandler(store, log)
+	historyHandler := handler.NewHistoryHandler(notificationRepo, router, log)
+	preferenceHandler := handler.NewPreferenceHandler(preferenceRepo, signer, channelNames, log)
+
+	deliverer := webhook.NewDeliverer(webhookRepo, &http.Client{Timeout: cfg.WebhookDeliveryTimeout}, webhook.RetryPolicy{
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		BaseDelay:    cfg.WebhookBackoffBase,
+		MaxDelay:     cfg.WebhookBackoffMax,
+		DisableAfter: cfg.WebhookDisableAfter,
+	}, cfg.WebhookConcurrency, log)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo, deliverer, log)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /templates/preview", templateHandler.Preview)
+	mux.HandleFunc("POST /templates/preview", templateHandler.Preview)
+	mux.HandleFunc("GET /notifications", historyHandler.ListNotifications)
+	mux.HandleFunc("GET /notifications/{id}", historyHandler.GetNotification)
+	mux.HandleFunc("POST /notifications/{id}/resend", historyHandler.Resend)
+	mux.HandleFunc("GET /users/{user_id}/preferences", preferenceHandler.GetPreferences)
+	mux.HandleFunc("PUT /users/{user_id}/preferences", preferenceHandler.PutPreferences)
+	mux.HandleFunc("GET /unsubscribe", preferenceHandler.Unsubscribe)
+	mux.HandleFunc("POST /unsubscribe", preferenceHandler.Unsubscribe)
+	mux.HandleFunc("POST /webhooks", webhookHandler.CreateWebhook)
+	mux.HandleFunc("GET /webhooks", webhookHandler.ListWebhooks)
+	mux.HandleFunc("GET /webhooks/{id}", webhookHandler.GetWebhook)
+	mux.HandleFunc("PUT /webhooks/{id}", webhookHandler.UpdateWebhook)
+	mux.HandleFunc("DELETE /webhooks/{id}", webhookHandler.DeleteWebhook)
+	mux.HandleFunc("POST /webhooks/{id}/enable", webhookHandler.EnableWebhook)
+	mux.HandleFunc("POST /webhooks/{id}/disable", webhookHandler.DisableWebhook)
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", webhookHandler.ListDeliveries)
+	mux.HandleFunc("GET /webhook-deliveries/{id}", webhookHandler.GetDelivery)
+	mux.HandleFunc("POST /webhook-deliveries/{id}/redeliver", webhookHandler.Redeliver)
+
+	go func() {
+		log.Info().Str("addr", ":"+cfg.AppPort).Msg("Starting HTTP server")
+		if err := http.ListenAndServe(":"+cfg.AppPort, mux); err != nil {
+			log.Fatal().Err(err).Msg("Server failed")
+		}
+	}()
+
+	releaser := event.NewHeldReleaser(heldRepo, notificationHandler, cfg.HeldReleaseInterval, log)
+	go releaser.Run(ctx)
+	digestFlusher := event.NewDigestFlusher(digestRepo, notificationHandler, cfg.DigestFlushInterval, cfg.DedupTTL, log)
+	go digestFlusher.Run(ctx)
+	purger := event.NewDedupPurger(dedupRepo, time.Hour, log)
+	go purger.Run(ctx)
+	backInStockPurger := event.NewDedupPurger(backInStockRepo, time.Hour, log)
+	go backInStockPurger.Run(ctx)
+	webhookWorker := event.NewWebhookWorker(deliverer, cfg.WebhookInterval, log)
+	go webhookWorker.Run(ctx)
+
+	webhookConsumer := event.NewConsumer(ch, cfg.WebhookQueue, log, quarantine, event.NewWebhookDispatcher(webhookRepo, log))
+	go func() {
+		if err := webhookConsumer.StartConsuming(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Webhook consumer startup failed")
+		}
+	}()
 
-	dispatcher := event.NewDispatcher(log)
-	consumer := event.NewConsumer(ch, cfg.RabbitMQQueue, log, dispatcher)
+	dispatcher := event.NewDispatcher(log, notificationHandler)
+	consumer := event.NewConsumer(ch, cfg.RabbitMQQueue, log, quarantine, dispatcher)
 
-	if err := consumer.StartConsuming(context.Background()); err != nil {
+	if err := consumer.StartConsuming(ctx); err != nil {
 		log.Fatal().Err(err).Msg("Consumer startup failed")
 	}
 }
@@ -4,6 +4,8 @@ go 1.23.7
 
 require (
 	github.com/cemrezr/ecommerce-system v0.0.0-20250802002814-49458cdb6cd1
+	github.com/jmoiron/sqlx v1.4.0
+	github.com/lib/pq v1.10.9
 	github.com/rs/zerolog v1.34.0
 	github.com/streadway/amqp v1.1.0
 )
@@ -13,3 +15,6 @@ require (
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	golang.org/x/sys v0.12.0 // indirect
 )
+
+// The shared packages are used from this checkout.
+replace github.com/cemrezr/ecommerce-system => ../..
@@ -2,20 +2,32 @@ package event
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
 	"github.com/rs/zerolog"
 	"github.com/streadway/amqp"
 )
 
+// EventDispatcher handles one event from a queue; an error NACKs the message
+// so that it is redelivered. version is the version the event was published
+// as, see events.VersionHeader.
+type EventDispatcher interface {
+	Dispatch(ctx context.Context, eventType, version, eventID string, body []byte) error
+}
+
 type Consumer struct {
 	ch         *amqp.Channel
 	queue      string
 	log        zerolog.Logger
-	dispatcher *Dispatcher
+	quarantine *rabbitmq.Quarantine
+	dispatcher EventDispatcher
 }
 
-func NewConsumer(ch *amqp.Channel, queue string, log zerolog.Logger, dispatcher *Dispatcher) *Consumer {
-	return &Consumer{ch: ch, queue: queue, log: log, dispatcher: dispatcher}
+func NewConsumer(ch *amqp.Channel, queue string, log zerolog.Logger, quarantine *rabbitmq.Quarantine, dispatcher EventDispatcher) *Consumer {
+	return &Consumer{ch: ch, queue: queue, log: log, quarantine: quarantine, dispatcher: dispatcher}
 }
 
 func (c *Consumer) StartConsuming(ctx context.Context) error {
@@ -37,11 +49,26 @@ func (c *Consumer) StartConsuming(ctx context.Context) error {
 
 	go func() {
 		for msg := range msgs {
+			id := eventID(msg)
 			c.log.Debug().
 				Str("type", msg.Type).
+				Str("event_id", id).
 				Msg("Received message")
 
-			err := c.dispatcher.Dispatch(msg.Type, msg.Body)
+			// Invalid events are quarantined with their validation report
+			// instead of being requeued forever.
+			valid, err := c.quarantine.Check(c.queue, msg)
+			if err != nil {
+				c.log.Error().Err(err).Str("type", msg.Type).Msg("Failed to quarantine invalid event — NACKing")
+				_ = msg.Nack(false, true)
+				continue
+			}
+			if !valid {
+				_ = msg.Ack(false)
+				continue
+			}
+
+			err = c.dispatcher.Dispatch(ctx, msg.Type, eventVersion(msg), id, msg.Body)
 			if err != nil {
 				c.log.Error().Err(err).Str("type", msg.Type).Msg("Failed to process event — NACKing")
 				_ = msg.Nack(false, true)
@@ -57,3 +84,21 @@ func (c *Consumer) StartConsuming(ctx context.Context) error {
 	c.log.Info().Msg("Consumer shutting down")
 	return nil
 }
+
+// eventID is the publisher's message ID. Messages published without one are
+// identified by a hash of their type and body, which is the same for every
+// redelivery of the message.
+func eventID(msg amqp.Delivery) string {
+	if msg.MessageId != "" {
+		return msg.MessageId
+	}
+	sum := sha256.Sum256(append([]byte(msg.Type+"\n"), msg.Body...))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// eventVersion is the version the event was published as; messages from
+// before versioning have none.
+func eventVersion(msg amqp.Delivery) string {
+	version, _ := msg.Headers[events.VersionHeader].(string)
+	return version
+}
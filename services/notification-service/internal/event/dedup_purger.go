@@ -0,0 +1,42 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// DedupPurger deletes expired dedup entries: delivered events, or
+// back-in-stock notifications sent.
+type DedupPurger struct {
+	repo     repository.Expiring
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func NewDedupPurger(repo repository.Expiring, interval time.Duration, log zerolog.Logger) *DedupPurger {
+	return &DedupPurger{repo: repo, interval: interval, log: log}
+}
+
+func (p *DedupPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := p.repo.PurgeExpired(ctx)
+			if err != nil {
+				p.log.Error().Err(err).Msg("Failed to purge dedup entries")
+				continue
+			}
+			if purged > 0 {
+				p.log.Info().Int64("purged", purged).Msg("Expired dedup entries purged")
+			}
+		}
+	}
+}
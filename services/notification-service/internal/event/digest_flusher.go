@@ -0,0 +1,81 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/handler"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	digestBatchSize     = 100
+	digestPurgeInterval = time.Hour
+)
+
+// DigestFlusher sends users' digests once their window has ended, and
+// retries digests whose flush failed. Sent digests are kept for retention
+// so that a redelivered event is not collected twice.
+type DigestFlusher struct {
+	repo      repository.DigestRepository
+	handler   *handler.NotificationHandler
+	interval  time.Duration
+	retention time.Duration
+	log       zerolog.Logger
+}
+
+func NewDigestFlusher(repo repository.DigestRepository, h *handler.NotificationHandler, interval, retention time.Duration, log zerolog.Logger) *DigestFlusher {
+	return &DigestFlusher{repo: repo, handler: h, interval: interval, retention: retention, log: log}
+}
+
+func (f *DigestFlusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	f.log.Info().Dur("interval", f.interval).Msg("Digest flusher started")
+
+	var lastPurge time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			f.log.Info().Msg("Digest flusher stopped")
+			return
+		case <-ticker.C:
+			f.flush(ctx)
+			if time.Since(lastPurge) >= digestPurgeInterval {
+				f.purge(ctx)
+				lastPurge = time.Now()
+			}
+		}
+	}
+}
+
+func (f *DigestFlusher) flush(ctx context.Context) {
+	due, err := f.repo.DueDigests(ctx, digestBatchSize, handler.DigestLease)
+	if err != nil {
+		f.log.Error().Err(err).Msg("Failed to load due digests")
+		return
+	}
+
+	for _, digest := range due {
+		if err := f.handler.FlushDigest(ctx, digest); err != nil {
+			f.log.Error().Err(err).
+				Int64("digest_id", digest.ID).
+				Int("user_id", digest.UserID).
+				Int("items", digest.ItemCount).
+				Msg("Failed to flush digest — retrying later")
+		}
+	}
+}
+
+func (f *DigestFlusher) purge(ctx context.Context) {
+	purged, err := f.repo.PurgeSentDigests(ctx, f.retention)
+	if err != nil {
+		f.log.Error().Err(err).Msg("Failed to purge sent digests")
+		return
+	}
+	if purged > 0 {
+		f.log.Info().Int64("purged", purged).Msg("Sent digests purged")
+	}
+}
@@ -1,49 +1,57 @@
 package event
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 
 	"github.com/cemrezr/ecommerce-system/notification-service/internal/handler"
-	"github.com/cemrezr/ecommerce-system/notification-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
 	"github.com/rs/zerolog"
 )
 
+// Dispatcher decodes events and hands them to the NotificationHandler. Every
+// message to a user goes through NotificationHandler.Deliver, so the user's
+// preferences are consulted before anything is sent.
 type Dispatcher struct {
 	log     zerolog.Logger
 	handler *handler.NotificationHandler
 }
 
-func NewDispatcher(log zerolog.Logger) *Dispatcher {
-	return &Dispatcher{
-		log:     log,
-		handler: handler.NewNotificationHandler(log),
-	}
+func NewDispatcher(log zerolog.Logger, h *handler.NotificationHandler) *Dispatcher {
+	return &Dispatcher{log: log, handler: h}
 }
 
-func (d *Dispatcher) Dispatch(eventType string, body []byte) error {
+// Dispatch handles one event. eventID identifies the event across broker
+// redeliveries; deliveries already made for it are not repeated.
+func (d *Dispatcher) Dispatch(ctx context.Context, eventType, version, eventID string, body []byte) error {
 	switch eventType {
-	case "order.created":
-		var event model.OrderCreatedEvent
-		if err := json.Unmarshal(body, &event); err != nil {
-			d.log.Error().
-				Err(err).
-				Str("event_type", eventType).
-				Msg("Failed to unmarshal order.created event")
-			return fmt.Errorf("failed to unmarshal order.created: %w", err)
+	case events.TypeOrderCreated:
+		event, err := events.DecodeAs[events.OrderCreated](eventType, version, body)
+		if err != nil {
+			return d.decodeFailed(eventType, err)
+		}
+		return d.handler.SendOrderCreatedEmail(ctx, eventID, event)
+
+	case events.TypeOrderCancelled:
+		event, err := events.DecodeAs[events.OrderCancelled](eventType, version, body)
+		if err != nil {
+			return d.decodeFailed(eventType, err)
+		}
+		return d.handler.SendOrderCancelledEmail(ctx, eventID, event)
+
+	case events.TypeLowStock, events.TypeOutOfStock, events.TypeRestocked:
+		event, err := events.DecodeAs[events.StockAlert](eventType, version, body)
+		if err != nil {
+			return d.decodeFailed(eventType, err)
 		}
-		return d.handler.SendOrderCreatedEmail(event)
-
-	case "order.cancelled":
-		var event model.OrderCancelledEvent
-		if err := json.Unmarshal(body, &event); err != nil {
-			d.log.Error().
-				Err(err).
-				Str("event_type", eventType).
-				Msg("Failed to unmarshal order.cancelled event")
-			return fmt.Errorf("failed to unmarshal order.cancelled: %w", err)
+		return d.handler.SendStockAlert(ctx, eventID, eventType, event)
+
+	case events.TypeBackInStock:
+		event, err := events.DecodeAs[events.BackInStock](eventType, version, body)
+		if err != nil {
+			return d.decodeFailed(eventType, err)
 		}
-		return d.handler.SendOrderCancelledEmail(event)
+		return d.handler.SendBackInStockEmails(ctx, eventID, event)
 
 	default:
 		d.log.Warn().
@@ -52,3 +60,11 @@ func (d *Dispatcher) Dispatch(eventType string, body []byte) error {
 		return fmt.Errorf("unknown event type: %s", eventType)
 	}
 }
+
+func (d *Dispatcher) decodeFailed(eventType string, err error) error {
+	d.log.Error().
+		Err(err).
+		Str("event_type", eventType).
+		Msg("Failed to decode event")
+	return fmt.Errorf("failed to decode %s: %w", eventType, err)
+}
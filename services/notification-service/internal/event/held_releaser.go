@@ -0,0 +1,98 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/handler"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	releaseBatchSize  = 100
+	releaseRetryDelay = 5 * time.Minute
+)
+
+// HeldReleaser delivers messages held back by quiet hours once they are due.
+// They go through NotificationHandler.Deliver again, so preferences changed
+// in the meantime, including an unsubscribe, still apply.
+type HeldReleaser struct {
+	repo     repository.HeldRepository
+	handler  *handler.NotificationHandler
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func NewHeldReleaser(repo repository.HeldRepository, h *handler.NotificationHandler, interval time.Duration, log zerolog.Logger) *HeldReleaser {
+	return &HeldReleaser{repo: repo, handler: h, interval: interval, log: log}
+}
+
+func (r *HeldReleaser) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.log.Info().Dur("interval", r.interval).Msg("Held notification releaser started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info().Msg("Held notification releaser stopped")
+			return
+		case <-ticker.C:
+			r.release(ctx)
+		}
+	}
+}
+
+func (r *HeldReleaser) release(ctx context.Context) {
+	due, err := r.repo.DueHeld(ctx, releaseBatchSize)
+	if err != nil {
+		r.log.Error().Err(err).Msg("Failed to load held notifications")
+		return
+	}
+
+	for _, held := range due {
+		if err := r.deliver(ctx, held); err != nil {
+			r.log.Error().Err(err).
+				Int64("held_id", held.ID).
+				Int("user_id", held.UserID).
+				Str("event_type", held.EventType).
+				Msg("Failed to deliver held notification — retrying later")
+			if err := r.repo.RetryHeld(ctx, held.ID, time.Now().Add(releaseRetryDelay), err); err != nil {
+				r.log.Error().Err(err).Int64("held_id", held.ID).Msg("Failed to reschedule held notification")
+			}
+			continue
+		}
+		if err := r.repo.DeleteHeld(ctx, held.ID); err != nil {
+			r.log.Error().Err(err).Int64("held_id", held.ID).Msg("Failed to delete released notification")
+		}
+	}
+}
+
+func (r *HeldReleaser) deliver(ctx context.Context, held repository.HeldNotification) error {
+	event, ok := model.NewEvent(held.EventType)
+	if !ok {
+		return fmt.Errorf("unknown event type: %s", held.EventType)
+	}
+	if err := json.Unmarshal(held.Payload, event); err != nil {
+		return fmt.Errorf("failed to decode held %s: %w", held.EventType, err)
+	}
+
+	msg := notifier.Message{
+		EventID:   held.EventID,
+		EventType: held.EventType,
+		To:        held.Recipient,
+		UserID:    held.UserID,
+		Data:      event,
+	}
+	if held.OrderID != nil {
+		msg.OrderID = *held.OrderID
+	}
+	_, err := r.handler.Deliver(ctx, msg)
+	return err
+}
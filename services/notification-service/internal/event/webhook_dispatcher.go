@@ -0,0 +1,84 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/webhook"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/rs/zerolog"
+)
+
+// WebhookDispatcher queues a delivery of each event for every active webhook
+// subscribed to its type. The deliveries themselves are made by the
+// WebhookWorker, so a slow or failing partner never holds up the queue.
+type WebhookDispatcher struct {
+	repo repository.WebhookRepository
+	log  zerolog.Logger
+}
+
+func NewWebhookDispatcher(repo repository.WebhookRepository, log zerolog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{repo: repo, log: log}
+}
+
+// Dispatch is safe to repeat for a redelivered event: each webhook gets at
+// most one delivery per eventID.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, eventType, version, eventID string, body []byte) error {
+	payload, err := partnerPayload(eventType, version, body)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := d.repo.ActiveWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range webhooks {
+		if !webhook.Matches(w.EventTypes, eventType) {
+			continue
+		}
+		queued, err := d.repo.EnqueueWebhookDelivery(ctx, repository.WebhookDelivery{
+			WebhookID: w.ID,
+			EventID:   eventID,
+			EventType: eventType,
+			Payload:   payload,
+		})
+		if err != nil {
+			return err
+		}
+		if queued {
+			d.log.Debug().
+				Int64("webhook_id", w.ID).
+				Str("event_type", eventType).
+				Str("event_id", eventID).
+				Msg("Webhook delivery queued")
+		}
+	}
+	return nil
+}
+
+// partnerPayload is the body partners receive for an event: its latest
+// version, whatever version it was published as, so that a webhook only
+// ever sees one shape of each event type. Event types without a contract
+// are forwarded as published.
+func partnerPayload(eventType, version string, body []byte) ([]byte, error) {
+	event, err := events.Decode(eventType, version, body)
+	if errors.Is(err, events.ErrUnknownEvent) {
+		if !json.Valid(body) {
+			return nil, fmt.Errorf("invalid JSON in %s event", eventType)
+		}
+		return body, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", eventType, err)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", eventType, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,49 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/webhook"
+	"github.com/rs/zerolog"
+)
+
+const webhookBatchSize = 100
+
+// WebhookWorker makes the webhook deliveries that are due: new ones and
+// retries whose backoff has passed.
+type WebhookWorker struct {
+	deliverer *webhook.Deliverer
+	interval  time.Duration
+	log       zerolog.Logger
+}
+
+func NewWebhookWorker(deliverer *webhook.Deliverer, interval time.Duration, log zerolog.Logger) *WebhookWorker {
+	return &WebhookWorker{deliverer: deliverer, interval: interval, log: log}
+}
+
+func (w *WebhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.log.Info().Dur("interval", w.interval).Msg("Webhook worker started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.log.Info().Msg("Webhook worker stopped")
+			return
+		case <-ticker.C:
+			// A full batch means more may be due; keep going until drained.
+			for {
+				n, err := w.deliverer.DeliverDue(ctx, webhookBatchSize)
+				if err != nil {
+					w.log.Error().Err(err).Msg("Failed to deliver due webhooks")
+				}
+				if err != nil || n < webhookBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// ChannelSender delivers an already rendered message on a named channel;
+// notifier.Router implements it.
+type ChannelSender interface {
+	Send(ctx context.Context, channel string, msg notifier.Message) (string, error)
+}
+
+// HistoryHandler serves the notification history and resends stored
+// notifications.
+type HistoryHandler struct {
+	repo   repository.NotificationRepository
+	sender ChannelSender
+	log    zerolog.Logger
+}
+
+func NewHistoryHandler(repo repository.NotificationRepository, sender ChannelSender, log zerolog.Logger) *HistoryHandler {
+	return &HistoryHandler{repo: repo, sender: sender, log: log}
+}
+
+// ListNotifications handles GET /notifications?user_id=&order_id=&status=&limit=.
+func (h *HistoryHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.NotificationFilter{Status: q.Get("status"), Limit: defaultHistoryLimit}
+
+	var ok bool
+	if filter.UserID, ok = queryID(w, q.Get("user_id"), "user_id"); !ok {
+		return
+	}
+	if filter.OrderID, ok = queryID(w, q.Get("order_id"), "order_id"); !ok {
+		return
+	}
+	if filter.Status != "" && filter.Status != repository.StatusSent && filter.Status != repository.StatusFailed {
+		writeError(w, http.StatusBadRequest, "status must be sent or failed")
+		return
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxHistoryLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	list, err := h.repo.ListNotifications(r.Context(), filter)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list notifications")
+		writeError(w, http.StatusInternalServerError, "Failed to list notifications")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *HistoryHandler) GetNotification(w http.ResponseWriter, r *http.Request) {
+	id, ok := notificationID(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := h.repo.GetNotification(r.Context(), id)
+	if errors.Is(err, repository.ErrNotificationNotFound) {
+		writeError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("notification_id", id).Msg("Failed to get notification")
+		writeError(w, http.StatusInternalServerError, "Failed to get notification")
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+// Resend delivers a stored notification again on its original channel with
+// its original content, and records the attempt on the same record. A
+// failed attempt answers 502 with the updated notification.
+func (h *HistoryHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	id, ok := notificationID(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := h.repo.GetNotification(r.Context(), id)
+	if errors.Is(err, repository.ErrNotificationNotFound) {
+		writeError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("notification_id", id).Msg("Failed to get notification")
+		writeError(w, http.StatusInternalServerError, "Failed to get notification")
+		return
+	}
+
+	response, sendErr := h.sender.Send(r.Context(), n.Channel, n.Message())
+	if errors.Is(sendErr, notifier.ErrUnknownChannel) {
+		writeError(w, http.StatusConflict, "Channel "+n.Channel+" is no longer configured")
+		return
+	}
+
+	updated, err := h.repo.RecordAttempt(r.Context(), id, response, sendErr)
+	if err != nil {
+		h.log.Error().Err(err).Int64("notification_id", id).Msg("Failed to record resend")
+		writeError(w, http.StatusInternalServerError, "Failed to record resend")
+		return
+	}
+
+	if sendErr != nil {
+		h.log.Warn().Err(sendErr).Int64("notification_id", id).Str("channel", n.Channel).Msg("Notification resend failed")
+		writeJSON(w, http.StatusBadGateway, updated)
+		return
+	}
+	h.log.Info().Int64("notification_id", id).Str("channel", n.Channel).Msg("Notification resent")
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// queryID parses an optional positive ID from the query string.
+func queryID(w http.ResponseWriter, raw, name string) (*int, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, name+" must be a positive integer")
+		return nil, false
+	}
+	return &id, true
+}
+
+func notificationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid notification ID")
+		return 0, false
+	}
+	return id, true
+}
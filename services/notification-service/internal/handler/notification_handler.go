@@ -1,30 +1,398 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/cemrezr/ecommerce-system/notification-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/preference"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
 	"github.com/rs/zerolog"
 )
 
+// backInStockDedupWindow is how long a back-in-stock notification to a user
+// suppresses another one for the same product, e.g. when an event is
+// redelivered after a failed ack or the product sells out and returns again.
+const backInStockDedupWindow = 24 * time.Hour
+
+const (
+	// DigestLease is how long a digest being flushed stays claimed; if the
+	// flush has not finished by then, it is retried.
+	DigestLease = 10 * time.Minute
+	// digestRetryDelay is how long a digest waits after a failed flush.
+	digestRetryDelay = 5 * time.Minute
+)
+
+// NotificationHandler turns events into messages and hands them to a
+// notifier, normally a notifier.Router that picks the channels per event and
+// renders the subject and body from templates. Messages to users go through
+// Deliver, which applies the user's preferences first.
 type NotificationHandler struct {
-	log zerolog.Logger
+	log             zerolog.Logger
+	notifier        notifier.Notifier
+	prefs           repository.PreferenceRepository
+	held            repository.HeldRepository
+	digests         repository.DigestRepository
+	backInStock     repository.BackInStockRepository
+	signer          *preference.Signer
+	opsEmail        string
+	userEmailDomain string
+	digestMaxItems  int
+}
+
+func NewNotificationHandler(
+	log zerolog.Logger,
+	n notifier.Notifier,
+	prefs repository.PreferenceRepository,
+	held repository.HeldRepository,
+	digests repository.DigestRepository,
+	backInStock repository.BackInStockRepository,
+	signer *preference.Signer,
+	opsEmail, userEmailDomain string,
+	digestMaxItems int,
+) *NotificationHandler {
+	return &NotificationHandler{
+		log:             log,
+		notifier:        n,
+		prefs:           prefs,
+		held:            held,
+		digests:         digests,
+		backInStock:     backInStock,
+		signer:          signer,
+		opsEmail:        opsEmail,
+		userEmailDomain: userEmailDomain,
+		digestMaxItems:  digestMaxItems,
+	}
+}
+
+// userEmail derives a user's address until a user directory exists to look
+// it up from.
+func (h *NotificationHandler) userEmail(userID int) string {
+	return fmt.Sprintf("user-%d@%s", userID, h.userEmailDomain)
 }
 
-func NewNotificationHandler(log zerolog.Logger) *NotificationHandler {
-	return &NotificationHandler{log: log}
+// Deliver sends msg to msg.UserID according to the user's preferences and
+// reports whether it was sent now. Nothing is sent to an unsubscribed user,
+// order events to a user in digest mode are added to their digest, opted-out
+// channels are skipped, and a message arriving in quiet hours is held until
+// they end. msg.Data must be the event payload, since held and digested
+// messages are stored unrendered.
+func (h *NotificationHandler) Deliver(ctx context.Context, msg notifier.Message) (bool, error) {
+	prefs, err := h.prefs.GetPreferences(ctx, msg.UserID)
+	if err != nil {
+		return false, err
+	}
+	decision, err := preference.Decide(prefs, msg.EventType, time.Now())
+	if err != nil {
+		// Broken preferences must not stop the user's notifications.
+		h.log.Warn().Err(err).Int("user_id", msg.UserID).Msg("Invalid notification preferences — using defaults")
+		decision = preference.Decision{}
+	}
+
+	if decision.Unsubscribed {
+		h.log.Info().
+			Int("user_id", msg.UserID).
+			Str("event_type", msg.EventType).
+			Msg("User unsubscribed — notification suppressed")
+		return false, nil
+	}
+
+	if decision.DigestWindow > 0 {
+		return false, h.collect(ctx, msg, decision)
+	}
+
+	if !decision.HoldUntil.IsZero() {
+		payload, err := json.Marshal(msg.Data)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode held notification: %w", err)
+		}
+		held := repository.HeldNotification{
+			EventID:   msg.EventID,
+			EventType: msg.EventType,
+			Recipient: msg.To,
+			UserID:    msg.UserID,
+			Payload:   payload,
+			DeliverAt: decision.HoldUntil,
+		}
+		if msg.OrderID != 0 {
+			held.OrderID = &msg.OrderID
+		}
+		if err := h.held.Hold(ctx, held); err != nil {
+			return false, err
+		}
+		h.log.Info().
+			Int("user_id", msg.UserID).
+			Str("event_type", msg.EventType).
+			Time("deliver_at", decision.HoldUntil).
+			Msg("User in quiet hours — notification held")
+		return false, nil
+	}
+
+	if msg.Locale == "" {
+		msg.Locale = prefs.Locale
+	}
+	msg.UnsubscribeURL = h.signer.UnsubscribeURL(msg.UserID)
+	msg.Channels = decision.Channels
+	if err := h.notifier.Notify(ctx, msg); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (h *NotificationHandler) SendOrderCreatedEmail(event model.OrderCreatedEvent) error {
+func (h *NotificationHandler) SendOrderCreatedEmail(ctx context.Context, eventID string, event events.OrderCreated) error {
+	sent, err := h.Deliver(ctx, notifier.Message{
+		EventID:   eventID,
+		EventType: events.TypeOrderCreated,
+		To:        h.userEmail(int(event.UserID)),
+		UserID:    int(event.UserID),
+		OrderID:   int(event.ID),
+		Data:      event,
+	})
+	if err != nil || !sent {
+		return err
+	}
+
 	h.log.Info().
-		Int("user_id", event.UserID).
-		Int("order_id", event.ID).
+		Int64("user_id", event.UserID).
+		Int64("order_id", event.ID).
 		Msg("Order creation email sent to user")
 	return nil
 }
 
-func (h *NotificationHandler) SendOrderCancelledEmail(event model.OrderCancelledEvent) error {
+// SendOrderCancelledEmail notifies the user who placed a cancelled order.
+// Cancellations published as v1 do not name the user and are skipped.
+func (h *NotificationHandler) SendOrderCancelledEmail(ctx context.Context, eventID string, event events.OrderCancelled) error {
+	if event.UserID == 0 {
+		h.log.Warn().
+			Int64("order_id", event.ID).
+			Msg("Order cancellation without a user — skipping email")
+		return nil
+	}
+
+	sent, err := h.Deliver(ctx, notifier.Message{
+		EventID:   eventID,
+		EventType: events.TypeOrderCancelled,
+		To:        h.userEmail(int(event.UserID)),
+		UserID:    int(event.UserID),
+		OrderID:   int(event.ID),
+		Data:      event,
+	})
+	if err != nil || !sent {
+		return err
+	}
+
 	h.log.Info().
-		Int("user_id", event.UserID).
-		Int("order_id", event.ID).
+		Int64("user_id", event.UserID).
+		Int64("order_id", event.ID).
 		Msg("Order cancellation email sent to user")
 	return nil
 }
+
+func (h *NotificationHandler) SendStockAlert(ctx context.Context, eventID, eventType string, event events.StockAlert) error {
+	err := h.notifier.Notify(ctx, notifier.Message{
+		EventID:   eventID,
+		EventType: eventType,
+		To:        h.opsEmail,
+		Data:      event,
+	})
+	if err != nil {
+		return err
+	}
+
+	logEvent := h.log.Warn()
+	if eventType == events.TypeRestocked {
+		logEvent = h.log.Info()
+	}
+	logEvent.
+		Str("to", h.opsEmail).
+		Str("event_type", eventType).
+		Int64("product_id", event.ProductID).
+		Str("sku", event.SKU).
+		Int("stock", event.Stock).
+		Int("threshold", event.Threshold).
+		Msg("Stock alert email sent to ops")
+	return nil
+}
+
+// SendBackInStockEmails notifies every subscriber in the event once. Users
+// listed twice, or already notified about the product within the dedup
+// window, are skipped. A failed delivery stops the fan-out; users reached
+// before it are remembered, so the redelivered event only retries the rest.
+func (h *NotificationHandler) SendBackInStockEmails(ctx context.Context, eventID string, event events.BackInStock) error {
+	sent := 0
+	for _, uid := range event.UserIDs {
+		userID := int(uid)
+		claimed, err := h.backInStock.Claim(ctx, event.ProductID, userID, backInStockDedupWindow)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			h.log.Debug().
+				Int("user_id", userID).
+				Int64("product_id", event.ProductID).
+				Msg("Back-in-stock email already sent — skipping")
+			continue
+		}
+
+		delivered, err := h.Deliver(ctx, notifier.Message{
+			EventID:   eventID,
+			EventType: events.TypeBackInStock,
+			To:        h.userEmail(userID),
+			UserID:    userID,
+			Data:      event,
+		})
+		if err != nil {
+			if releaseErr := h.backInStock.Release(ctx, event.ProductID, userID); releaseErr != nil {
+				h.log.Error().Err(releaseErr).Int("user_id", userID).Msg("Failed to release back-in-stock claim")
+			}
+			return err
+		}
+		if !delivered {
+			continue
+		}
+		sent++
+
+		h.log.Info().
+			Int("user_id", userID).
+			Int64("product_id", event.ProductID).
+			Str("sku", event.SKU).
+			Msg("Back-in-stock email sent to user")
+	}
+
+	h.log.Info().
+		Int64("product_id", event.ProductID).
+		Int("subscribers", len(event.UserIDs)).
+		Int("sent", sent).
+		Msg("Back-in-stock notifications fanned out")
+	return nil
+}
+
+// collect adds msg to the user's digest, and flushes the digest right away
+// once it is full.
+func (h *NotificationHandler) collect(ctx context.Context, msg notifier.Message, decision preference.Decision) error {
+	payload, err := json.Marshal(msg.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode digest item: %w", err)
+	}
+	item := repository.DigestItem{
+		UserID:    msg.UserID,
+		EventID:   msg.EventID,
+		EventType: msg.EventType,
+		Payload:   payload,
+	}
+	if msg.OrderID != 0 {
+		item.OrderID = &msg.OrderID
+	}
+	digest, added, err := h.digests.AddToDigest(ctx, item, msg.To, decision.DigestWindow)
+	if err != nil {
+		return err
+	}
+	if !added {
+		h.log.Debug().
+			Int("user_id", msg.UserID).
+			Str("event_id", msg.EventID).
+			Msg("Event already in a digest — skipping")
+		return nil
+	}
+	h.log.Info().
+		Int("user_id", msg.UserID).
+		Str("event_type", msg.EventType).
+		Int64("digest_id", digest.ID).
+		Int("items", digest.ItemCount).
+		Time("flush_at", digest.FlushAt).
+		Msg("Notification added to digest")
+
+	maxItems := decision.DigestMaxItems
+	if maxItems == 0 {
+		maxItems = h.digestMaxItems
+	}
+	if maxItems <= 0 || digest.ItemCount < maxItems {
+		return nil
+	}
+
+	// The event is safely in the digest, so a failed flush is left to the
+	// digest's retries rather than failing the event.
+	claimed, err := h.digests.ClaimDigest(ctx, digest.ID, DigestLease)
+	if err != nil {
+		h.log.Error().Err(err).Int64("digest_id", digest.ID).Msg("Failed to claim full digest")
+		return nil
+	}
+	if claimed != nil {
+		if err := h.FlushDigest(ctx, *claimed); err != nil {
+			h.log.Error().Err(err).Int64("digest_id", digest.ID).Msg("Failed to flush full digest — retrying later")
+		}
+	}
+	return nil
+}
+
+// FlushDigest sends a claimed digest as one order.digest notification and
+// marks it sent. If sending fails, the digest is rescheduled and the error
+// returned. The summary goes through Deliver, so the user's channels and
+// quiet hours apply to it as to any other message.
+func (h *NotificationHandler) FlushDigest(ctx context.Context, digest repository.Digest) error {
+	if err := h.flushDigest(ctx, digest); err != nil {
+		if err := h.digests.RetryDigest(ctx, digest.ID, time.Now().Add(digestRetryDelay), err); err != nil {
+			h.log.Error().Err(err).Int64("digest_id", digest.ID).Msg("Failed to reschedule digest")
+		}
+		return err
+	}
+	return h.digests.MarkDigestSent(ctx, digest.ID)
+}
+
+func (h *NotificationHandler) flushDigest(ctx context.Context, digest repository.Digest) error {
+	items, err := h.digests.DigestItems(ctx, digest.ID)
+	if err != nil {
+		return err
+	}
+
+	event := model.OrderDigestEvent{
+		UserID: digest.UserID,
+		From:   digest.OpenedAt.UTC(),
+		To:     time.Now().UTC(),
+		Items:  make([]model.OrderDigestItem, 0, len(items)),
+	}
+	for _, item := range items {
+		// Both order events carry the order itself.
+		var order events.OrderCreated
+		if err := json.Unmarshal(item.Payload, &order); err != nil {
+			return fmt.Errorf("failed to decode digest item %d: %w", item.ID, err)
+		}
+		event.Items = append(event.Items, model.OrderDigestItem{
+			Type:      item.EventType,
+			OrderID:   order.ID,
+			ProductID: order.ProductID,
+			Quantity:  order.Quantity,
+			Status:    order.Status,
+			CreatedAt: order.CreatedAt,
+		})
+		switch item.EventType {
+		case events.TypeOrderCreated:
+			event.Created++
+		case events.TypeOrderCancelled:
+			event.Cancelled++
+		}
+	}
+
+	sent, err := h.Deliver(ctx, notifier.Message{
+		EventID:   fmt.Sprintf("digest:%d", digest.ID),
+		EventType: model.OrderDigestType,
+		To:        digest.Recipient,
+		UserID:    digest.UserID,
+		Data:      event,
+	})
+	if err != nil {
+		return err
+	}
+	if sent {
+		h.log.Info().
+			Int("user_id", digest.UserID).
+			Int64("digest_id", digest.ID).
+			Int("items", len(event.Items)).
+			Msg("Notification digest sent to user")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/preference"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// PreferenceHandler manages users' notification preferences and serves the
+// signed unsubscribe links included in their notifications.
+type PreferenceHandler struct {
+	repo     repository.PreferenceRepository
+	signer   *preference.Signer
+	channels map[string]bool
+	log      zerolog.Logger
+}
+
+// NewPreferenceHandler accepts channel preferences for the given configured
+// channel names.
+func NewPreferenceHandler(repo repository.PreferenceRepository, signer *preference.Signer, channels []string, log zerolog.Logger) *PreferenceHandler {
+	known := make(map[string]bool, len(channels))
+	for _, c := range channels {
+		known[c] = true
+	}
+	return &PreferenceHandler{repo: repo, signer: signer, channels: known, log: log}
+}
+
+type quietHoursRequest struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type digestRequest struct {
+	Window   string `json:"window"`
+	MaxItems int    `json:"max_items"`
+}
+
+type preferencesRequest struct {
+	Locale       string                         `json:"locale"`
+	Timezone     string                         `json:"timezone"`
+	QuietHours   *quietHoursRequest             `json:"quiet_hours"`
+	Digest       *digestRequest                 `json:"digest"`
+	Unsubscribed bool                           `json:"unsubscribed"`
+	Channels     []repository.ChannelPreference `json:"channels"`
+}
+
+func (h *PreferenceHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.repo.GetPreferences(r.Context(), userID)
+	if err != nil {
+		h.log.Error().Err(err).Int("user_id", userID).Msg("Failed to get notification preferences")
+		writeError(w, http.StatusInternalServerError, "Failed to get preferences")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// PutPreferences replaces the user's preferences:
+//
+//	{"locale": "tr", "timezone": "Europe/Istanbul",
+//	 "quiet_hours": {"start": "22:00", "end": "07:00"},
+//	 "digest": {"window": "1h", "max_items": 20},
+//	 "channels": [{"category": "orders", "channel": "smtp", "enabled": false}]}
+//
+// "digest" collects the user's order notifications into one summary per
+// window; max_items sends it early once it holds that many events.
+// "unsubscribed": false resubscribes a user who used an unsubscribe link.
+func (h *PreferenceHandler) PutPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req preferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	p := repository.DefaultPreferences(userID)
+	p.Locale = req.Locale
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			writeError(w, http.StatusBadRequest, "Unknown timezone "+req.Timezone)
+			return
+		}
+		p.Timezone = req.Timezone
+	}
+	if req.QuietHours != nil {
+		if _, err := preference.ParseClock(req.QuietHours.Start); err != nil {
+			writeError(w, http.StatusBadRequest, "quiet_hours.start: "+err.Error())
+			return
+		}
+		if _, err := preference.ParseClock(req.QuietHours.End); err != nil {
+			writeError(w, http.StatusBadRequest, "quiet_hours.end: "+err.Error())
+			return
+		}
+		if req.QuietHours.Start == req.QuietHours.End {
+			writeError(w, http.StatusBadRequest, "quiet_hours.start and quiet_hours.end must differ")
+			return
+		}
+		p.QuietStart, p.QuietEnd = &req.QuietHours.Start, &req.QuietHours.End
+	}
+	if req.Digest != nil {
+		if _, err := preference.ParseDigestWindow(req.Digest.Window); err != nil {
+			writeError(w, http.StatusBadRequest, "digest.window: "+err.Error())
+			return
+		}
+		if req.Digest.MaxItems < 0 {
+			writeError(w, http.StatusBadRequest, "digest.max_items must not be negative")
+			return
+		}
+		p.DigestWindow = &req.Digest.Window
+		if req.Digest.MaxItems > 0 {
+			p.DigestMaxItems = &req.Digest.MaxItems
+		}
+	}
+
+	seen := make(map[[2]string]bool)
+	for _, c := range req.Channels {
+		if !preference.IsCategory(c.Category) {
+			writeError(w, http.StatusBadRequest, "Unknown category "+c.Category)
+			return
+		}
+		if !h.channels[c.Channel] {
+			writeError(w, http.StatusBadRequest, "Unknown channel "+c.Channel)
+			return
+		}
+		key := [2]string{c.Category, c.Channel}
+		if seen[key] {
+			writeError(w, http.StatusBadRequest, "Duplicate preference for "+c.Category+"/"+c.Channel)
+			return
+		}
+		seen[key] = true
+		p.Channels = append(p.Channels, c)
+	}
+
+	if req.Unsubscribed {
+		current, err := h.repo.GetPreferences(r.Context(), userID)
+		if err != nil {
+			h.log.Error().Err(err).Int("user_id", userID).Msg("Failed to get notification preferences")
+			writeError(w, http.StatusInternalServerError, "Failed to save preferences")
+			return
+		}
+		p.UnsubscribedAt = current.UnsubscribedAt
+		if p.UnsubscribedAt == nil {
+			now := time.Now().UTC()
+			p.UnsubscribedAt = &now
+		}
+	}
+
+	saved, err := h.repo.SavePreferences(r.Context(), p)
+	if err != nil {
+		h.log.Error().Err(err).Int("user_id", userID).Msg("Failed to save notification preferences")
+		writeError(w, http.StatusInternalServerError, "Failed to save preferences")
+		return
+	}
+	h.log.Info().Int("user_id", userID).Msg("Notification preferences updated")
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// Unsubscribe handles the link in notifications, GET /unsubscribe?user_id=
+// &token=, as well as one-click POSTs from mail clients (RFC 8058).
+func (h *PreferenceHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || userID <= 0 || !h.signer.Verify(userID, r.URL.Query().Get("token")) {
+		writeError(w, http.StatusForbidden, "Invalid unsubscribe link")
+		return
+	}
+
+	if _, err := h.repo.Unsubscribe(r.Context(), userID); err != nil {
+		h.log.Error().Err(err).Int("user_id", userID).Msg("Failed to unsubscribe user")
+		writeError(w, http.StatusInternalServerError, "Failed to unsubscribe")
+		return
+	}
+	h.log.Info().Int("user_id", userID).Msg("User unsubscribed from notifications")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("You have been unsubscribed and will no longer receive notifications.\n"))
+}
+
+func userIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("user_id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return 0, false
+	}
+	return id, true
+}
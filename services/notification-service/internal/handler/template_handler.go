@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/templates"
+	"github.com/rs/zerolog"
+)
+
+type TemplateHandler struct {
+	renderer notifier.Renderer
+	log      zerolog.Logger
+}
+
+func NewTemplateHandler(renderer notifier.Renderer, log zerolog.Logger) *TemplateHandler {
+	return &TemplateHandler{renderer: renderer, log: log}
+}
+
+// Preview renders the template for event_type, channel and locale. GET uses
+// a built-in sample event; POST takes the event to render in the body:
+//
+//	{"event_type": "order.created", "channel": "smtp", "locale": "tr", "event": {...}}
+func (h *TemplateHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		EventType string          `json:"event_type"`
+		Channel   string          `json:"channel"`
+		Locale    string          `json:"locale"`
+		Event     json.RawMessage `json:"event"`
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		req.EventType, req.Channel, req.Locale = q.Get("event_type"), q.Get("channel"), q.Get("locale")
+	}
+	req.EventType = strings.TrimSpace(req.EventType)
+	if req.Channel == "" {
+		req.Channel = templates.DefaultChannel
+	}
+
+	var event interface{}
+	if len(req.Event) > 0 {
+		e, ok := model.NewEvent(req.EventType)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Unknown event_type")
+			return
+		}
+		if err := json.Unmarshal(req.Event, e); err != nil {
+			writeError(w, http.StatusBadRequest, "event does not match the "+req.EventType+" payload")
+			return
+		}
+		event = e
+	} else {
+		e, ok := model.SampleEvent(req.EventType)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Unknown event_type")
+			return
+		}
+		event = e
+	}
+
+	content, err := h.renderer.Render(req.Channel, notifier.Message{
+		EventType: req.EventType,
+		Locale:    req.Locale,
+		Data:      event,
+	})
+	if err != nil {
+		h.log.Warn().Err(err).Str("event_type", req.EventType).Str("channel", req.Channel).Msg("Template preview failed")
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"event_type": req.EventType,
+		"channel":    req.Channel,
+		"locale":     req.Locale,
+		"subject":    content.Subject,
+		"body":       content.Body,
+		"html_body":  content.HTMLBody,
+	})
+}
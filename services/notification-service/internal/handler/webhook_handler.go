@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/webhook"
+	"github.com/rs/zerolog"
+)
+
+// Redeliverer makes a manual attempt at a webhook delivery;
+// webhook.Deliverer implements it.
+type Redeliverer interface {
+	Redeliver(ctx context.Context, id int64) (*repository.WebhookDelivery, error)
+}
+
+// WebhookHandler manages partner webhook subscriptions and their delivery
+// log.
+type WebhookHandler struct {
+	repo        repository.WebhookRepository
+	redeliverer Redeliverer
+	log         zerolog.Logger
+}
+
+func NewWebhookHandler(repo repository.WebhookRepository, redeliverer Redeliverer, log zerolog.Logger) *WebhookHandler {
+	return &WebhookHandler{repo: repo, redeliverer: redeliverer, log: log}
+}
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description"`
+}
+
+// webhookWithSecret shows the signing secret, which is otherwise never
+// returned, to whoever creates the webhook.
+type webhookWithSecret struct {
+	*repository.Webhook
+	Secret string `json:"secret"`
+}
+
+type webhookDeliveryResponse struct {
+	*repository.WebhookDelivery
+	Attempts []repository.WebhookAttempt `json:"attempt_log"`
+}
+
+// CreateWebhook handles POST /webhooks:
+//
+//	{"url": "https://partner.example.com/hooks", "event_types": ["order.*"]}
+//
+// A secret is generated unless one is given.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if msg := validateWebhookRequest(req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if req.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			h.log.Error().Err(err).Msg("Failed to generate webhook secret")
+			writeError(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+		req.Secret = secret
+	}
+
+	created, err := h.repo.CreateWebhook(r.Context(), &repository.Webhook{
+		URL:         req.URL,
+		Secret:      req.Secret,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+	})
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to create webhook")
+		writeError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+	h.log.Info().Int64("webhook_id", created.ID).Str("url", created.URL).Strs("event_types", created.EventTypes).Msg("Webhook created")
+	writeJSON(w, http.StatusCreated, webhookWithSecret{Webhook: created, Secret: created.Secret})
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	list, err := h.repo.ListWebhooks(r.Context())
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list webhooks")
+		writeError(w, http.StatusInternalServerError, "Failed to list webhooks")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "webhook")
+	if !ok {
+		return
+	}
+	hook, ok := h.getWebhook(w, r, id)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// UpdateWebhook handles PUT /webhooks/{id}. The secret is kept unless a new
+// one is given; status is changed with the enable and disable endpoints.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "webhook")
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if msg := validateWebhookRequest(req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	current, ok := h.getWebhook(w, r, id)
+	if !ok {
+		return
+	}
+	current.URL, current.EventTypes, current.Description = req.URL, req.EventTypes, req.Description
+	if req.Secret != "" {
+		current.Secret = req.Secret
+	}
+
+	updated, err := h.repo.UpdateWebhook(r.Context(), current)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("webhook_id", id).Msg("Failed to update webhook")
+		writeError(w, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+	h.log.Info().Int64("webhook_id", id).Msg("Webhook updated")
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "webhook")
+	if !ok {
+		return
+	}
+
+	err := h.repo.DeleteWebhook(r.Context(), id)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("webhook_id", id).Msg("Failed to delete webhook")
+		writeError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+	h.log.Info().Int64("webhook_id", id).Msg("Webhook deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EnableWebhook re-enables a webhook, e.g. once a partner has fixed the
+// endpoint that got it disabled. Deliveries queued meanwhile are resumed.
+func (h *WebhookHandler) EnableWebhook(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, repository.WebhookActive, "")
+}
+
+func (h *WebhookHandler) DisableWebhook(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, repository.WebhookDisabled, "disabled manually")
+}
+
+func (h *WebhookHandler) setStatus(w http.ResponseWriter, r *http.Request, status, reason string) {
+	id, ok := pathID(w, r, "webhook")
+	if !ok {
+		return
+	}
+
+	updated, err := h.repo.SetWebhookStatus(r.Context(), id, status, reason)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("webhook_id", id).Msg("Failed to change webhook status")
+		writeError(w, http.StatusInternalServerError, "Failed to change webhook status")
+		return
+	}
+	h.log.Info().Int64("webhook_id", id).Str("status", status).Msg("Webhook status changed")
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// ListDeliveries handles GET /webhooks/{id}/deliveries?status=&limit=.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "webhook")
+	if !ok {
+		return
+	}
+	if _, ok := h.getWebhook(w, r, id); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.WebhookDeliveryFilter{WebhookID: id, Status: q.Get("status"), Limit: defaultHistoryLimit}
+	switch filter.Status {
+	case "", repository.DeliveryPending, repository.DeliveryDelivered, repository.DeliveryFailed:
+	default:
+		writeError(w, http.StatusBadRequest, "status must be pending, delivered or failed")
+		return
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxHistoryLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	list, err := h.repo.ListWebhookDeliveries(r.Context(), filter)
+	if err != nil {
+		h.log.Error().Err(err).Int64("webhook_id", id).Msg("Failed to list webhook deliveries")
+		writeError(w, http.StatusInternalServerError, "Failed to list deliveries")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GetDelivery returns a delivery with the log of its attempts.
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "delivery")
+	if !ok {
+		return
+	}
+
+	d, err := h.repo.GetWebhookDelivery(r.Context(), id)
+	if errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+		writeError(w, http.StatusNotFound, "Delivery not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("delivery_id", id).Msg("Failed to get webhook delivery")
+		writeError(w, http.StatusInternalServerError, "Failed to get delivery")
+		return
+	}
+	attempts, err := h.repo.ListWebhookAttempts(r.Context(), id)
+	if err != nil {
+		h.log.Error().Err(err).Int64("delivery_id", id).Msg("Failed to list webhook attempts")
+		writeError(w, http.StatusInternalServerError, "Failed to get delivery")
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookDeliveryResponse{WebhookDelivery: d, Attempts: attempts})
+}
+
+// Redeliver sends a delivery again right away, even one that already
+// succeeded or gave up, or whose webhook is disabled. A failed attempt
+// answers 502 with the updated delivery.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "delivery")
+	if !ok {
+		return
+	}
+
+	d, err := h.redeliverer.Redeliver(r.Context(), id)
+	if errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+		writeError(w, http.StatusNotFound, "Delivery not found")
+		return
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("delivery_id", id).Msg("Failed to redeliver webhook")
+		writeError(w, http.StatusInternalServerError, "Failed to redeliver")
+		return
+	}
+
+	if d.LastError != "" {
+		writeJSON(w, http.StatusBadGateway, d)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (h *WebhookHandler) getWebhook(w http.ResponseWriter, r *http.Request, id int64) (*repository.Webhook, bool) {
+	hook, err := h.repo.GetWebhook(r.Context(), id)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return nil, false
+	}
+	if err != nil {
+		h.log.Error().Err(err).Int64("webhook_id", id).Msg("Failed to get webhook")
+		writeError(w, http.StatusInternalServerError, "Failed to get webhook")
+		return nil, false
+	}
+	return hook, true
+}
+
+func validateWebhookRequest(req webhookRequest) string {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url must be an absolute http or https URL"
+	}
+	if err := webhook.ValidateEventTypes(req.EventTypes); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid "+name+" ID")
+		return 0, false
+	}
+	return id, true
+}
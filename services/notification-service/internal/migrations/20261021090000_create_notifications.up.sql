@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS notifications (
+                                             id BIGSERIAL PRIMARY KEY,
+                                             event_type TEXT NOT NULL,
+                                             channel TEXT NOT NULL,
+                                             recipient TEXT NOT NULL DEFAULT '',
+                                             user_id INT,
+                                             order_id INT,
+                                             template TEXT NOT NULL DEFAULT '',
+                                             locale TEXT NOT NULL DEFAULT '',
+                                             subject TEXT NOT NULL DEFAULT '',
+                                             body TEXT NOT NULL DEFAULT '',
+                                             html_body TEXT NOT NULL DEFAULT '',
+                                             payload JSONB,
+                                             status TEXT NOT NULL CHECK (status IN ('sent', 'failed')),
+                                             attempts INT NOT NULL DEFAULT 1,
+                                             provider_response TEXT NOT NULL DEFAULT '',
+                                             last_error TEXT NOT NULL DEFAULT '',
+                                             created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                             updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                             sent_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE INDEX idx_notifications_user ON notifications (user_id, created_at DESC) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_notifications_order ON notifications (order_id, created_at DESC) WHERE order_id IS NOT NULL;
+CREATE INDEX idx_notifications_failed ON notifications (created_at DESC) WHERE status = 'failed';
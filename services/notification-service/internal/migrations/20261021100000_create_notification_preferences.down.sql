@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS held_notifications;
+DROP TABLE IF EXISTS notification_channel_preferences;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+                                                        user_id INT PRIMARY KEY,
+                                                        locale TEXT NOT NULL DEFAULT '',
+                                                        timezone TEXT NOT NULL DEFAULT 'UTC',
+                                                        quiet_start TEXT CHECK (quiet_start ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+                                                        quiet_end TEXT CHECK (quiet_end ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+                                                        unsubscribed_at TIMESTAMP WITHOUT TIME ZONE,
+                                                        created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                        updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                        CHECK ((quiet_start IS NULL) = (quiet_end IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS notification_channel_preferences (
+                                                                user_id INT NOT NULL REFERENCES notification_preferences (user_id) ON DELETE CASCADE,
+                                                                category TEXT NOT NULL,
+                                                                channel TEXT NOT NULL,
+                                                                enabled BOOLEAN NOT NULL,
+                                                                PRIMARY KEY (user_id, category, channel)
+);
+
+-- Messages that arrived during a user's quiet hours, delivered once they end.
+CREATE TABLE IF NOT EXISTS held_notifications (
+                                                  id BIGSERIAL PRIMARY KEY,
+                                                  event_type TEXT NOT NULL,
+                                                  recipient TEXT NOT NULL,
+                                                  user_id INT NOT NULL,
+                                                  order_id INT,
+                                                  payload JSONB NOT NULL,
+                                                  deliver_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                                                  attempts INT NOT NULL DEFAULT 0,
+                                                  last_error TEXT NOT NULL DEFAULT '',
+                                                  created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_held_notifications_due ON held_notifications (deliver_at);
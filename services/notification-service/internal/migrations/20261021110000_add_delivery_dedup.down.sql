@@ -0,0 +1,3 @@
+ALTER TABLE held_notifications DROP COLUMN IF EXISTS event_id;
+ALTER TABLE notifications DROP COLUMN IF EXISTS event_id;
+DROP TABLE IF EXISTS delivered_events;
@@ -0,0 +1,15 @@
+-- One row per (event, recipient, channel) delivered, kept until expires_at so
+-- a redelivered event is not sent again.
+CREATE TABLE IF NOT EXISTS delivered_events (
+                                                event_id TEXT NOT NULL,
+                                                recipient TEXT NOT NULL,
+                                                channel TEXT NOT NULL,
+                                                delivered_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                                                PRIMARY KEY (event_id, recipient, channel)
+);
+
+CREATE INDEX idx_delivered_events_expires ON delivered_events (expires_at);
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS event_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE held_notifications ADD COLUMN IF NOT EXISTS event_id TEXT NOT NULL DEFAULT '';
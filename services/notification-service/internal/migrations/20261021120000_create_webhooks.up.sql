@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+                                                     id BIGSERIAL PRIMARY KEY,
+                                                     url TEXT NOT NULL,
+                                                     secret TEXT NOT NULL,
+                                                     event_types TEXT[] NOT NULL,
+                                                     description TEXT NOT NULL DEFAULT '',
+                                                     status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'disabled')),
+                                                     consecutive_failures INT NOT NULL DEFAULT 0,
+                                                     disabled_reason TEXT NOT NULL DEFAULT '',
+                                                     disabled_at TIMESTAMP WITHOUT TIME ZONE,
+                                                     last_success_at TIMESTAMP WITHOUT TIME ZONE,
+                                                     created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                     updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+                                                  id BIGSERIAL PRIMARY KEY,
+                                                  subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+                                                  event_id TEXT NOT NULL,
+                                                  event_type TEXT NOT NULL,
+                                                  payload JSONB NOT NULL,
+                                                  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+                                                  attempts INT NOT NULL DEFAULT 0,
+                                                  next_attempt_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                  last_status_code INT,
+                                                  last_error TEXT NOT NULL DEFAULT '',
+                                                  created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                  updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                  delivered_at TIMESTAMP WITHOUT TIME ZONE,
+                                                  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+                                                         id BIGSERIAL PRIMARY KEY,
+                                                         delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+                                                         attempt INT NOT NULL,
+                                                         manual BOOLEAN NOT NULL DEFAULT FALSE,
+                                                         status_code INT,
+                                                         response TEXT NOT NULL DEFAULT '',
+                                                         error TEXT NOT NULL DEFAULT '',
+                                                         duration_ms INT NOT NULL,
+                                                         created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, attempt);
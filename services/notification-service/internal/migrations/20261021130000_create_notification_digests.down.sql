@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS notification_digest_items;
+DROP TABLE IF EXISTS notification_digests;
+ALTER TABLE notification_preferences
+    DROP COLUMN IF EXISTS digest_max_items,
+    DROP COLUMN IF EXISTS digest_window;
@@ -0,0 +1,37 @@
+-- A digest window is a Go duration such as "1h"; NULL sends every event on
+-- its own.
+ALTER TABLE notification_preferences
+    ADD COLUMN IF NOT EXISTS digest_window TEXT,
+    ADD COLUMN IF NOT EXISTS digest_max_items INT CHECK (digest_max_items > 0);
+
+-- Events collected for a user in digest mode. A user has at most one open
+-- digest; it is flushed at flush_at, or earlier once it is full.
+CREATE TABLE IF NOT EXISTS notification_digests (
+                                                    id BIGSERIAL PRIMARY KEY,
+                                                    user_id INT NOT NULL,
+                                                    recipient TEXT NOT NULL,
+                                                    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'flushing', 'sent')),
+                                                    item_count INT NOT NULL DEFAULT 0,
+                                                    attempts INT NOT NULL DEFAULT 0,
+                                                    last_error TEXT NOT NULL DEFAULT '',
+                                                    opened_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                    flush_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                                                    sent_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_notification_digests_open ON notification_digests (user_id) WHERE status = 'open';
+CREATE INDEX idx_notification_digests_due ON notification_digests (flush_at) WHERE status <> 'sent';
+
+CREATE TABLE IF NOT EXISTS notification_digest_items (
+                                                         id BIGSERIAL PRIMARY KEY,
+                                                         digest_id BIGINT NOT NULL REFERENCES notification_digests (id) ON DELETE CASCADE,
+                                                         user_id INT NOT NULL,
+                                                         event_id TEXT NOT NULL,
+                                                         event_type TEXT NOT NULL,
+                                                         order_id INT,
+                                                         payload JSONB NOT NULL,
+                                                         created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                         UNIQUE (user_id, event_id)
+);
+
+CREATE INDEX idx_notification_digest_items_digest ON notification_digest_items (digest_id, id);
@@ -0,0 +1,11 @@
+-- One row per (product, user) sent a back-in-stock notification, kept until
+-- expires_at so that another one for the product is suppressed until then.
+CREATE TABLE IF NOT EXISTS back_in_stock_sent (
+                                                  product_id BIGINT NOT NULL,
+                                                  user_id INTEGER NOT NULL,
+                                                  sent_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+                                                  expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                                                  PRIMARY KEY (product_id, user_id)
+);
+
+CREATE INDEX idx_back_in_stock_sent_expires ON back_in_stock_sent (expires_at);
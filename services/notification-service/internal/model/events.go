@@ -1,19 +1,29 @@
 package model
 
-type OrderCreatedEvent struct {
-	ID        int    `json:"id"`
-	UserID    int    `json:"user_id"`
-	ProductID int    `json:"product_id"`
-	Quantity  int    `json:"quantity"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
+import "time"
+
+// OrderDigestType is the event type of the summary sent to a user in digest
+// mode in place of their individual order notifications.
+const OrderDigestType = "order.digest"
+
+// OrderDigestEvent summarises the order events collected for a user between
+// From and To, oldest first. Unlike the events in pkg/events it is not
+// published; it only exists to render digests.
+type OrderDigestEvent struct {
+	UserID    int               `json:"user_id"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Items     []OrderDigestItem `json:"items"`
+	Created   int               `json:"created"`
+	Cancelled int               `json:"cancelled"`
 }
 
-type OrderCancelledEvent struct {
-	ID        int    `json:"id"`
-	UserID    int    `json:"user_id"`
-	ProductID int    `json:"product_id"`
-	Quantity  int    `json:"quantity"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
+// OrderDigestItem is one order event in a digest; Type is its event type.
+type OrderDigestItem struct {
+	Type      string    `json:"type"`
+	OrderID   int64     `json:"order_id"`
+	ProductID int64     `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+)
+
+// NewEvent returns a pointer to an empty payload of the type published for
+// eventType, ready to be unmarshalled into.
+func NewEvent(eventType string) (interface{}, bool) {
+	if eventType == OrderDigestType {
+		return &OrderDigestEvent{}, true
+	}
+	e, err := events.LatestPayload(eventType)
+	if err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+var sampleTime = time.Date(2026, 10, 19, 14, 5, 0, 0, time.UTC)
+
+// SampleEvent returns a realistic payload for eventType, used to preview
+// templates.
+func SampleEvent(eventType string) (interface{}, bool) {
+	switch eventType {
+	case events.TypeOrderCreated:
+		return &events.OrderCreated{
+			ID: 1042, UserID: 7, VariantID: 12, ProductID: 12, Quantity: 2,
+			UnitPriceMinor: 129900, Currency: "TRY", Status: "created", CreatedAt: sampleTime,
+		}, true
+	case events.TypeOrderCancelled:
+		return &events.OrderCancelled{
+			ID: 1042, UserID: 7, VariantID: 12, ProductID: 12, Quantity: 2, Status: "cancelled",
+			CreatedAt: sampleTime, CancelledAt: sampleTime.Add(90 * time.Minute),
+		}, true
+	case events.TypeLowStock:
+		return &events.StockAlert{
+			ProductID: 12, SKU: "MUG-BLK-350", ProductName: "Black Mug 350ml",
+			State: "low_stock", PreviousState: "in_stock", Stock: 4, Threshold: 10,
+			OccurredAt: sampleTime,
+		}, true
+	case events.TypeOutOfStock:
+		return &events.StockAlert{
+			ProductID: 12, SKU: "MUG-BLK-350", ProductName: "Black Mug 350ml",
+			State: "out_of_stock", PreviousState: "low_stock", Stock: 0, Threshold: 10,
+			OccurredAt: sampleTime,
+		}, true
+	case events.TypeRestocked:
+		return &events.StockAlert{
+			ProductID: 12, SKU: "MUG-BLK-350", ProductName: "Black Mug 350ml",
+			State: "in_stock", PreviousState: "out_of_stock", Stock: 60, Threshold: 10,
+			OccurredAt: sampleTime,
+		}, true
+	case events.TypeBackInStock:
+		return &events.BackInStock{
+			ProductID: 12, SKU: "MUG-BLK-350", ProductName: "Black Mug 350ml", Stock: 60,
+			UserIDs: []int64{7, 9}, OccurredAt: sampleTime,
+		}, true
+	case OrderDigestType:
+		from := sampleTime.Add(-65 * time.Minute)
+		return &OrderDigestEvent{
+			UserID: 7, From: from, To: from.Add(time.Hour),
+			Items: []OrderDigestItem{
+				{Type: events.TypeOrderCreated, OrderID: 1042, ProductID: 12, Quantity: 2, Status: "created", CreatedAt: from.Add(5 * time.Minute)},
+				{Type: events.TypeOrderCreated, OrderID: 1043, ProductID: 31, Quantity: 10, Status: "created", CreatedAt: from.Add(20 * time.Minute)},
+				{Type: events.TypeOrderCancelled, OrderID: 1042, ProductID: 12, Quantity: 2, Status: "cancelled", CreatedAt: from.Add(5 * time.Minute)},
+			},
+			Created: 2, Cancelled: 1,
+		}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,105 @@
+// Package notifier delivers rendered notifications over pluggable channels
+// (SMTP email, log or file sink, HTTP webhook) and routes each event type to
+// the channels configured for it.
+package notifier
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is one notification to one recipient. Data carries the original
+// event, both for rendering and so that structured channels such as webhooks
+// can forward it as is. Subject, Body and HTMLBody are filled in per channel
+// by the Router's Renderer.
+type Message struct {
+	EventID   string      `json:"event_id,omitempty"`
+	EventType string      `json:"event_type"`
+	To        string      `json:"to"`
+	UserID    int         `json:"user_id,omitempty"`
+	OrderID   int         `json:"order_id,omitempty"`
+	Locale    string      `json:"locale,omitempty"`
+	Subject   string      `json:"subject"`
+	Body      string      `json:"body"`
+	HTMLBody  string      `json:"html_body,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+
+	// UnsubscribeURL is the recipient's signed opt-out link, if any.
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+	// Channels adjusts routing for this recipient: true adds a configured
+	// channel the event is not routed to, false removes a routed one.
+	Channels map[string]bool `json:"-"`
+}
+
+// Content is a rendered subject and body. HTMLBody is optional. Template
+// names the template used, empty when the content was not templated.
+type Content struct {
+	Template string `json:"template,omitempty"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	HTMLBody string `json:"html_body,omitempty"`
+}
+
+// Renderer produces the content of msg for one channel.
+type Renderer interface {
+	Render(channel string, msg Message) (Content, error)
+}
+
+// Notifier is a delivery channel. Notify must be safe for concurrent use and
+// return an error only if the message was not delivered.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Sender is implemented by channels that can report the provider's reply to
+// a delivery, such as an SMTP queue id or a webhook status line. The Router
+// prefers it to Notify.
+type Sender interface {
+	Send(ctx context.Context, msg Message) (response string, err error)
+}
+
+// Delivery is the outcome of one message on one channel. Message holds the
+// rendered content; Err is nil when the message was delivered.
+type Delivery struct {
+	Channel  string
+	Template string
+	Message  Message
+	Response string
+	Err      error
+}
+
+// Recorder keeps a history of deliveries. The Router records every attempt,
+// including messages that failed to render.
+type Recorder interface {
+	Record(ctx context.Context, d Delivery) error
+}
+
+// DeliveryKey identifies one delivery for deduplication: an event reaches
+// each recipient at most once per channel.
+type DeliveryKey struct {
+	EventID   string
+	Recipient string
+	Channel   string
+}
+
+// Deduper remembers successful deliveries for a while, so that an event
+// redelivered by the broker is not sent again.
+type Deduper interface {
+	Delivered(ctx context.Context, key DeliveryKey) (bool, error)
+	MarkDelivered(ctx context.Context, key DeliveryKey) error
+}
+
+// DeliveryError reports which channel failed to deliver a message.
+type DeliveryError struct {
+	Channel string
+	Err     error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("%s delivery failed: %v", e.Channel, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,226 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Route sends events matching Pattern to Channels. A pattern is an exact
+// event type, a prefix ending in "*" such as "inventory.*", or "*" alone.
+type Route struct {
+	Pattern  string
+	Channels []string
+}
+
+// ParseRoutes reads a spec like "order.*=smtp,log;inventory.*=webhook;*=log".
+func ParseRoutes(spec string) ([]Route, error) {
+	var routes []Route
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, channels, ok := strings.Cut(entry, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid route %q: expected pattern=channel[,channel]", entry)
+		}
+		if i := strings.Index(pattern, "*"); i >= 0 && i != len(pattern)-1 {
+			return nil, fmt.Errorf("invalid route %q: '*' is only allowed at the end", entry)
+		}
+
+		route := Route{Pattern: pattern}
+		for _, name := range strings.Split(channels, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				route.Channels = append(route.Channels, name)
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// Router is a Notifier that fans a message out to the channels routed for
+// its event type, rendering the content separately for each channel. The
+// most specific pattern wins: an exact match, then the longest prefix, then
+// "*". An event with no route is dropped with a warning.
+type Router struct {
+	routes   []Route
+	channels map[string]Notifier
+	renderer Renderer
+	recorder Recorder
+	deduper  Deduper
+	log      zerolog.Logger
+}
+
+// NewRouter checks that every routed channel exists in channels. recorder
+// and deduper may be nil.
+func NewRouter(routes []Route, channels map[string]Notifier, renderer Renderer, recorder Recorder, deduper Deduper, log zerolog.Logger) (*Router, error) {
+	for _, route := range routes {
+		for _, name := range route.Channels {
+			if _, ok := channels[name]; !ok {
+				return nil, fmt.Errorf("route %q uses channel %q, which is not configured", route.Pattern, name)
+			}
+		}
+	}
+
+	sorted := append([]Route(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return specificity(sorted[i].Pattern) > specificity(sorted[j].Pattern)
+	})
+	return &Router{routes: sorted, channels: channels, renderer: renderer, recorder: recorder, deduper: deduper, log: log}, nil
+}
+
+func (r *Router) Name() string { return "router" }
+
+// Notify delivers msg on every routed channel, even when an earlier one
+// fails, and returns the failures joined as DeliveryErrors. Channels that
+// already delivered msg.EventID to the recipient are skipped, so a retried
+// event only goes out on the channels that failed.
+func (r *Router) Notify(ctx context.Context, msg Message) error {
+	names := r.channelsForMessage(msg)
+	if len(names) == 0 {
+		r.log.Warn().Str("event_type", msg.EventType).Msg("No notification channel routed for event — dropping")
+		return nil
+	}
+
+	var errs []error
+	for _, name := range names {
+		key := DeliveryKey{EventID: msg.EventID, Recipient: msg.To, Channel: name}
+		if r.alreadyDelivered(ctx, key) {
+			r.log.Info().
+				Str("event_id", msg.EventID).
+				Str("event_type", msg.EventType).
+				Str("channel", name).
+				Msg("Notification already delivered — skipping duplicate")
+			continue
+		}
+
+		rendered := msg
+		content, err := r.renderer.Render(name, msg)
+		if err == nil {
+			rendered.Subject, rendered.Body, rendered.HTMLBody = content.Subject, content.Body, content.HTMLBody
+		}
+
+		var response string
+		if err == nil {
+			response, err = r.Send(ctx, name, rendered)
+		}
+		r.record(ctx, Delivery{Channel: name, Template: content.Template, Message: rendered, Response: response, Err: err})
+		if err != nil {
+			errs = append(errs, &DeliveryError{Channel: name, Err: err})
+			continue
+		}
+		r.markDelivered(ctx, key)
+	}
+	return errors.Join(errs...)
+}
+
+// Send delivers an already rendered message on one channel, bypassing
+// routing and recording. It is used to resend a stored notification.
+func (r *Router) Send(ctx context.Context, channel string, msg Message) (string, error) {
+	n, ok := r.channels[channel]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownChannel, channel)
+	}
+	if s, ok := n.(Sender); ok {
+		return s.Send(ctx, msg)
+	}
+	return "", n.Notify(ctx, msg)
+}
+
+// alreadyDelivered fails open: if the dedup store is unavailable the message
+// is sent, as a duplicate is better than a lost notification.
+func (r *Router) alreadyDelivered(ctx context.Context, key DeliveryKey) bool {
+	if r.deduper == nil || key.EventID == "" {
+		return false
+	}
+	delivered, err := r.deduper.Delivered(ctx, key)
+	if err != nil {
+		r.log.Error().Err(err).Str("event_id", key.EventID).Str("channel", key.Channel).Msg("Failed to check delivery dedup")
+		return false
+	}
+	return delivered
+}
+
+func (r *Router) markDelivered(ctx context.Context, key DeliveryKey) {
+	if r.deduper == nil || key.EventID == "" {
+		return
+	}
+	if err := r.deduper.MarkDelivered(ctx, key); err != nil {
+		r.log.Error().Err(err).Str("event_id", key.EventID).Str("channel", key.Channel).Msg("Failed to mark delivery as done")
+	}
+}
+
+// record stores d. A failure to record is logged rather than returned, so
+// that a delivered message is not sent again because of it.
+func (r *Router) record(ctx context.Context, d Delivery) {
+	if r.recorder == nil {
+		return
+	}
+	if err := r.recorder.Record(ctx, d); err != nil {
+		r.log.Error().Err(err).
+			Str("channel", d.Channel).
+			Str("event_type", d.Message.EventType).
+			Msg("Failed to record notification delivery")
+	}
+}
+
+// ChannelsFor returns the channel names routed for eventType.
+func (r *Router) ChannelsFor(eventType string) []string {
+	for _, route := range r.routes {
+		if matches(route.Pattern, eventType) {
+			return route.Channels
+		}
+	}
+	return nil
+}
+
+// channelsForMessage applies the message's channel overrides to the routed
+// channels. Added channels that are not configured are ignored.
+func (r *Router) channelsForMessage(msg Message) []string {
+	routed := r.ChannelsFor(msg.EventType)
+	if len(msg.Channels) == 0 {
+		return routed
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range routed {
+		seen[name] = true
+		if enabled, ok := msg.Channels[name]; !ok || enabled {
+			names = append(names, name)
+		}
+	}
+	extra := make([]string, 0, len(msg.Channels))
+	for name, enabled := range msg.Channels {
+		if _, configured := r.channels[name]; enabled && configured && !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+func matches(pattern, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
+
+// specificity ranks exact patterns above any wildcard and longer prefixes
+// above shorter ones.
+func specificity(pattern string) int {
+	if !strings.HasSuffix(pattern, "*") {
+		return 1 << 20
+	}
+	return len(pattern)
+}
@@ -0,0 +1,266 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeChannel records the messages it is asked to deliver and fails while
+// err is set.
+type fakeChannel struct {
+	name string
+	err  error
+	sent []Message
+}
+
+func (c *fakeChannel) Name() string { return c.name }
+
+func (c *fakeChannel) Notify(_ context.Context, msg Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// fakeSender is a channel reporting a provider reply.
+type fakeSender struct {
+	fakeChannel
+}
+
+func (c *fakeSender) Send(ctx context.Context, msg Message) (string, error) {
+	if err := c.Notify(ctx, msg); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("250 queued as %d", len(c.sent)), nil
+}
+
+// channelRenderer renders content that names the channel, with an HTML body
+// for smtp only.
+type channelRenderer struct{}
+
+func (channelRenderer) Render(channel string, msg Message) (Content, error) {
+	if channel == "broken" {
+		return Content{}, errors.New("template missing")
+	}
+	c := Content{
+		Template: msg.EventType + "." + channel,
+		Subject:  channel + " subject for " + msg.EventType,
+		Body:     channel + " body for " + msg.To,
+	}
+	if channel == "smtp" {
+		c.HTMLBody = "<p>" + c.Body + "</p>"
+	}
+	return c, nil
+}
+
+type memoryRecorder struct {
+	deliveries []Delivery
+}
+
+func (r *memoryRecorder) Record(_ context.Context, d Delivery) error {
+	r.deliveries = append(r.deliveries, d)
+	return nil
+}
+
+type memoryDeduper struct {
+	delivered map[DeliveryKey]bool
+}
+
+func (d *memoryDeduper) Delivered(_ context.Context, key DeliveryKey) (bool, error) {
+	return d.delivered[key], nil
+}
+
+func (d *memoryDeduper) MarkDelivered(_ context.Context, key DeliveryKey) error {
+	d.delivered[key] = true
+	return nil
+}
+
+type routerFixture struct {
+	router   *Router
+	smtp     *fakeSender
+	log      *fakeChannel
+	webhook  *fakeChannel
+	recorder *memoryRecorder
+	deduper  *memoryDeduper
+}
+
+func newRouterFixture(t *testing.T, spec string) *routerFixture {
+	t.Helper()
+	routes, err := ParseRoutes(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &routerFixture{
+		smtp:     &fakeSender{fakeChannel{name: "smtp"}},
+		log:      &fakeChannel{name: "log"},
+		webhook:  &fakeChannel{name: "webhook"},
+		recorder: &memoryRecorder{},
+		deduper:  &memoryDeduper{delivered: map[DeliveryKey]bool{}},
+	}
+	channels := map[string]Notifier{"smtp": f.smtp, "log": f.log, "webhook": f.webhook, "broken": &fakeChannel{name: "broken"}}
+	f.router, err = NewRouter(routes, channels, channelRenderer{}, f.recorder, f.deduper, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestRouterRendersPerChannel(t *testing.T) {
+	f := newRouterFixture(t, "order.*=smtp,log")
+
+	msg := Message{EventID: "order-service/event_logs/1", EventType: "order.created", To: "user-7@example.com", UserID: 7}
+	if err := f.router.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if len(f.smtp.sent) != 1 || len(f.log.sent) != 1 {
+		t.Fatalf("smtp got %d, log got %d messages; want 1 each", len(f.smtp.sent), len(f.log.sent))
+	}
+	smtp, log := f.smtp.sent[0], f.log.sent[0]
+	if smtp.Subject != "smtp subject for order.created" || smtp.Body != "smtp body for user-7@example.com" ||
+		smtp.HTMLBody != "<p>smtp body for user-7@example.com</p>" {
+		t.Errorf("smtp message = %+v", smtp)
+	}
+	if log.Subject != "log subject for order.created" || log.Body != "log body for user-7@example.com" || log.HTMLBody != "" {
+		t.Errorf("log message = %+v", log)
+	}
+	if smtp.UserID != 7 || smtp.EventID != msg.EventID {
+		t.Errorf("smtp message lost fields of the original: %+v", smtp)
+	}
+
+	if len(f.recorder.deliveries) != 2 {
+		t.Fatalf("recorded %d deliveries, want 2", len(f.recorder.deliveries))
+	}
+	for _, d := range f.recorder.deliveries {
+		if d.Template != "order.created."+d.Channel || d.Message.Subject != d.Channel+" subject for order.created" || d.Err != nil {
+			t.Errorf("recorded delivery = %+v", d)
+		}
+	}
+	if got := f.recorder.deliveries[0].Response; got != "250 queued as 1" {
+		t.Errorf("smtp response = %q, want the sender's reply", got)
+	}
+}
+
+func TestRouterRecordsRenderFailure(t *testing.T) {
+	f := newRouterFixture(t, "order.created=broken,log")
+
+	err := f.router.Notify(context.Background(), Message{EventID: "e1", EventType: "order.created", To: "user-7@example.com"})
+	var derr *DeliveryError
+	if !errors.As(err, &derr) || derr.Channel != "broken" {
+		t.Fatalf("Notify = %v, want a DeliveryError for broken", err)
+	}
+	if len(f.log.sent) != 1 {
+		t.Errorf("log got %d messages; a failing channel must not stop the others", len(f.log.sent))
+	}
+	if len(f.recorder.deliveries) != 2 || f.recorder.deliveries[0].Err == nil {
+		t.Errorf("recorded %+v, want the failed render recorded", f.recorder.deliveries)
+	}
+}
+
+func TestRouterPicksMostSpecificRoute(t *testing.T) {
+	f := newRouterFixture(t, "*=log;inventory.*=webhook;inventory.back_in_stock=smtp")
+
+	tests := []struct {
+		eventType string
+		channels  []string
+	}{
+		{"inventory.back_in_stock", []string{"smtp"}},
+		{"inventory.low_stock", []string{"webhook"}},
+		{"order.created", []string{"log"}},
+	}
+	for _, tt := range tests {
+		if got := f.router.ChannelsFor(tt.eventType); !reflect.DeepEqual(got, tt.channels) {
+			t.Errorf("ChannelsFor(%q) = %v, want %v", tt.eventType, got, tt.channels)
+		}
+	}
+}
+
+func TestRouterDedupsByEventRecipientAndChannel(t *testing.T) {
+	f := newRouterFixture(t, "order.*=smtp,webhook")
+	ctx := context.Background()
+	msg := Message{EventID: "order-service/event_logs/42", EventType: "order.created", To: "user-7@example.com"}
+
+	// The webhook fails the first time: only smtp is marked delivered.
+	f.webhook.err = errors.New("connection refused")
+	if err := f.router.Notify(ctx, msg); err == nil {
+		t.Fatal("Notify succeeded with a failing channel")
+	}
+	f.webhook.err = nil
+
+	// The redelivered event only goes out on the channel that failed.
+	if err := f.router.Notify(ctx, msg); err != nil {
+		t.Fatalf("redelivered Notify: %v", err)
+	}
+	if len(f.smtp.sent) != 1 || len(f.webhook.sent) != 1 {
+		t.Fatalf("smtp got %d, webhook got %d messages; want 1 each", len(f.smtp.sent), len(f.webhook.sent))
+	}
+
+	// Once every channel delivered, the event is not sent again.
+	if err := f.router.Notify(ctx, msg); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(f.smtp.sent) != 1 || len(f.webhook.sent) != 1 {
+		t.Errorf("duplicate event was sent again: smtp %d, webhook %d", len(f.smtp.sent), len(f.webhook.sent))
+	}
+
+	// The same event to another recipient is a different delivery.
+	other := msg
+	other.To = "user-9@example.com"
+	if err := f.router.Notify(ctx, other); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(f.smtp.sent) != 2 || len(f.webhook.sent) != 2 {
+		t.Errorf("other recipient: smtp %d, webhook %d messages; want 2 each", len(f.smtp.sent), len(f.webhook.sent))
+	}
+
+	want := map[DeliveryKey]bool{
+		{EventID: msg.EventID, Recipient: "user-7@example.com", Channel: "smtp"}:    true,
+		{EventID: msg.EventID, Recipient: "user-7@example.com", Channel: "webhook"}: true,
+		{EventID: msg.EventID, Recipient: "user-9@example.com", Channel: "smtp"}:    true,
+		{EventID: msg.EventID, Recipient: "user-9@example.com", Channel: "webhook"}: true,
+	}
+	if !reflect.DeepEqual(f.deduper.delivered, want) {
+		t.Errorf("delivered keys = %v, want %v", f.deduper.delivered, want)
+	}
+}
+
+func TestRouterWithoutEventIDSkipsDedup(t *testing.T) {
+	f := newRouterFixture(t, "order.*=log")
+	msg := Message{EventType: "order.created", To: "user-7@example.com"}
+
+	for i := 0; i < 2; i++ {
+		if err := f.router.Notify(context.Background(), msg); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+	if len(f.log.sent) != 2 || len(f.deduper.delivered) != 0 {
+		t.Errorf("sent %d, marked %d; want every message sent and none marked", len(f.log.sent), len(f.deduper.delivered))
+	}
+}
+
+func TestRouterChannelOverrides(t *testing.T) {
+	f := newRouterFixture(t, "order.*=smtp,log")
+	msg := Message{
+		EventType: "order.created",
+		To:        "user-7@example.com",
+		Channels:  map[string]bool{"smtp": false, "webhook": true, "sms": true},
+	}
+	if err := f.router.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(f.smtp.sent) != 0 || len(f.log.sent) != 1 || len(f.webhook.sent) != 1 {
+		t.Errorf("smtp %d, log %d, webhook %d; want 0, 1, 1", len(f.smtp.sent), len(f.log.sent), len(f.webhook.sent))
+	}
+}
+
+func TestNewRouterRejectsUnknownChannel(t *testing.T) {
+	if _, err := NewRouter([]Route{{Pattern: "*", Channels: []string{"pigeon"}}}, map[string]Notifier{}, channelRenderer{}, nil, nil, zerolog.Nop()); err == nil {
+		t.Fatal("NewRouter accepted a route to an unconfigured channel")
+	}
+}
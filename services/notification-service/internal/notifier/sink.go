@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LogNotifier writes notifications to the service log instead of sending
+// them. It is the development default.
+type LogNotifier struct {
+	log zerolog.Logger
+}
+
+func NewLogNotifier(log zerolog.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+func (n *LogNotifier) Name() string { return "log" }
+
+func (n *LogNotifier) Notify(_ context.Context, msg Message) error {
+	n.log.Info().
+		Str("channel", n.Name()).
+		Str("event_type", msg.EventType).
+		Str("to", msg.To).
+		Int("user_id", msg.UserID).
+		Str("subject", msg.Subject).
+		Msg("📨 Notification delivered")
+	return nil
+}
+
+// FileNotifier appends each notification as one JSON line, which makes a
+// handy sink for local runs and for asserting on output.
+type FileNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewFileNotifier(w io.Writer) *FileNotifier {
+	return &FileNotifier{w: w}
+}
+
+// OpenFileNotifier appends to the file at path, creating it if needed.
+func OpenFileNotifier(path string) (*FileNotifier, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open notification file: %w", err)
+	}
+	return NewFileNotifier(f), f, nil
+}
+
+func (n *FileNotifier) Name() string { return "file" }
+
+func (n *FileNotifier) Notify(_ context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{Message: msg, SentAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// SMTPNotifier sends plain-text email through an SMTP relay. It upgrades to
+// TLS when the server offers STARTTLS and authenticates only when a username
+// is configured, so it works unchanged against MailHog or an in-process test
+// server listening on localhost.
+type SMTPNotifier struct {
+	cfg  SMTPConfig
+	addr string
+}
+
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg, addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))}
+}
+
+func (n *SMTPNotifier) Name() string { return "smtp" }
+
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	_, err := n.Send(ctx, msg)
+	return err
+}
+
+// Send delivers msg and returns the server's final reply, which usually
+// carries the relay's queue id.
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) (string, error) {
+	if msg.To == "" {
+		return "", fmt.Errorf("no recipient address for %s", msg.EventType)
+	}
+
+	if n.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.cfg.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", n.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return "", fmt.Errorf("starttls failed: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		auth := smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return "", fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.cfg.From); err != nil {
+		return "", fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return "", fmt.Errorf("smtp RCPT TO failed: %w", err)
+	}
+	reply, err := data(client.Text, n.compose(msg))
+	if err != nil {
+		return "", err
+	}
+	// The message is accepted at this point; a failed QUIT does not undo it.
+	_ = client.Quit()
+	return reply, nil
+}
+
+// data runs the DATA command by hand because smtp.Client discards the reply
+// to the final ".", which is the provider response worth keeping.
+func data(text *textproto.Conn, body []byte) (string, error) {
+	id, err := text.Cmd("DATA")
+	if err != nil {
+		return "", fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	text.StartResponse(id)
+	_, _, err = text.ReadResponse(354)
+	text.EndResponse(id)
+	if err != nil {
+		return "", fmt.Errorf("smtp DATA failed: %w", err)
+	}
+
+	w := text.DotWriter()
+	if _, err := w.Write(body); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	code, reply, err := text.ReadResponse(250)
+	if err != nil {
+		return "", fmt.Errorf("smtp server rejected message: %w", err)
+	}
+	return fmt.Sprintf("%d %s", code, reply), nil
+}
+
+// compose builds the RFC 5322 message: plain text, or multipart/alternative
+// with a text and an HTML part when the message has an HTML body.
+func (n *SMTPNotifier) compose(msg Message) []byte {
+	var b bytes.Buffer
+	header := func(key, value string) {
+		b.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", n.cfg.From)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().UTC().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("X-Event-Type", msg.EventType)
+	if msg.UnsubscribeURL != "" {
+		header("List-Unsubscribe", "<"+msg.UnsubscribeURL+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+
+	if msg.HTMLBody == "" {
+		header("Content-Type", "text/plain; charset=UTF-8")
+		header("Content-Transfer-Encoding", "8bit")
+		b.WriteString("\r\n")
+		b.WriteString(crlf(msg.Body))
+		return b.Bytes()
+	}
+
+	mw := multipart.NewWriter(&b)
+	header("Content-Type", `multipart/alternative; boundary="`+mw.Boundary()+`"`)
+	b.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Body},
+		{"text/html; charset=UTF-8", msg.HTMLBody},
+	} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		_, _ = w.Write([]byte(crlf(part.body)))
+	}
+	_ = mw.Close()
+	return b.Bytes()
+}
+
+// crlf normalises line endings to CRLF and ends the text with one.
+func crlf(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n") + "\r\n"
+}
@@ -0,0 +1,244 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what the stand-in server received in one session.
+type smtpSession struct {
+	from string
+	rcpt []string
+	data []byte
+}
+
+// smtpServer is a minimal SMTP server on localhost: it speaks just enough of
+// the protocol for net/smtp, offers no STARTTLS or AUTH, and rejects
+// recipients listed in reject.
+type smtpServer struct {
+	addr     string
+	reject   map[string]bool
+	sessions chan smtpSession
+}
+
+func startSMTPServer(t *testing.T, reject ...string) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String(), reject: map[string]bool{}, sessions: make(chan smtpSession, 1)}
+	for _, r := range reject {
+		s.reject[r] = true
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var session smtpSession
+	reply := func(format string, args ...interface{}) { _ = text.PrintfLine(format, args...) }
+
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "MAIL":
+			// net/smtp adds BODY=8BITMIME after the address.
+			from, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			session.from = strings.Trim(from, "<>")
+			reply("250 2.1.0 Ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if s.reject[to] {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			session.rcpt = append(session.rcpt, to)
+			reply("250 2.1.5 Ok")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			// ReadDotBytes undoes dot-stuffing and turns CRLF into LF.
+			if session.data, err = text.ReadDotBytes(); err != nil {
+				return
+			}
+			reply("250 2.0.0 Ok: queued as 4F2A9C")
+			s.sessions <- session
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (s *smtpServer) notifier(t *testing.T) *SMTPNotifier {
+	t.Helper()
+	host, port, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := net.LookupPort("tcp", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSMTPNotifier(SMTPConfig{Host: host, Port: p, From: "shop@example.com", Timeout: 5 * time.Second})
+}
+
+func (s *smtpServer) session(t *testing.T) smtpSession {
+	t.Helper()
+	select {
+	case session := <-s.sessions:
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server received no message")
+		return smtpSession{}
+	}
+}
+
+func TestSMTPNotifierSendsPlainText(t *testing.T) {
+	server := startSMTPServer(t)
+	n := server.notifier(t)
+
+	reply, err := n.Send(context.Background(), Message{
+		EventType:      "inventory.back_in_stock",
+		To:             "user-7@example.com",
+		Subject:        "Black Mug 350ml is back — önce gelen alır",
+		Body:           "Good news!\nIt is back in stock.",
+		UnsubscribeURL: "https://shop.example.com/unsubscribe?token=abc",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if reply != "250 2.0.0 Ok: queued as 4F2A9C" {
+		t.Errorf("reply = %q, want the server's final reply", reply)
+	}
+
+	session := server.session(t)
+	if session.from != "shop@example.com" || len(session.rcpt) != 1 || session.rcpt[0] != "user-7@example.com" {
+		t.Errorf("envelope = %q -> %q, want shop@example.com -> user-7@example.com", session.from, session.rcpt)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(string(session.data)))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("subject does not decode: %v", err)
+	}
+	headers := map[string]string{
+		"From":                  "shop@example.com",
+		"To":                    "user-7@example.com",
+		"MIME-Version":          "1.0",
+		"Content-Type":          "text/plain; charset=UTF-8",
+		"X-Event-Type":          "inventory.back_in_stock",
+		"List-Unsubscribe":      "<https://shop.example.com/unsubscribe?token=abc>",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	for key, want := range headers {
+		if got := m.Header.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if subject != "Black Mug 350ml is back — önce gelen alır" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if _, err := m.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	body, _ := io.ReadAll(m.Body)
+	if string(body) != "Good news!\nIt is back in stock.\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSMTPNotifierSendsHTMLAlternative(t *testing.T) {
+	server := startSMTPServer(t)
+	n := server.notifier(t)
+
+	if err := n.Notify(context.Background(), Message{
+		EventType: "order.created",
+		To:        "user-7@example.com",
+		Subject:   "Order #1042 confirmed",
+		Body:      "Thanks for your order.",
+		HTMLBody:  "<p>Thanks for your order.</p>",
+	}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(string(server.session(t).data)))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	if m.Header.Get("List-Unsubscribe") != "" {
+		t.Error("List-Unsubscribe set without an unsubscribe URL")
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", m.Header.Get("Content-Type"))
+	}
+
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", "Thanks for your order.\n"},
+		{"text/html; charset=UTF-8", "<p>Thanks for your order.</p>\n"},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", want.contentType, err)
+		}
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Type") != want.contentType || string(body) != want.body {
+			t.Errorf("part %q = %q, want %q = %q", part.Header.Get("Content-Type"), body, want.contentType, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("unexpected extra part: %v", err)
+	}
+}
+
+func TestSMTPNotifierRejectedRecipient(t *testing.T) {
+	server := startSMTPServer(t, "nobody@example.com")
+	n := server.notifier(t)
+
+	_, err := n.Send(context.Background(), Message{EventType: "order.created", To: "nobody@example.com", Body: "x"})
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO") {
+		t.Fatalf("Send = %v, want a RCPT TO error", err)
+	}
+}
+
+func TestSMTPNotifierRequiresRecipient(t *testing.T) {
+	n := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: 1})
+	if _, err := n.Send(context.Background(), Message{EventType: "order.created"}); err == nil {
+		t.Fatal("Send without a recipient succeeded")
+	}
+}
@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WebhookNotifier POSTs each notification as JSON to a fixed URL. Any
+// non-2xx response counts as a failed delivery.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier posts to url with client; pass an http.Client with a
+// timeout, since notifications are sent from the consumer loop.
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: client}
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	_, err := n.Send(ctx, msg)
+	return err
+}
+
+// Send posts msg and returns the response status line followed by the start
+// of the response body.
+func (n *WebhookNotifier) Send(ctx context.Context, msg Message) (string, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", msg.EventType)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	response := resp.Status
+	if b := strings.TrimSpace(string(body)); b != "" {
+		response += ": " + b
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return response, fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return response, nil
+}
@@ -0,0 +1,154 @@
+// Package preference decides how, when and whether a user is notified,
+// based on the user's stored notification preferences.
+package preference
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+)
+
+// Event categories users can set channel preferences for. Events to ops,
+// such as stock alerts, have no category and ignore preferences.
+const (
+	CategoryOrders      = "orders"
+	CategoryBackInStock = "back_in_stock"
+)
+
+var Categories = []string{CategoryOrders, CategoryBackInStock}
+
+func CategoryFor(eventType string) string {
+	switch {
+	case strings.HasPrefix(eventType, "order."):
+		return CategoryOrders
+	case eventType == events.TypeBackInStock:
+		return CategoryBackInStock
+	default:
+		return ""
+	}
+}
+
+func IsCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Decision is what to do with one message to one user.
+type Decision struct {
+	// Unsubscribed users get nothing.
+	Unsubscribed bool
+	// Channels overrides routing per channel, see notifier.Message.Channels.
+	Channels map[string]bool
+	// HoldUntil is set while the user is in quiet hours; the message is
+	// delivered when they end.
+	HoldUntil time.Time
+	// DigestWindow is set for order events to a user in digest mode: the
+	// event goes into the user's digest, which is sent when the window ends
+	// or, if DigestMaxItems is set, once it holds that many events.
+	DigestWindow   time.Duration
+	DigestMaxItems int
+}
+
+// Decide applies p to a message of eventType at now.
+func Decide(p *repository.Preferences, eventType string, now time.Time) (Decision, error) {
+	if p.UnsubscribedAt != nil {
+		return Decision{Unsubscribed: true}, nil
+	}
+
+	var d Decision
+	category := CategoryFor(eventType)
+	for _, c := range p.Channels {
+		if c.Category != category {
+			continue
+		}
+		if d.Channels == nil {
+			d.Channels = make(map[string]bool)
+		}
+		d.Channels[c.Channel] = c.Enabled
+	}
+
+	if p.DigestWindow != nil && category == CategoryOrders && eventType != model.OrderDigestType {
+		window, err := ParseDigestWindow(*p.DigestWindow)
+		if err != nil {
+			return Decision{}, err
+		}
+		d.DigestWindow = window
+		if p.DigestMaxItems != nil {
+			d.DigestMaxItems = *p.DigestMaxItems
+		}
+	}
+
+	until, quiet, err := QuietUntil(p, now)
+	if err != nil {
+		return Decision{}, err
+	}
+	if quiet {
+		d.HoldUntil = until
+	}
+	return d, nil
+}
+
+// QuietUntil reports whether now falls in the user's quiet hours and, if
+// so, when they end. Quiet hours are wall-clock times in the user's time
+// zone and may span midnight, e.g. 22:00 to 07:00.
+func QuietUntil(p *repository.Preferences, now time.Time) (time.Time, bool, error) {
+	if p.QuietStart == nil || p.QuietEnd == nil {
+		return time.Time{}, false, nil
+	}
+	start, err := ParseClock(*p.QuietStart)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	end, err := ParseClock(*p.QuietEnd)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	var quiet bool
+	if start <= end {
+		quiet = minute >= start && minute < end
+	} else {
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}, false, nil
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true, nil
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseDigestWindow parses a digest window such as "30m" or "4h".
+func ParseDigestWindow(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid digest window %q, expected a positive duration such as 1h", s)
+	}
+	return d, nil
+}
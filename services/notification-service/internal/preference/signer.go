@@ -0,0 +1,41 @@
+package preference
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Signer creates and checks unsubscribe links. A token is an HMAC of the
+// user ID, so links need no storage and cannot be forged for another user;
+// rotating the secret invalidates every link already sent.
+type Signer struct {
+	secret  []byte
+	baseURL string
+}
+
+// NewSigner signs with secret; baseURL is where the service's HTTP API is
+// reachable from a recipient's mail client.
+func NewSigner(secret, baseURL string) *Signer {
+	return &Signer{secret: []byte(secret), baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (s *Signer) Token(userID int) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("unsubscribe:" + strconv.Itoa(userID)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) Verify(userID int, token string) bool {
+	return hmac.Equal([]byte(token), []byte(s.Token(userID)))
+}
+
+func (s *Signer) UnsubscribeURL(userID int) string {
+	q := url.Values{}
+	q.Set("user_id", strconv.Itoa(userID))
+	q.Set("token", s.Token(userID))
+	return s.baseURL + "/unsubscribe?" + q.Encode()
+}
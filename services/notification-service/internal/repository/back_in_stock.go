@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BackInStockRepository remembers which users were sent a back-in-stock
+// notification for a product. It is shared by every replica and survives
+// restarts, so a redelivered event never notifies a user twice.
+type BackInStockRepository interface {
+	// Claim records a notification to userID about productID unless one was
+	// recorded less than window ago, and reports whether it did.
+	Claim(ctx context.Context, productID int64, userID int, window time.Duration) (bool, error)
+	// Release forgets a claim whose notification could not be delivered.
+	Release(ctx context.Context, productID int64, userID int) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type PostgresBackInStockRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresBackInStockRepository(db *sqlx.DB) *PostgresBackInStockRepository {
+	return &PostgresBackInStockRepository{db: db}
+}
+
+func (r *PostgresBackInStockRepository) Claim(ctx context.Context, productID int64, userID int, window time.Duration) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO back_in_stock_sent (product_id, user_id, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (product_id, user_id) DO UPDATE
+		SET sent_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE back_in_stock_sent.expires_at <= NOW()
+	`, productID, userID, window.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim back-in-stock notification: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim back-in-stock notification: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (r *PostgresBackInStockRepository) Release(ctx context.Context, productID int64, userID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM back_in_stock_sent WHERE product_id = $1 AND user_id = $2`, productID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to release back-in-stock notification: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBackInStockRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM back_in_stock_sent WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge back-in-stock notifications: %w", err)
+	}
+	return res.RowsAffected()
+}
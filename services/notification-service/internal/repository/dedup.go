@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/jmoiron/sqlx"
+)
+
+// Expiring is a store of dedup entries that expire.
+type Expiring interface {
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// DedupRepository implements notifier.Deduper. Entries expire after the
+// TTL, which only has to outlast the broker's redeliveries.
+type DedupRepository interface {
+	Delivered(ctx context.Context, key notifier.DeliveryKey) (bool, error)
+	MarkDelivered(ctx context.Context, key notifier.DeliveryKey) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type PostgresDedupRepository struct {
+	db  *sqlx.DB
+	ttl time.Duration
+}
+
+func NewPostgresDedupRepository(db *sqlx.DB, ttl time.Duration) *PostgresDedupRepository {
+	return &PostgresDedupRepository{db: db, ttl: ttl}
+}
+
+func (r *PostgresDedupRepository) Delivered(ctx context.Context, key notifier.DeliveryKey) (bool, error) {
+	var delivered bool
+	err := r.db.GetContext(ctx, &delivered, `
+		SELECT EXISTS (
+			SELECT 1 FROM delivered_events
+			WHERE event_id = $1 AND recipient = $2 AND channel = $3 AND expires_at > NOW()
+		)
+	`, key.EventID, key.Recipient, key.Channel)
+	if err != nil {
+		return false, fmt.Errorf("failed to check delivered event: %w", err)
+	}
+	return delivered, nil
+}
+
+func (r *PostgresDedupRepository) MarkDelivered(ctx context.Context, key notifier.DeliveryKey) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO delivered_events (event_id, recipient, channel, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (event_id, recipient, channel) DO UPDATE
+		SET delivered_at = NOW(), expires_at = EXCLUDED.expires_at
+	`, key.EventID, key.Recipient, key.Channel, r.ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to mark event delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresDedupRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM delivered_events WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge delivered events: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	DigestOpen     = "open"
+	DigestFlushing = "flushing"
+	DigestSent     = "sent"
+)
+
+// DigestRepository collects the events of users in digest mode until their
+// digest is flushed as one notification.
+type DigestRepository interface {
+	AddToDigest(ctx context.Context, item DigestItem, recipient string, window time.Duration) (*Digest, bool, error)
+	ClaimDigest(ctx context.Context, id int64, lease time.Duration) (*Digest, error)
+	DueDigests(ctx context.Context, limit int, lease time.Duration) ([]Digest, error)
+	DigestItems(ctx context.Context, digestID int64) ([]DigestItem, error)
+	MarkDigestSent(ctx context.Context, id int64) error
+	RetryDigest(ctx context.Context, id int64, retryAt time.Time, flushErr error) error
+	PurgeSentDigests(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+// Digest is one user's batch of events. An open digest takes new events; a
+// flushing one is being sent, or waits for FlushAt to be retried.
+type Digest struct {
+	ID        int64      `db:"id" json:"id"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	Recipient string     `db:"recipient" json:"recipient"`
+	Status    string     `db:"status" json:"status"`
+	ItemCount int        `db:"item_count" json:"item_count"`
+	Attempts  int        `db:"attempts" json:"attempts"`
+	LastError string     `db:"last_error" json:"last_error"`
+	OpenedAt  time.Time  `db:"opened_at" json:"opened_at"`
+	FlushAt   time.Time  `db:"flush_at" json:"flush_at"`
+	SentAt    *time.Time `db:"sent_at" json:"sent_at"`
+}
+
+// DigestItem is an unrendered event in a digest.
+type DigestItem struct {
+	ID        int64           `db:"id" json:"id"`
+	DigestID  int64           `db:"digest_id" json:"digest_id"`
+	UserID    int             `db:"user_id" json:"user_id"`
+	EventID   string          `db:"event_id" json:"event_id"`
+	EventType string          `db:"event_type" json:"event_type"`
+	OrderID   *int            `db:"order_id" json:"order_id"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+const (
+	digestColumns     = `id, user_id, recipient, status, item_count, attempts, last_error, opened_at, flush_at, sent_at`
+	digestItemColumns = `id, digest_id, user_id, event_id, event_type, order_id, payload, created_at`
+)
+
+type PostgresDigestRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresDigestRepository(db *sqlx.DB) *PostgresDigestRepository {
+	return &PostgresDigestRepository{db: db}
+}
+
+// AddToDigest adds item to the user's open digest, opening one that flushes
+// after window if there is none, and returns the digest. It reports false,
+// and changes nothing, if the event is already in one of the user's digests.
+func (r *PostgresDigestRepository) AddToDigest(ctx context.Context, item DigestItem, recipient string, window time.Duration) (*Digest, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin digest transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialises opening a digest per user. The row lock on the open digest
+	// keeps it from being flushed before this item is in.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('notification_digests'), $1)`, item.UserID); err != nil {
+		return nil, false, fmt.Errorf("failed to lock user digest: %w", err)
+	}
+
+	var d Digest
+	err = tx.GetContext(ctx, &d, `
+		SELECT `+digestColumns+` FROM notification_digests
+		WHERE user_id = $1 AND status = 'open'
+		FOR UPDATE
+	`, item.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.GetContext(ctx, &d, `
+			INSERT INTO notification_digests (user_id, recipient, flush_at)
+			VALUES ($1, $2, NOW() + make_interval(secs => $3))
+			RETURNING `+digestColumns, item.UserID, recipient, window.Seconds())
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open digest: %w", err)
+	}
+
+	// Passed as text: lib/pq would send []byte as bytea, which JSONB rejects.
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO notification_digest_items (digest_id, user_id, event_id, event_type, order_id, payload)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, event_id) DO NOTHING
+	`, d.ID, item.UserID, item.EventID, item.EventType, item.OrderID, string(item.Payload))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to add digest item: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, false, nil
+	}
+
+	err = tx.GetContext(ctx, &d, `
+		UPDATE notification_digests SET item_count = item_count + 1
+		WHERE id = $1
+		RETURNING `+digestColumns, d.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to count digest item: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit digest item: %w", err)
+	}
+	return &d, true, nil
+}
+
+// ClaimDigest closes open digest id for flushing, e.g. because it is full.
+// It returns nil if the digest is no longer open.
+func (r *PostgresDigestRepository) ClaimDigest(ctx context.Context, id int64, lease time.Duration) (*Digest, error) {
+	var d Digest
+	err := r.db.GetContext(ctx, &d, `
+		UPDATE notification_digests
+		SET status = 'flushing', flush_at = NOW() + make_interval(secs => $2)
+		WHERE id = $1 AND status = 'open'
+		RETURNING `+digestColumns, id, lease.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim digest: %w", err)
+	}
+	return &d, nil
+}
+
+// DueDigests claims up to limit digests whose window has ended, or whose
+// flush is due to be retried. Claimed digests are not due again until the
+// lease has passed, so a flush that dies halfway is retried.
+func (r *PostgresDigestRepository) DueDigests(ctx context.Context, limit int, lease time.Duration) ([]Digest, error) {
+	list := []Digest{}
+	err := r.db.SelectContext(ctx, &list, `
+		UPDATE notification_digests
+		SET status = 'flushing', flush_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM notification_digests
+			WHERE status <> 'sent' AND flush_at <= NOW()
+			ORDER BY flush_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+digestColumns, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due digests: %w", err)
+	}
+	return list, nil
+}
+
+// DigestItems returns the digest's events in the order they arrived.
+func (r *PostgresDigestRepository) DigestItems(ctx context.Context, digestID int64) ([]DigestItem, error) {
+	list := []DigestItem{}
+	query := `SELECT ` + digestItemColumns + ` FROM notification_digest_items WHERE digest_id = $1 ORDER BY id`
+	if err := r.db.SelectContext(ctx, &list, query, digestID); err != nil {
+		return nil, fmt.Errorf("list digest items failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresDigestRepository) MarkDigestSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notification_digests
+		SET status = 'sent', sent_at = NOW(), attempts = attempts + 1, last_error = ''
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark digest sent: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresDigestRepository) RetryDigest(ctx context.Context, id int64, retryAt time.Time, flushErr error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notification_digests
+		SET flush_at = $2, attempts = attempts + 1, last_error = $3
+		WHERE id = $1
+	`, id, retryAt.UTC(), flushErr.Error())
+	if err != nil {
+		return fmt.Errorf("failed to reschedule digest: %w", err)
+	}
+	return nil
+}
+
+// PurgeSentDigests deletes digests sent longer ago than olderThan, with their
+// items. Items are kept until then so that a redelivered event is not added
+// to a new digest.
+func (r *PostgresDigestRepository) PurgeSentDigests(ctx context.Context, olderThan time.Duration) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM notification_digests
+		WHERE status = 'sent' AND sent_at < NOW() - make_interval(secs => $1)
+	`, olderThan.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge sent digests: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HeldRepository keeps messages held back by a user's quiet hours until
+// they are due.
+type HeldRepository interface {
+	Hold(ctx context.Context, n HeldNotification) error
+	DueHeld(ctx context.Context, limit int) ([]HeldNotification, error)
+	DeleteHeld(ctx context.Context, id int64) error
+	RetryHeld(ctx context.Context, id int64, retryAt time.Time, deliveryErr error) error
+}
+
+// HeldNotification is an unrendered message: rendering waits until delivery
+// so that it uses the templates and preferences of that moment.
+type HeldNotification struct {
+	ID        int64           `db:"id" json:"id"`
+	EventID   string          `db:"event_id" json:"event_id"`
+	EventType string          `db:"event_type" json:"event_type"`
+	Recipient string          `db:"recipient" json:"recipient"`
+	UserID    int             `db:"user_id" json:"user_id"`
+	OrderID   *int            `db:"order_id" json:"order_id"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	DeliverAt time.Time       `db:"deliver_at" json:"deliver_at"`
+	Attempts  int             `db:"attempts" json:"attempts"`
+	LastError string          `db:"last_error" json:"last_error"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+const heldColumns = `id, event_id, event_type, recipient, user_id, order_id, payload, deliver_at, attempts, last_error, created_at`
+
+type PostgresHeldRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresHeldRepository(db *sqlx.DB) *PostgresHeldRepository {
+	return &PostgresHeldRepository{db: db}
+}
+
+func (r *PostgresHeldRepository) Hold(ctx context.Context, n HeldNotification) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO held_notifications (event_id, event_type, recipient, user_id, order_id, payload, deliver_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, n.EventID, n.EventType, n.Recipient, n.UserID, n.OrderID, string(n.Payload), n.DeliverAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to hold notification: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresHeldRepository) DueHeld(ctx context.Context, limit int) ([]HeldNotification, error) {
+	query := `SELECT ` + heldColumns + ` FROM held_notifications WHERE deliver_at <= NOW() ORDER BY deliver_at, id LIMIT $1`
+
+	list := []HeldNotification{}
+	if err := r.db.SelectContext(ctx, &list, query, limit); err != nil {
+		return nil, fmt.Errorf("list due notifications failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresHeldRepository) DeleteHeld(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM held_notifications WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete held notification: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresHeldRepository) RetryHeld(ctx context.Context, id int64, retryAt time.Time, deliveryErr error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE held_notifications
+		SET deliver_at = $2, attempts = attempts + 1, last_error = $3
+		WHERE id = $1
+	`, id, retryAt.UTC(), deliveryErr.Error())
+	if err != nil {
+		return fmt.Errorf("failed to reschedule held notification: %w", err)
+	}
+	return nil
+}
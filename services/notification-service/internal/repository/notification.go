@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationRepository is the delivery history. It implements
+// notifier.Recorder, so the Router stores every attempt through it.
+type NotificationRepository interface {
+	Record(ctx context.Context, d notifier.Delivery) error
+	GetNotification(ctx context.Context, id int64) (*Notification, error)
+	ListNotifications(ctx context.Context, filter NotificationFilter) ([]Notification, error)
+	RecordAttempt(ctx context.Context, id int64, response string, deliveryErr error) (*Notification, error)
+}
+
+// Notification is one message on one channel: who it went to, what it said
+// and how the provider answered the latest attempt.
+type Notification struct {
+	ID               int64           `db:"id" json:"id"`
+	EventID          string          `db:"event_id" json:"event_id"`
+	EventType        string          `db:"event_type" json:"event_type"`
+	Channel          string          `db:"channel" json:"channel"`
+	Recipient        string          `db:"recipient" json:"recipient"`
+	UserID           *int            `db:"user_id" json:"user_id"`
+	OrderID          *int            `db:"order_id" json:"order_id"`
+	Template         string          `db:"template" json:"template"`
+	Locale           string          `db:"locale" json:"locale"`
+	Subject          string          `db:"subject" json:"subject"`
+	Body             string          `db:"body" json:"body"`
+	HTMLBody         string          `db:"html_body" json:"html_body,omitempty"`
+	Payload          json.RawMessage `db:"payload" json:"payload"`
+	Status           string          `db:"status" json:"status"`
+	Attempts         int             `db:"attempts" json:"attempts"`
+	ProviderResponse string          `db:"provider_response" json:"provider_response"`
+	LastError        string          `db:"last_error" json:"last_error"`
+	CreatedAt        time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time       `db:"updated_at" json:"updated_at"`
+	SentAt           *time.Time      `db:"sent_at" json:"sent_at"`
+}
+
+// Message rebuilds the rendered message, e.g. to resend it unchanged.
+func (n *Notification) Message() notifier.Message {
+	msg := notifier.Message{
+		EventID:   n.EventID,
+		EventType: n.EventType,
+		To:        n.Recipient,
+		Locale:    n.Locale,
+		Subject:   n.Subject,
+		Body:      n.Body,
+		HTMLBody:  n.HTMLBody,
+	}
+	if n.UserID != nil {
+		msg.UserID = *n.UserID
+	}
+	if n.OrderID != nil {
+		msg.OrderID = *n.OrderID
+	}
+	if len(n.Payload) > 0 {
+		msg.Data = n.Payload
+	}
+	return msg
+}
+
+// NotificationFilter narrows ListNotifications; zero values match anything.
+type NotificationFilter struct {
+	UserID  *int
+	OrderID *int
+	Status  string
+	Limit   int
+}
+
+const notificationColumns = `id, event_id, event_type, channel, recipient, user_id, order_id, template, locale,
+	subject, body, html_body, payload, status, attempts, provider_response, last_error,
+	created_at, updated_at, sent_at`
+
+type PostgresNotificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresNotificationRepository(db *sqlx.DB) *PostgresNotificationRepository {
+	return &PostgresNotificationRepository{db: db}
+}
+
+func (r *PostgresNotificationRepository) Record(ctx context.Context, d notifier.Delivery) error {
+	// Passed as text: lib/pq would send []byte as bytea, which JSONB rejects.
+	var payload *string
+	if d.Message.Data != nil {
+		p, err := json.Marshal(d.Message.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification payload: %w", err)
+		}
+		s := string(p)
+		payload = &s
+	}
+	status, lastError := deliveryStatus(d.Err)
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO notifications (
+			event_type, channel, recipient, user_id, order_id, template, locale,
+			subject, body, html_body, payload, status, provider_response, last_error, event_id, sent_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+			CASE WHEN $12 = 'sent' THEN NOW() END
+		)
+	`, d.Message.EventType, d.Channel, d.Message.To, nullableID(d.Message.UserID), nullableID(d.Message.OrderID),
+		d.Template, d.Message.Locale, d.Message.Subject, d.Message.Body, d.Message.HTMLBody, payload,
+		status, d.Response, lastError, d.Message.EventID)
+	if err != nil {
+		return fmt.Errorf("insert notification failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresNotificationRepository) GetNotification(ctx context.Context, id int64) (*Notification, error) {
+	var n Notification
+	err := r.db.GetContext(ctx, &n, `SELECT `+notificationColumns+` FROM notifications WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get notification failed: %w", err)
+	}
+	return &n, nil
+}
+
+// ListNotifications returns the newest notifications first.
+func (r *PostgresNotificationRepository) ListNotifications(ctx context.Context, filter NotificationFilter) ([]Notification, error) {
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notifications
+		WHERE ($1::int IS NULL OR user_id = $1)
+		  AND ($2::int IS NULL OR order_id = $2)
+		  AND ($3 = '' OR status = $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	list := []Notification{}
+	if err := r.db.SelectContext(ctx, &list, query, filter.UserID, filter.OrderID, filter.Status, filter.Limit); err != nil {
+		return nil, fmt.Errorf("list notifications failed: %w", err)
+	}
+	return list, nil
+}
+
+// RecordAttempt stores the outcome of another attempt at notification id.
+// The provider response and error always describe the latest attempt.
+func (r *PostgresNotificationRepository) RecordAttempt(ctx context.Context, id int64, response string, deliveryErr error) (*Notification, error) {
+	status, lastError := deliveryStatus(deliveryErr)
+
+	var n Notification
+	err := r.db.GetContext(ctx, &n, `
+		UPDATE notifications
+		SET status = $2,
+			attempts = attempts + 1,
+			provider_response = $3,
+			last_error = $4,
+			sent_at = CASE WHEN $2 = 'sent' THEN NOW() ELSE sent_at END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+notificationColumns, id, status, response, lastError)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update notification failed: %w", err)
+	}
+	return &n, nil
+}
+
+func deliveryStatus(err error) (string, string) {
+	if err != nil {
+		return StatusFailed, err.Error()
+	}
+	return StatusSent, ""
+}
+
+// nullableID stores the zero ID of a message without a user or order as NULL.
+func nullableID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
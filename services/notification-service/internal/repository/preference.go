@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PreferenceRepository interface {
+	GetPreferences(ctx context.Context, userID int) (*Preferences, error)
+	SavePreferences(ctx context.Context, p *Preferences) (*Preferences, error)
+	Unsubscribe(ctx context.Context, userID int) (*Preferences, error)
+}
+
+// Preferences are a user's notification settings. A user without a stored
+// row gets the defaults: every routed channel, no quiet hours, subscribed.
+type Preferences struct {
+	UserID         int                 `db:"user_id" json:"user_id"`
+	Locale         string              `db:"locale" json:"locale"`
+	Timezone       string              `db:"timezone" json:"timezone"`
+	QuietStart     *string             `db:"quiet_start" json:"quiet_start"`
+	QuietEnd       *string             `db:"quiet_end" json:"quiet_end"`
+	UnsubscribedAt *time.Time          `db:"unsubscribed_at" json:"unsubscribed_at"`
+	DigestWindow   *string             `db:"digest_window" json:"digest_window"`
+	DigestMaxItems *int                `db:"digest_max_items" json:"digest_max_items"`
+	UpdatedAt      *time.Time          `db:"updated_at" json:"updated_at"`
+	Channels       []ChannelPreference `db:"-" json:"channels"`
+}
+
+// ChannelPreference turns a channel on or off for one event category,
+// overriding the service's routing for that user.
+type ChannelPreference struct {
+	Category string `db:"category" json:"category"`
+	Channel  string `db:"channel" json:"channel"`
+	Enabled  bool   `db:"enabled" json:"enabled"`
+}
+
+func DefaultPreferences(userID int) *Preferences {
+	return &Preferences{UserID: userID, Timezone: "UTC", Channels: []ChannelPreference{}}
+}
+
+const preferenceColumns = `user_id, locale, timezone, quiet_start, quiet_end, unsubscribed_at,
+	digest_window, digest_max_items, updated_at`
+
+type PostgresPreferenceRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresPreferenceRepository(db *sqlx.DB) *PostgresPreferenceRepository {
+	return &PostgresPreferenceRepository{db: db}
+}
+
+func (r *PostgresPreferenceRepository) GetPreferences(ctx context.Context, userID int) (*Preferences, error) {
+	return getPreferences(ctx, r.db, userID)
+}
+
+// SavePreferences replaces the user's settings, including the full list of
+// channel preferences.
+func (r *PostgresPreferenceRepository) SavePreferences(ctx context.Context, p *Preferences) (*Preferences, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin preferences transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO notification_preferences (
+			user_id, locale, timezone, quiet_start, quiet_end, unsubscribed_at, digest_window, digest_max_items
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id) DO UPDATE
+		SET locale = EXCLUDED.locale,
+			timezone = EXCLUDED.timezone,
+			quiet_start = EXCLUDED.quiet_start,
+			quiet_end = EXCLUDED.quiet_end,
+			unsubscribed_at = EXCLUDED.unsubscribed_at,
+			digest_window = EXCLUDED.digest_window,
+			digest_max_items = EXCLUDED.digest_max_items,
+			updated_at = NOW()
+	`, p.UserID, p.Locale, p.Timezone, p.QuietStart, p.QuietEnd, p.UnsubscribedAt, p.DigestWindow, p.DigestMaxItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notification_channel_preferences WHERE user_id = $1`, p.UserID); err != nil {
+		return nil, fmt.Errorf("failed to clear channel preferences: %w", err)
+	}
+	for _, c := range p.Channels {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_channel_preferences (user_id, category, channel, enabled)
+			VALUES ($1, $2, $3, $4)
+		`, p.UserID, c.Category, c.Channel, c.Enabled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save channel preference: %w", err)
+		}
+	}
+
+	saved, err := getPreferences(ctx, tx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit preferences: %w", err)
+	}
+	return saved, nil
+}
+
+// Unsubscribe stops all notifications to the user. Unsubscribing again keeps
+// the original timestamp.
+func (r *PostgresPreferenceRepository) Unsubscribe(ctx context.Context, userID int) (*Preferences, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, unsubscribed_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET unsubscribed_at = COALESCE(notification_preferences.unsubscribed_at, NOW()),
+			updated_at = NOW()
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unsubscribe user: %w", err)
+	}
+	return getPreferences(ctx, r.db, userID)
+}
+
+func getPreferences(ctx context.Context, q sqlx.QueryerContext, userID int) (*Preferences, error) {
+	var p Preferences
+	err := sqlx.GetContext(ctx, q, &p, `SELECT `+preferenceColumns+` FROM notification_preferences WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultPreferences(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get preferences failed: %w", err)
+	}
+
+	p.Channels = []ChannelPreference{}
+	query := `
+		SELECT category, channel, enabled
+		FROM notification_channel_preferences
+		WHERE user_id = $1
+		ORDER BY category, channel
+	`
+	if err := sqlx.SelectContext(ctx, q, &p.Channels, query, userID); err != nil {
+		return nil, fmt.Errorf("get channel preferences failed: %w", err)
+	}
+	return &p, nil
+}
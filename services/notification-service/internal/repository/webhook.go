@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	WebhookActive   = "active"
+	WebhookDisabled = "disabled"
+
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// WebhookRepository stores partner webhook subscriptions, the deliveries
+// queued for them and a log of every attempt at each delivery.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, w *Webhook) (*Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (*Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	UpdateWebhook(ctx context.Context, w *Webhook) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	SetWebhookStatus(ctx context.Context, id int64, status, reason string) (*Webhook, error)
+	ActiveWebhooks(ctx context.Context) ([]Webhook, error)
+
+	EnqueueWebhookDelivery(ctx context.Context, d WebhookDelivery) (bool, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDelivery, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error)
+	RecordWebhookAttempt(ctx context.Context, result WebhookAttemptResult) (*WebhookDelivery, *Webhook, error)
+}
+
+// Webhook is a partner endpoint subscribed to events whose type matches one
+// of EventTypes. The secret signs every delivery and is only shown when the
+// webhook is created.
+type Webhook struct {
+	ID                  int64          `db:"id" json:"id"`
+	URL                 string         `db:"url" json:"url"`
+	Secret              string         `db:"secret" json:"-"`
+	EventTypes          pq.StringArray `db:"event_types" json:"event_types"`
+	Description         string         `db:"description" json:"description"`
+	Status              string         `db:"status" json:"status"`
+	ConsecutiveFailures int            `db:"consecutive_failures" json:"consecutive_failures"`
+	DisabledReason      string         `db:"disabled_reason" json:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time     `db:"disabled_at" json:"disabled_at"`
+	LastSuccessAt       *time.Time     `db:"last_success_at" json:"last_success_at"`
+	CreatedAt           time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt           time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// WebhookDelivery is one event to one webhook. Its status and last_* fields
+// describe the latest attempt; the attempts themselves are WebhookAttempts.
+type WebhookDelivery struct {
+	ID             int64           `db:"id" json:"id"`
+	WebhookID      int64           `db:"subscription_id" json:"webhook_id"`
+	EventID        string          `db:"event_id" json:"event_id"`
+	EventType      string          `db:"event_type" json:"event_type"`
+	Payload        json.RawMessage `db:"payload" json:"payload"`
+	Status         string          `db:"status" json:"status"`
+	Attempts       int             `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode *int            `db:"last_status_code" json:"last_status_code"`
+	LastError      string          `db:"last_error" json:"last_error"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `db:"updated_at" json:"updated_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at" json:"delivered_at"`
+}
+
+// WebhookAttempt is one HTTP request made for a delivery. Manual attempts
+// are redeliveries requested through the API.
+type WebhookAttempt struct {
+	ID         int64     `db:"id" json:"id"`
+	DeliveryID int64     `db:"delivery_id" json:"delivery_id"`
+	Attempt    int       `db:"attempt" json:"attempt"`
+	Manual     bool      `db:"manual" json:"manual"`
+	StatusCode *int      `db:"status_code" json:"status_code"`
+	Response   string    `db:"response" json:"response"`
+	Error      string    `db:"error" json:"error"`
+	DurationMS int       `db:"duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// WebhookAttemptResult is the outcome of an attempt and what it does to the
+// delivery and its webhook.
+type WebhookAttemptResult struct {
+	DeliveryID int64
+	Manual     bool
+	StatusCode *int
+	Response   string
+	Err        error
+	Duration   time.Duration
+
+	// Status is the delivery's new status. NextAttemptAt reschedules a
+	// pending delivery; nil leaves the schedule as it is.
+	Status        string
+	NextAttemptAt *time.Time
+
+	// A failed automatic attempt that brings the webhook's consecutive
+	// failures to DisableAfter disables it; zero never disables. Manual
+	// attempts do not count, but a successful one resets the count.
+	DisableAfter int
+}
+
+// WebhookDeliveryFilter narrows ListWebhookDeliveries; zero values match
+// anything.
+type WebhookDeliveryFilter struct {
+	WebhookID int64
+	Status    string
+	Limit     int
+}
+
+const (
+	webhookColumns = `id, url, secret, event_types, description, status, consecutive_failures,
+	disabled_reason, disabled_at, last_success_at, created_at, updated_at`
+	webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_status_code, last_error, created_at, updated_at, delivered_at`
+	webhookAttemptColumns = `id, delivery_id, attempt, manual, status_code, response, error, duration_ms, created_at`
+)
+
+type PostgresWebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgresWebhookRepository(db *sqlx.DB) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db}
+}
+
+func (r *PostgresWebhookRepository) CreateWebhook(ctx context.Context, w *Webhook) (*Webhook, error) {
+	var created Webhook
+	err := r.db.GetContext(ctx, &created, `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+webhookColumns, w.URL, w.Secret, w.EventTypes, w.Description)
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook failed: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *PostgresWebhookRepository) GetWebhook(ctx context.Context, id int64) (*Webhook, error) {
+	var w Webhook
+	err := r.db.GetContext(ctx, &w, `SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook failed: %w", err)
+	}
+	return &w, nil
+}
+
+func (r *PostgresWebhookRepository) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	list := []Webhook{}
+	if err := r.db.SelectContext(ctx, &list, `SELECT `+webhookColumns+` FROM webhook_subscriptions ORDER BY id`); err != nil {
+		return nil, fmt.Errorf("list webhooks failed: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateWebhook changes the URL, event types, description and secret of
+// w.ID; its status and failure count are left alone.
+func (r *PostgresWebhookRepository) UpdateWebhook(ctx context.Context, w *Webhook) (*Webhook, error) {
+	var updated Webhook
+	err := r.db.GetContext(ctx, &updated, `
+		UPDATE webhook_subscriptions
+		SET url = $2, secret = $3, event_types = $4, description = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+webhookColumns, w.ID, w.URL, w.Secret, w.EventTypes, w.Description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update webhook failed: %w", err)
+	}
+	return &updated, nil
+}
+
+// DeleteWebhook removes the webhook together with its deliveries.
+func (r *PostgresWebhookRepository) DeleteWebhook(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook failed: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SetWebhookStatus enables or disables a webhook by hand. Enabling clears
+// the failure count, so the webhook gets a full allowance of failures again.
+func (r *PostgresWebhookRepository) SetWebhookStatus(ctx context.Context, id int64, status, reason string) (*Webhook, error) {
+	var w Webhook
+	err := r.db.GetContext(ctx, &w, `
+		UPDATE webhook_subscriptions
+		SET status = $2,
+			disabled_reason = $3,
+			disabled_at = CASE WHEN $2 = 'disabled' THEN COALESCE(disabled_at, NOW()) END,
+			consecutive_failures = CASE WHEN $2 = 'active' THEN 0 ELSE consecutive_failures END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+webhookColumns, id, status, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update webhook status failed: %w", err)
+	}
+	return &w, nil
+}
+
+func (r *PostgresWebhookRepository) ActiveWebhooks(ctx context.Context) ([]Webhook, error) {
+	list := []Webhook{}
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE status = 'active' ORDER BY id`
+	if err := r.db.SelectContext(ctx, &list, query); err != nil {
+		return nil, fmt.Errorf("list active webhooks failed: %w", err)
+	}
+	return list, nil
+}
+
+// EnqueueWebhookDelivery queues d for immediate delivery. It reports false
+// if the webhook already has a delivery of the event, e.g. because the
+// broker redelivered it.
+func (r *PostgresWebhookRepository) EnqueueWebhookDelivery(ctx context.Context, d WebhookDelivery) (bool, error) {
+	// Passed as text: lib/pq would send []byte as bytea, which JSONB rejects.
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`, d.WebhookID, d.EventID, d.EventType, string(d.Payload))
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ClaimDueWebhookDeliveries returns up to limit pending deliveries of active
+// webhooks that are due, and pushes their next attempt lease into the
+// future so that no other worker picks them up meanwhile. A worker that
+// dies mid-delivery leaves them to be retried once the lease runs out.
+func (r *PostgresWebhookRepository) ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	list := []WebhookDelivery{}
+	err := r.db.SelectContext(ctx, &list, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = NOW() + make_interval(secs => $2), updated_at = NOW()
+		WHERE id IN (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND s.status = 'active'
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresWebhookRepository) GetWebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := r.db.GetContext(ctx, &d, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook delivery failed: %w", err)
+	}
+	return &d, nil
+}
+
+// ListWebhookDeliveries returns the newest deliveries first.
+func (r *PostgresWebhookRepository) ListWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE ($1 = 0 OR subscription_id = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	list := []WebhookDelivery{}
+	if err := r.db.SelectContext(ctx, &list, query, filter.WebhookID, filter.Status, filter.Limit); err != nil {
+		return nil, fmt.Errorf("list webhook deliveries failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PostgresWebhookRepository) ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error) {
+	list := []WebhookAttempt{}
+	query := `SELECT ` + webhookAttemptColumns + ` FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY attempt`
+	if err := r.db.SelectContext(ctx, &list, query, deliveryID); err != nil {
+		return nil, fmt.Errorf("list webhook attempts failed: %w", err)
+	}
+	return list, nil
+}
+
+// RecordWebhookAttempt logs an attempt and applies its outcome to the
+// delivery and its webhook in one transaction.
+func (r *PostgresWebhookRepository) RecordWebhookAttempt(ctx context.Context, result WebhookAttemptResult) (*WebhookDelivery, *Webhook, error) {
+	var lastError string
+	if result.Err != nil {
+		lastError = result.Err.Error()
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin webhook attempt transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var d WebhookDelivery
+	err = tx.GetContext(ctx, &d, `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = attempts + 1,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+webhookDeliveryColumns, result.DeliveryID, result.Status, result.NextAttemptAt,
+		result.StatusCode, lastError)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("update webhook delivery failed: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, manual, status_code, response, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, d.ID, d.Attempts, result.Manual, result.StatusCode, result.Response, lastError, result.Duration.Milliseconds())
+	if err != nil {
+		return nil, nil, fmt.Errorf("insert webhook attempt failed: %w", err)
+	}
+
+	var w Webhook
+	switch {
+	case result.Err == nil:
+		err = tx.GetContext(ctx, &w, `
+			UPDATE webhook_subscriptions
+			SET consecutive_failures = 0, last_success_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+webhookColumns, d.WebhookID)
+	case result.Manual:
+		err = tx.GetContext(ctx, &w, `SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id = $1`, d.WebhookID)
+	default:
+		err = tx.GetContext(ctx, &w, `
+			UPDATE webhook_subscriptions
+			SET consecutive_failures = consecutive_failures + 1,
+				status = CASE WHEN $2 > 0 AND consecutive_failures + 1 >= $2 THEN 'disabled' ELSE status END,
+				disabled_reason = CASE WHEN status = 'active' AND $2 > 0 AND consecutive_failures + 1 >= $2
+					THEN $3 ELSE disabled_reason END,
+				disabled_at = CASE WHEN status = 'active' AND $2 > 0 AND consecutive_failures + 1 >= $2
+					THEN NOW() ELSE disabled_at END,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+webhookColumns, d.WebhookID, result.DisableAfter,
+			fmt.Sprintf("disabled after %d consecutive failed deliveries, last: %s", result.DisableAfter, lastError))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("update webhook after attempt failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit webhook attempt: %w", err)
+	}
+	return &d, &w, nil
+}
@@ -0,0 +1,173 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localeFormat holds the number and date conventions of a language. Locales
+// without an entry use English conventions.
+type localeFormat struct {
+	thousands   string
+	decimal     string
+	symbolAfter bool
+	date        string
+	datetime    string
+}
+
+var localeFormats = map[string]localeFormat{
+	"en": {thousands: ",", decimal: ".", date: "Jan 2, 2006", datetime: "Jan 2, 2006 15:04 MST"},
+	"tr": {thousands: ".", decimal: ",", symbolAfter: true, date: "02.01.2006", datetime: "02.01.2006 15:04"},
+	"de": {thousands: ".", decimal: ",", symbolAfter: true, date: "02.01.2006", datetime: "02.01.2006 15:04"},
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"TRY": "₺",
+	"JPY": "¥",
+}
+
+// currencyExponents lists currencies whose minor unit is not 1/100.
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+}
+
+func formatFor(locale string) localeFormat {
+	if f, ok := localeFormats[locale]; ok {
+		return f
+	}
+	if f, ok := localeFormats[baseLanguage(locale)]; ok {
+		return f
+	}
+	return localeFormats["en"]
+}
+
+// funcMap returns the template helpers bound to locale:
+//
+//	{{money .Event.TotalMinor "TRY"}}  ->  1.234,56 ₺ (tr) / $1,234.56 (en, USD)
+//	{{date .Event.CreatedAt}}          ->  19.10.2026 (tr) / Oct 19, 2026 (en)
+//	{{datetime .Event.CreatedAt}}      ->  date and time of day
+func funcMap(locale string) map[string]interface{} {
+	f := formatFor(locale)
+	return map[string]interface{}{
+		"money": func(minor interface{}, currency string) (string, error) {
+			amount, err := toInt64(minor)
+			if err != nil {
+				return "", err
+			}
+			return formatMoney(f, amount, strings.ToUpper(currency)), nil
+		},
+		"date": func(v interface{}) string {
+			return formatTime(v, f.date)
+		},
+		"datetime": func(v interface{}) string {
+			return formatTime(v, f.datetime)
+		},
+	}
+}
+
+func formatMoney(f localeFormat, minor int64, currency string) string {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		exp = 2
+	}
+
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	unit := int64(1)
+	for i := 0; i < exp; i++ {
+		unit *= 10
+	}
+
+	number := groupThousands(strconv.FormatInt(minor/unit, 10), f.thousands)
+	if exp > 0 {
+		number += f.decimal + fmt.Sprintf("%0*d", exp, minor%unit)
+	}
+
+	symbol, ok := currencySymbols[currency]
+	switch {
+	case !ok:
+		return sign + number + " " + currency
+	case f.symbolAfter:
+		return sign + number + " " + symbol
+	default:
+		return sign + symbol + number
+	}
+}
+
+func groupThousands(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// formatTime accepts a time.Time or a timestamp string as found in event
+// payloads. Strings it cannot parse are returned unchanged rather than
+// failing the whole notification.
+func formatTime(v interface{}, layout string) string {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(layout)
+	case *time.Time:
+		if t == nil {
+			return ""
+		}
+		return t.Format(layout)
+	case string:
+		for _, l := range timeLayouts {
+			if parsed, err := time.Parse(l, t); err == nil {
+				return parsed.Format(layout)
+			}
+		}
+		return t
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case float64:
+		return int64(n), nil
+	case *int64:
+		if n == nil {
+			return 0, nil
+		}
+		return *n, nil
+	default:
+		return 0, fmt.Errorf("money: unsupported amount type %T", v)
+	}
+}
+
+func baseLanguage(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	base, _, _ = strings.Cut(base, "_")
+	return strings.ToLower(base)
+}
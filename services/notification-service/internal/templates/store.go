@@ -0,0 +1,317 @@
+// Package templates renders notification content from text/template and
+// html/template files on disk, keyed by locale, event type and channel.
+//
+// Layout:
+//
+//	<dir>/<locale>/<event_type>/<channel>.txt.tmpl   defines "subject" and "body"
+//	<dir>/<locale>/<event_type>/<channel>.html.tmpl  optional, defines "body"
+//
+// The channel "default" is used for channels without their own template. A
+// channel with only an HTML template takes its subject and text body from
+// "default".
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/notifier"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultChannel = "default"
+
+	textSuffix = ".txt.tmpl"
+	htmlSuffix = ".html.tmpl"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+type key struct {
+	locale    string
+	eventType string
+	channel   string
+}
+
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Data is what templates are executed against.
+type Data struct {
+	EventType string
+	Locale    string
+	To        string
+	UserID    int
+	Event     interface{}
+
+	// UnsubscribeURL is empty for messages that are not sent to a user.
+	UnsubscribeURL string
+}
+
+// Store holds the parsed templates and swaps them atomically on reload. A
+// reload that fails to parse keeps the previous templates in service.
+type Store struct {
+	dir           string
+	defaultLocale string
+	log           zerolog.Logger
+
+	mu          sync.RWMutex
+	sets        map[key]*templateSet
+	fingerprint string
+}
+
+// NewStore loads every template under dir once; the service should not start
+// with broken templates.
+func NewStore(dir, defaultLocale string, log zerolog.Logger) (*Store, error) {
+	s := &Store{dir: dir, defaultLocale: defaultLocale, log: log}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload parses the template tree and replaces the current templates.
+func (s *Store) Reload() error {
+	fingerprint, err := s.scan()
+	if err != nil {
+		return err
+	}
+	sets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.sets = sets
+	s.fingerprint = fingerprint
+	s.mu.Unlock()
+
+	s.log.Info().Str("dir", s.dir).Int("templates", len(sets)).Msg("Notification templates loaded")
+	return nil
+}
+
+// Watch polls the template directory and reloads when a file is added,
+// removed or modified.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fingerprint, err := s.scan()
+			if err != nil {
+				s.log.Error().Err(err).Msg("Failed to scan notification templates")
+				continue
+			}
+			s.mu.RLock()
+			changed := fingerprint != s.fingerprint
+			s.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				s.log.Error().Err(err).Msg("Template reload failed — keeping previous templates")
+				s.mu.Lock()
+				s.fingerprint = fingerprint
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Render implements notifier.Renderer. The locale falls back to its base
+// language and then to the default locale, and within a locale the channel
+// falls back to "default". Events without any template are rendered as
+// their JSON payload so that a new event type is never lost.
+func (s *Store) Render(channel string, msg notifier.Message) (notifier.Content, error) {
+	set, name, locale, err := s.lookup(msg.EventType, channel, msg.Locale)
+	if errors.Is(err, ErrTemplateNotFound) {
+		return fallbackContent(msg), nil
+	}
+	if err != nil {
+		return notifier.Content{}, err
+	}
+
+	data := Data{
+		EventType:      msg.EventType,
+		Locale:         locale,
+		To:             msg.To,
+		UserID:         msg.UserID,
+		Event:          msg.Data,
+		UnsubscribeURL: msg.UnsubscribeURL,
+	}
+
+	content := notifier.Content{Template: name}
+	var buf bytes.Buffer
+	if err := set.text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return notifier.Content{}, fmt.Errorf("render subject for %s: %w", msg.EventType, err)
+	}
+	content.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := set.text.ExecuteTemplate(&buf, "body", data); err != nil {
+		return notifier.Content{}, fmt.Errorf("render body for %s: %w", msg.EventType, err)
+	}
+	content.Body = strings.TrimSpace(buf.String()) + "\n"
+
+	if set.html != nil {
+		buf.Reset()
+		if err := set.html.ExecuteTemplate(&buf, "body", data); err != nil {
+			return notifier.Content{}, fmt.Errorf("render html body for %s: %w", msg.EventType, err)
+		}
+		content.HTMLBody = buf.String()
+	}
+	return content, nil
+}
+
+// lookup returns the template set, its name as locale/event_type/channel and
+// the locale it was found in.
+func (s *Store) lookup(eventType, channel, locale string) (*templateSet, string, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, l := range s.localeChain(locale) {
+		for _, c := range []string{channel, DefaultChannel} {
+			if set, ok := s.sets[key{locale: l, eventType: eventType, channel: c}]; ok {
+				return set, l + "/" + eventType + "/" + c, l, nil
+			}
+		}
+	}
+	return nil, "", "", ErrTemplateNotFound
+}
+
+func (s *Store) localeChain(locale string) []string {
+	var chain []string
+	for _, l := range []string{locale, baseLanguage(locale), s.defaultLocale} {
+		if l == "" {
+			continue
+		}
+		seen := false
+		for _, c := range chain {
+			seen = seen || c == l
+		}
+		if !seen {
+			chain = append(chain, l)
+		}
+	}
+	return chain
+}
+
+// scan fingerprints the tree by path, size and modification time.
+func (s *Store) scan() (string, error) {
+	var entries []string
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan template dir %s: %w", s.dir, err)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "|"), nil
+}
+
+func (s *Store) load() (map[key]*templateSet, error) {
+	sets := make(map[key]*templateSet)
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) != 3 {
+			return nil
+		}
+		locale, eventType, file := parts[0], parts[1], parts[2]
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", rel, err)
+		}
+
+		switch {
+		case strings.HasSuffix(file, textSuffix):
+			k := key{locale: locale, eventType: eventType, channel: strings.TrimSuffix(file, textSuffix)}
+			t, err := texttemplate.New(rel).Funcs(funcMap(locale)).Option("missingkey=error").Parse(string(src))
+			if err != nil {
+				return fmt.Errorf("failed to parse template %s: %w", rel, err)
+			}
+			if t.Lookup("subject") == nil || t.Lookup("body") == nil {
+				return fmt.Errorf("template %s must define \"subject\" and \"body\"", rel)
+			}
+			set := setFor(sets, k)
+			set.text = t
+		case strings.HasSuffix(file, htmlSuffix):
+			k := key{locale: locale, eventType: eventType, channel: strings.TrimSuffix(file, htmlSuffix)}
+			t, err := htmltemplate.New(rel).Funcs(funcMap(locale)).Option("missingkey=error").Parse(string(src))
+			if err != nil {
+				return fmt.Errorf("failed to parse template %s: %w", rel, err)
+			}
+			if t.Lookup("body") == nil {
+				return fmt.Errorf("template %s must define \"body\"", rel)
+			}
+			set := setFor(sets, k)
+			set.html = t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A channel may only override the HTML body; it then takes its subject
+	// and text body from the default channel.
+	for k, set := range sets {
+		if set.text != nil {
+			continue
+		}
+		def, ok := sets[key{locale: k.locale, eventType: k.eventType, channel: DefaultChannel}]
+		if !ok || def.text == nil {
+			return nil, fmt.Errorf("template %s/%s/%s has an HTML body but no %s file", k.locale, k.eventType, k.channel, textSuffix)
+		}
+		set.text = def.text
+	}
+	return sets, nil
+}
+
+func setFor(sets map[key]*templateSet, k key) *templateSet {
+	set, ok := sets[k]
+	if !ok {
+		set = &templateSet{}
+		sets[k] = set
+	}
+	return set
+}
+
+func fallbackContent(msg notifier.Message) notifier.Content {
+	body, _ := json.MarshalIndent(msg.Data, "", "  ")
+	return notifier.Content{Subject: msg.EventType, Body: string(body) + "\n"}
+}
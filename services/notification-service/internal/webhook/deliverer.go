@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// claimLease keeps a claimed delivery from being claimed again while it
+	// is in flight; it must outlast the HTTP client's timeout.
+	claimLease = 5 * time.Minute
+	// maxResponseBody is how much of a receiver's answer is logged.
+	maxResponseBody = 1024
+)
+
+// Envelope is the JSON body of a delivery. It is the same for every attempt,
+// so receivers can deduplicate on ID.
+type Envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Deliverer makes delivery attempts and records their outcome. Any 2xx
+// response is a success; anything else, including a timeout, is retried
+// according to the policy.
+type Deliverer struct {
+	repo        repository.WebhookRepository
+	client      *http.Client
+	policy      RetryPolicy
+	concurrency int
+	now         func() time.Time
+	log         zerolog.Logger
+}
+
+// NewDeliverer posts with client, which should have a timeout well below a
+// few minutes, and makes up to concurrency requests at a time.
+func NewDeliverer(repo repository.WebhookRepository, client *http.Client, policy RetryPolicy, concurrency int, log zerolog.Logger) *Deliverer {
+	return &Deliverer{
+		repo:        repo,
+		client:      client,
+		policy:      policy,
+		concurrency: max(concurrency, 1),
+		now:         time.Now,
+		log:         log,
+	}
+}
+
+// DeliverDue attempts up to limit due deliveries and returns how many it
+// attempted.
+func (d *Deliverer) DeliverDue(ctx context.Context, limit int) (int, error) {
+	due, err := d.repo.ClaimDueWebhookDeliveries(ctx, limit, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	var mu sync.Mutex
+	webhooks := make(map[int64]*repository.Webhook)
+	webhookFor := func(id int64) (*repository.Webhook, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if w, ok := webhooks[id]; ok {
+			return w, nil
+		}
+		w, err := d.repo.GetWebhook(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		webhooks[id] = w
+		return w, nil
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, d.concurrency)
+	for _, delivery := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(delivery repository.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			w, err := webhookFor(delivery.WebhookID)
+			if err != nil {
+				d.log.Error().Err(err).Int64("delivery_id", delivery.ID).Msg("Failed to load webhook for delivery")
+				return
+			}
+			if _, err := d.attempt(ctx, w, delivery, false); err != nil {
+				d.log.Error().Err(err).Int64("delivery_id", delivery.ID).Msg("Failed to record webhook attempt")
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// Redeliver makes a manual attempt at delivery id right away, whatever its
+// status and whether or not its webhook is disabled. A failed manual attempt
+// is logged but leaves the delivery's schedule alone.
+func (d *Deliverer) Redeliver(ctx context.Context, id int64) (*repository.WebhookDelivery, error) {
+	delivery, err := d.repo.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	w, err := d.repo.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	return d.attempt(ctx, w, *delivery, true)
+}
+
+func (d *Deliverer) attempt(ctx context.Context, w *repository.Webhook, delivery repository.WebhookDelivery, manual bool) (*repository.WebhookDelivery, error) {
+	attempt := delivery.Attempts + 1
+	start := d.now()
+	statusCode, response, sendErr := d.post(ctx, w, delivery, attempt)
+
+	result := repository.WebhookAttemptResult{
+		DeliveryID:   delivery.ID,
+		Manual:       manual,
+		StatusCode:   statusCode,
+		Response:     response,
+		Err:          sendErr,
+		Duration:     d.now().Sub(start),
+		Status:       delivery.Status,
+		DisableAfter: d.policy.DisableAfter,
+	}
+	switch {
+	case sendErr == nil:
+		result.Status = repository.DeliveryDelivered
+	case manual:
+		// A failed redelivery leaves the automatic retries as they were.
+	case attempt >= d.policy.MaxAttempts:
+		result.Status = repository.DeliveryFailed
+	default:
+		next := d.now().Add(d.policy.Backoff(attempt)).UTC()
+		result.NextAttemptAt = &next
+	}
+
+	updated, webhook, err := d.repo.RecordWebhookAttempt(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+
+	logEvent := d.log.Info()
+	if sendErr != nil {
+		logEvent = d.log.Warn().Err(sendErr)
+	}
+	logEvent.
+		Int64("webhook_id", w.ID).
+		Int64("delivery_id", delivery.ID).
+		Str("event_type", delivery.EventType).
+		Int("attempt", attempt).
+		Bool("manual", manual).
+		Str("status", updated.Status).
+		Msg("Webhook delivery attempted")
+
+	// Only the attempt that reached the limit sees exactly DisableAfter.
+	if sendErr != nil && !manual && webhook.Status == repository.WebhookDisabled &&
+		webhook.ConsecutiveFailures == d.policy.DisableAfter {
+		d.log.Warn().
+			Int64("webhook_id", w.ID).
+			Str("url", w.URL).
+			Int("consecutive_failures", webhook.ConsecutiveFailures).
+			Msg("Webhook disabled after repeated failures")
+	}
+	return updated, nil
+}
+
+// post sends one attempt and returns the response status code, if the
+// receiver answered, and the status line with the start of the body.
+func (d *Deliverer) post(ctx context.Context, w *repository.Webhook, delivery repository.WebhookDelivery, attempt int) (*int, string, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt.UTC(),
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	ts := d.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ecommerce-system-webhooks/1.0")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(w.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	statusCode := resp.StatusCode
+	response := resp.Status
+	// Stored as TEXT, which takes neither invalid UTF-8 nor NUL bytes.
+	b := strings.ToValidUTF8(strings.ReplaceAll(string(respBody), "\x00", ""), "\uFFFD")
+	if b = strings.TrimSpace(b); b != "" {
+		response += ": " + b
+	}
+	if statusCode < 200 || statusCode > 299 {
+		return &statusCode, response, fmt.Errorf("webhook responded %d", statusCode)
+	}
+	return &statusCode, response, nil
+}
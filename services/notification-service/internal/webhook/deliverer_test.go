@@ -0,0 +1,441 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/notification-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// memoryWebhooks is an in-memory WebhookRepository for the methods the
+// Deliverer uses. RecordWebhookAttempt follows the rules documented on
+// repository.WebhookAttemptResult: every attempt is logged, failed automatic
+// attempts count towards DisableAfter, manual ones do not, and a success
+// resets the count.
+type memoryWebhooks struct {
+	repository.WebhookRepository
+
+	mu         sync.Mutex
+	webhooks   map[int64]*repository.Webhook
+	deliveries map[int64]*repository.WebhookDelivery
+	attempts   []repository.WebhookAttempt
+}
+
+func newMemoryWebhooks(w repository.Webhook, deliveries ...repository.WebhookDelivery) *memoryWebhooks {
+	m := &memoryWebhooks{
+		webhooks:   map[int64]*repository.Webhook{w.ID: &w},
+		deliveries: map[int64]*repository.WebhookDelivery{},
+	}
+	for i := range deliveries {
+		d := deliveries[i]
+		m.deliveries[d.ID] = &d
+	}
+	return m
+}
+
+func (m *memoryWebhooks) GetWebhook(_ context.Context, id int64) (*repository.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.webhooks[id]
+	if !ok {
+		return nil, repository.ErrWebhookNotFound
+	}
+	copied := *w
+	return &copied, nil
+}
+
+func (m *memoryWebhooks) GetWebhookDelivery(_ context.Context, id int64) (*repository.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[id]
+	if !ok {
+		return nil, repository.ErrWebhookDeliveryNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// ClaimDueWebhookDeliveries returns every pending delivery of an active
+// webhook, whatever its schedule; tests control time through the Deliverer.
+func (m *memoryWebhooks) ClaimDueWebhookDeliveries(_ context.Context, limit int, _ time.Duration) ([]repository.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []repository.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == repository.DeliveryPending && m.webhooks[d.WebhookID].Status == repository.WebhookActive {
+			due = append(due, *d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (m *memoryWebhooks) RecordWebhookAttempt(_ context.Context, result repository.WebhookAttemptResult) (*repository.WebhookDelivery, *repository.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[result.DeliveryID]
+	if !ok {
+		return nil, nil, repository.ErrWebhookDeliveryNotFound
+	}
+	var lastError string
+	if result.Err != nil {
+		lastError = result.Err.Error()
+	}
+	d.Status = result.Status
+	d.Attempts++
+	if result.NextAttemptAt != nil {
+		d.NextAttemptAt = *result.NextAttemptAt
+	}
+	d.LastStatusCode = result.StatusCode
+	d.LastError = lastError
+
+	m.attempts = append(m.attempts, repository.WebhookAttempt{
+		ID:         int64(len(m.attempts) + 1),
+		DeliveryID: d.ID,
+		Attempt:    d.Attempts,
+		Manual:     result.Manual,
+		StatusCode: result.StatusCode,
+		Response:   result.Response,
+		Error:      lastError,
+	})
+
+	w := m.webhooks[d.WebhookID]
+	switch {
+	case result.Err == nil:
+		w.ConsecutiveFailures = 0
+	case result.Manual:
+	default:
+		w.ConsecutiveFailures++
+		if result.DisableAfter > 0 && w.ConsecutiveFailures >= result.DisableAfter && w.Status == repository.WebhookActive {
+			w.Status = repository.WebhookDisabled
+			w.DisabledReason = fmt.Sprintf("disabled after %d consecutive failed deliveries, last: %s", result.DisableAfter, lastError)
+		}
+	}
+	dc, wc := *d, *w
+	return &dc, &wc, nil
+}
+
+func (m *memoryWebhooks) delivery(id int64) repository.WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.deliveries[id]
+}
+
+func (m *memoryWebhooks) webhook(id int64) repository.Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.webhooks[id]
+}
+
+var testNow = time.Date(2026, 10, 19, 14, 5, 0, 0, time.UTC)
+
+func testWebhook(url string) repository.Webhook {
+	return repository.Webhook{ID: 1, URL: url, Secret: testSecret, EventTypes: []string{"order.*"}, Status: repository.WebhookActive}
+}
+
+func testDelivery(id int64) repository.WebhookDelivery {
+	return repository.WebhookDelivery{
+		ID:        id,
+		WebhookID: 1,
+		EventID:   fmt.Sprintf("order-service/event_logs/%d", id),
+		EventType: "order.created",
+		Payload:   json.RawMessage(`{"id":1042,"quantity":2}`),
+		Status:    repository.DeliveryPending,
+		CreatedAt: testNow.Add(-time.Minute),
+	}
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 30 * time.Second, MaxDelay: 10 * time.Minute, DisableAfter: 5}
+
+func newTestDeliverer(repo repository.WebhookRepository, client *http.Client, policy RetryPolicy) *Deliverer {
+	d := NewDeliverer(repo, client, policy, 2, zerolog.Nop())
+	d.now = func() time.Time { return testNow }
+	return d
+}
+
+// statusReceiver answers every request with status and counts them.
+func statusReceiver(t *testing.T, status int) (*httptest.Server, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestDeliverySignedWithSubscriberSecret(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := newMemoryWebhooks(testWebhook(srv.URL), testDelivery(7))
+	d := newTestDeliverer(repo, srv.Client(), testPolicy)
+	if n, err := d.DeliverDue(context.Background(), 10); n != 1 || err != nil {
+		t.Fatalf("DeliverDue = %d, %v; want 1 attempt", n, err)
+	}
+
+	r := <-got
+	ts := r.header.Get(HeaderTimestamp)
+	if ts != strconv.FormatInt(testNow.Unix(), 10) {
+		t.Errorf("%s = %q, want %d", HeaderTimestamp, ts, testNow.Unix())
+	}
+	if sig := r.header.Get(HeaderSignature); sig != expectedSignature(testSecret, testNow.Unix(), r.body) {
+		t.Errorf("%s = %q, want HMAC-SHA256 of ts.body with the subscriber secret", HeaderSignature, sig)
+	}
+	if err := Verify(testSecret, r.header.Get(HeaderSignature), ts, r.body, 5*time.Minute, testNow); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		HeaderEventID:   "order-service/event_logs/7",
+		HeaderEventType: "order.created",
+		HeaderDelivery:  "7",
+		HeaderAttempt:   "1",
+	}
+	for key, want := range headers {
+		if v := r.header.Get(key); v != want {
+			t.Errorf("%s = %q, want %q", key, v, want)
+		}
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(r.body, &env); err != nil {
+		t.Fatalf("body is not an envelope: %v", err)
+	}
+	if env.ID != "order-service/event_logs/7" || env.Type != "order.created" ||
+		!env.CreatedAt.Equal(testNow.Add(-time.Minute)) || string(env.Data) != `{"id":1042,"quantity":2}` {
+		t.Errorf("envelope = %+v", env)
+	}
+
+	delivery := repo.delivery(7)
+	if delivery.Status != repository.DeliveryDelivered || delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusNoContent {
+		t.Errorf("delivery = %+v, want delivered with 204", delivery)
+	}
+}
+
+func TestServerErrorRetriedWithExponentialBackoff(t *testing.T) {
+	srv, calls := statusReceiver(t, http.StatusServiceUnavailable)
+	repo := newMemoryWebhooks(testWebhook(srv.URL), testDelivery(7))
+	d := newTestDeliverer(repo, srv.Client(), testPolicy)
+
+	for _, want := range []time.Duration{30 * time.Second, time.Minute} {
+		if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+			t.Fatal(err)
+		}
+		delivery := repo.delivery(7)
+		if delivery.Status != repository.DeliveryPending || !delivery.NextAttemptAt.Equal(testNow.Add(want)) {
+			t.Errorf("after attempt %d: status %s, next attempt %v; want pending at now+%v",
+				delivery.Attempts, delivery.Status, delivery.NextAttemptAt, want)
+		}
+		if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusServiceUnavailable {
+			t.Errorf("last status code = %v, want 503", delivery.LastStatusCode)
+		}
+	}
+
+	// The last allowed attempt gives the delivery up.
+	if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if delivery := repo.delivery(7); delivery.Status != repository.DeliveryFailed || delivery.Attempts != 3 {
+		t.Errorf("delivery = %s after %d attempts, want failed after 3", delivery.Status, delivery.Attempts)
+	}
+	if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("receiver got %d requests, want 3", *calls)
+	}
+}
+
+func TestClientErrorIsRetriedToo(t *testing.T) {
+	srv, _ := statusReceiver(t, http.StatusBadRequest)
+	repo := newMemoryWebhooks(testWebhook(srv.URL), testDelivery(7))
+	d := newTestDeliverer(repo, srv.Client(), testPolicy)
+
+	if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if delivery := repo.delivery(7); delivery.Status != repository.DeliveryPending || delivery.LastError != "webhook responded 400" {
+		t.Errorf("delivery = %+v, want pending after a non-2xx response", delivery)
+	}
+	if got := repo.attempts[0].Response; got != "400 Bad Request: Bad Request" {
+		t.Errorf("recorded response = %q, want the status line and body", got)
+	}
+}
+
+func TestTimeoutRetriedWithBackoff(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := srv.Client()
+	client.Timeout = 50 * time.Millisecond
+	repo := newMemoryWebhooks(testWebhook(srv.URL), testDelivery(7))
+	d := newTestDeliverer(repo, client, testPolicy)
+
+	if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	delivery := repo.delivery(7)
+	if delivery.Status != repository.DeliveryPending || !delivery.NextAttemptAt.Equal(testNow.Add(30*time.Second)) {
+		t.Errorf("delivery = %s, next attempt %v; want pending at now+30s", delivery.Status, delivery.NextAttemptAt)
+	}
+	if delivery.LastStatusCode != nil || delivery.LastError == "" {
+		t.Errorf("delivery = %+v, want no status code and the timeout as error", delivery)
+	}
+}
+
+func TestWebhookDisabledAfterConsecutiveFailures(t *testing.T) {
+	srv, calls := statusReceiver(t, http.StatusInternalServerError)
+	deliveries := []repository.WebhookDelivery{testDelivery(1), testDelivery(2), testDelivery(3), testDelivery(4)}
+	repo := newMemoryWebhooks(testWebhook(srv.URL), deliveries...)
+	policy := testPolicy
+	policy.DisableAfter = 3
+	d := newTestDeliverer(repo, srv.Client(), policy)
+
+	// One delivery at a time, so exactly DisableAfter attempts are made.
+	for i := 0; i < 3; i++ {
+		if n, err := d.DeliverDue(context.Background(), 1); n != 1 || err != nil {
+			t.Fatalf("DeliverDue = %d, %v", n, err)
+		}
+	}
+	w := repo.webhook(1)
+	if w.Status != repository.WebhookDisabled || w.ConsecutiveFailures != 3 {
+		t.Fatalf("webhook = %s with %d failures, want disabled after 3", w.Status, w.ConsecutiveFailures)
+	}
+
+	// A disabled webhook gets no more automatic attempts.
+	if n, err := d.DeliverDue(context.Background(), 10); n != 0 || err != nil {
+		t.Errorf("DeliverDue = %d, %v; want nothing due for a disabled webhook", n, err)
+	}
+	if *calls != 3 {
+		t.Errorf("receiver got %d requests, want 3", *calls)
+	}
+}
+
+func TestSuccessResetsConsecutiveFailures(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusBadGateway
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	repo := newMemoryWebhooks(testWebhook(srv.URL), testDelivery(1), testDelivery(2))
+	d := newTestDeliverer(repo, srv.Client(), testPolicy)
+	if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if w := repo.webhook(1); w.ConsecutiveFailures != 2 {
+		t.Fatalf("consecutive failures = %d, want 2", w.ConsecutiveFailures)
+	}
+
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+	if _, err := d.DeliverDue(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if w := repo.webhook(1); w.ConsecutiveFailures != 0 || w.Status != repository.WebhookActive {
+		t.Errorf("webhook = %s with %d failures, want active with 0", w.Status, w.ConsecutiveFailures)
+	}
+}
+
+func TestRedeliverWritesNewAttempt(t *testing.T) {
+	srv, _ := statusReceiver(t, http.StatusOK)
+	w := testWebhook(srv.URL)
+	w.Status = repository.WebhookDisabled
+	failed := testDelivery(7)
+	failed.Status = repository.DeliveryFailed
+	failed.Attempts = 3
+	repo := newMemoryWebhooks(w, failed)
+	repo.attempts = []repository.WebhookAttempt{
+		{ID: 1, DeliveryID: 7, Attempt: 1}, {ID: 2, DeliveryID: 7, Attempt: 2}, {ID: 3, DeliveryID: 7, Attempt: 3},
+	}
+	d := newTestDeliverer(repo, srv.Client(), testPolicy)
+
+	updated, err := d.Redeliver(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if updated.Status != repository.DeliveryDelivered || updated.Attempts != 4 {
+		t.Errorf("delivery = %s after %d attempts, want delivered after 4", updated.Status, updated.Attempts)
+	}
+	if len(repo.attempts) != 4 {
+		t.Fatalf("attempt log has %d rows, want a new fourth one", len(repo.attempts))
+	}
+	last := repo.attempts[3]
+	if !last.Manual || last.Attempt != 4 || last.DeliveryID != 7 || last.StatusCode == nil || *last.StatusCode != http.StatusOK {
+		t.Errorf("new attempt = %+v, want manual attempt 4 answered 200", last)
+	}
+	// Redelivery works on a disabled webhook and does not enable it.
+	if got := repo.webhook(1).Status; got != repository.WebhookDisabled {
+		t.Errorf("webhook status = %s, want still disabled", got)
+	}
+}
+
+func TestFailedRedeliveryLeavesScheduleAlone(t *testing.T) {
+	srv, _ := statusReceiver(t, http.StatusInternalServerError)
+	pending := testDelivery(7)
+	pending.Attempts = 1
+	pending.NextAttemptAt = testNow.Add(30 * time.Second)
+	repo := newMemoryWebhooks(testWebhook(srv.URL), pending)
+	d := newTestDeliverer(repo, srv.Client(), testPolicy)
+
+	updated, err := d.Redeliver(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if updated.Status != repository.DeliveryPending || !updated.NextAttemptAt.Equal(pending.NextAttemptAt) {
+		t.Errorf("delivery = %s at %v, want pending at its old schedule", updated.Status, updated.NextAttemptAt)
+	}
+	if len(repo.attempts) != 1 || !repo.attempts[0].Manual || repo.attempts[0].Attempt != 2 {
+		t.Errorf("attempt log = %+v, want one manual attempt 2", repo.attempts)
+	}
+	if w := repo.webhook(1); w.ConsecutiveFailures != 0 {
+		t.Errorf("consecutive failures = %d, want manual attempts not counted", w.ConsecutiveFailures)
+	}
+}
+
+func TestRedeliverUnknownDelivery(t *testing.T) {
+	repo := newMemoryWebhooks(testWebhook("http://127.0.0.1:1"))
+	d := newTestDeliverer(repo, http.DefaultClient, testPolicy)
+	if _, err := d.Redeliver(context.Background(), 99); err != repository.ErrWebhookDeliveryNotFound {
+		t.Errorf("Redeliver = %v, want ErrWebhookDeliveryNotFound", err)
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides when a failed delivery is tried again and when a
+// webhook has failed often enough to be disabled.
+type RetryPolicy struct {
+	// MaxAttempts is the number of automatic attempts before a delivery is
+	// given up as failed.
+	MaxAttempts int
+	// The nth retry waits BaseDelay * 2^(n-1), but never more than MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// DisableAfter consecutive failed attempts, across all of a webhook's
+	// deliveries, disable the webhook; zero never disables.
+	DisableAfter int
+}
+
+// Backoff is the wait after the given number of failed attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// ValidateEventTypes checks event type filters. A filter is an exact event
+// type, a prefix ending in "*" such as "order.*", or "*" alone.
+func ValidateEventTypes(filters []string) error {
+	if len(filters) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, f := range filters {
+		if f == "" {
+			return fmt.Errorf("event types must not be empty")
+		}
+		if i := strings.Index(f, "*"); i >= 0 && i != len(f)-1 {
+			return fmt.Errorf("invalid event type %q: '*' is only allowed at the end", f)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether an event of eventType passes any of filters.
+func Matches(filters []string, eventType string) bool {
+	for _, f := range filters {
+		if prefix, ok := strings.CutSuffix(f, "*"); ok {
+			if strings.HasPrefix(eventType, prefix) {
+				return true
+			}
+		} else if f == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDoublesUpToMaxDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 30 * time.Second, MaxDelay: 10 * time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute},
+		{7, 10 * time.Minute},
+		{200, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffBaseAboveMaxDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Hour, MaxDelay: time.Minute}
+	if got := p.Backoff(1); got != time.Minute {
+		t.Errorf("Backoff(1) = %v, want MaxDelay", got)
+	}
+}
+
+func TestValidateEventTypes(t *testing.T) {
+	tests := []struct {
+		filters []string
+		valid   bool
+	}{
+		{[]string{"order.created"}, true},
+		{[]string{"order.*", "inventory.back_in_stock"}, true},
+		{[]string{"*"}, true},
+		{nil, false},
+		{[]string{""}, false},
+		{[]string{"order.*.created"}, false},
+		{[]string{"*.created"}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateEventTypes(tt.filters); (err == nil) != tt.valid {
+			t.Errorf("ValidateEventTypes(%q) = %v, want valid %v", tt.filters, err, tt.valid)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		filters   []string
+		eventType string
+		want      bool
+	}{
+		{[]string{"order.created"}, "order.created", true},
+		{[]string{"order.created"}, "order.cancelled", false},
+		{[]string{"order.*"}, "order.cancelled", true},
+		{[]string{"order.*"}, "inventory.low_stock", false},
+		{[]string{"inventory.low_stock", "order.*"}, "order.created", true},
+		{[]string{"*"}, "inventory.back_in_stock", true},
+		{nil, "order.created", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.filters, tt.eventType); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.filters, tt.eventType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+// expectedSignature is the documented scheme, computed independently of
+// Sign: HMAC-SHA256 with the secret over the Unix timestamp, ".", and the
+// body.
+func expectedSignature(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10) + "." + string(body)))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSign(t *testing.T) {
+	ts := time.Date(2026, 10, 19, 14, 5, 0, 0, time.UTC)
+	body := []byte(`{"id":"e1","type":"order.created"}`)
+
+	got := Sign(testSecret, ts, body)
+	if want := expectedSignature(testSecret, ts.Unix(), body); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+	if Sign("whsec_other", ts, body) == got {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign(testSecret, ts.Add(time.Second), body) == got {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 10, 19, 14, 5, 0, 0, time.UTC)
+	body := []byte(`{"id":"e1"}`)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := Sign(testSecret, now, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		tolerance time.Duration
+		now       time.Time
+		want      error
+	}{
+		{"valid", testSecret, sig, ts, body, 5 * time.Minute, now, nil},
+		{"valid within tolerance", testSecret, sig, ts, body, 5 * time.Minute, now.Add(4 * time.Minute), nil},
+		{"wrong secret", "whsec_other", sig, ts, body, 5 * time.Minute, now, ErrInvalidSignature},
+		{"tampered body", testSecret, sig, ts, []byte(`{"id":"e2"}`), 5 * time.Minute, now, ErrInvalidSignature},
+		{"other timestamp", testSecret, sig, strconv.FormatInt(now.Unix()+1, 10), body, 5 * time.Minute, now, ErrInvalidSignature},
+		{"missing prefix", testSecret, strings.TrimPrefix(sig, "sha256="), ts, body, 5 * time.Minute, now, ErrInvalidSignature},
+		{"malformed timestamp", testSecret, sig, "yesterday", body, 5 * time.Minute, now, ErrInvalidSignature},
+		{"stale", testSecret, sig, ts, body, 5 * time.Minute, now.Add(6 * time.Minute), ErrStaleTimestamp},
+		{"from the future", testSecret, sig, ts, body, 5 * time.Minute, now.Add(-6 * time.Minute), ErrStaleTimestamp},
+		{"no tolerance", testSecret, sig, ts, body, 0, now.Add(24 * time.Hour), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, tt.tolerance, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 {
+		t.Errorf("NewSecret = %q, want whsec_ and 32 hex-encoded bytes", a)
+	}
+	if a == b {
+		t.Error("NewSecret returned the same secret twice")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/cemrezr/ecommerce-system/pkg/database"
 	"net/http"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/cemrezr/ecommerce-system/order-service/internal/event"
 	"github.com/cemrezr/ecommerce-system/order-service/internal/handler"
 	"github.com/cemrezr/ecommerce-system/order-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/order-service/internal/stream"
 	"github.com/cemrezr/ecommerce-system/pkg/logger"
 	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
 
@@ -31,15 +33,30 @@ func main() {
 	defer conn.Close()
 	defer ch.Close()
 
+	ctx := context.Background()
+
 	orderRepo := repository.NewOrderRepository(db)
 	eventLogger := repository.NewEventLogRepository(db)
+	orderEventRepo := repository.NewOrderEventRepository(db)
 
+	quarantine := rabbitmq.NewQuarantine(ch, "order-service", log)
 	breaker := setupCircuitBreaker()
-	publisher := event.NewPublisher(ch, cfg.RabbitMQExchange, breaker, eventLogger, log)
+	publisher := event.NewPublisher(ch, cfg.RabbitMQExchange, breaker, eventLogger, quarantine, log)
 
 	invClient := client.NewInventoryClient(cfg.InventoryServiceURL, log)
 
-	router := setupRouter(orderRepo, publisher, invClient, log)
+	hub := stream.NewHub(orderEventRepo, cfg.StreamPollInterval, log)
+	go hub.Run(ctx)
+
+	eventConsumer := event.NewOrderEventConsumer(ch, cfg.RabbitMQEventQueue, orderEventRepo, quarantine, log)
+	go func() {
+		if err := eventConsumer.StartConsuming(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Order event consumer startup failed")
+		}
+	}()
+
+	streamHandler := handler.NewOrderStreamHandler(orderRepo, orderEventRepo, hub, cfg.StreamHeartbeat, log)
+	router := setupRouter(orderRepo, publisher, invClient, streamHandler, log)
 
 	log.Info().Str("addr", ":"+cfg.AppPort).Msg("Starting HTTP server")
 	if err := http.ListenAndServe(":"+cfg.AppPort, router); err != nil {
@@ -61,6 +78,12 @@ func setupRabbitMQ(cfg *config.Config, log zerolog.Logger) (*amqp.Connection, *a
 	if err := rabbitmq.SetupOrderQueues(ch, cfg.RabbitMQExchange, cfg.RabbitMQQueue, log); err != nil {
 		log.Fatal().Err(err).Msg("Queue setup failed")
 	}
+	if err := rabbitmq.SetupBasicQueue(ch, cfg.RabbitMQExchange, cfg.RabbitMQEventQueue, event.OrderEventTypes, log); err != nil {
+		log.Fatal().Err(err).Msg("Order event queue setup failed")
+	}
+	if err := rabbitmq.SetupQuarantineQueue(ch, log); err != nil {
+		log.Fatal().Err(err).Msg("Quarantine queue setup failed")
+	}
 	return conn, ch
 }
 
@@ -76,7 +99,7 @@ func setupCircuitBreaker() *gobreaker.CircuitBreaker {
 	})
 }
 
-func setupRouter(orderRepo repository.OrderRepository, publisher *event.Publisher, invClient *client.InventoryClient, log zerolog.Logger) *mux.Router {
+func setupRouter(orderRepo repository.OrderRepository, publisher *event.Publisher, invClient *client.InventoryClient, streamHandler *handler.OrderStreamHandler, log zerolog.Logger) *mux.Router {
 	handler := handler.NewOrderHandler(orderRepo, publisher, invClient, log)
 
 	router := mux.NewRouter()
@@ -87,6 +110,8 @@ func setupRouter(orderRepo repository.OrderRepository, publisher *event.Publishe
 
 	router.HandleFunc("/orders", handler.CreateOrder).Methods("POST")
 	router.HandleFunc("/orders/{id}/cancel", handler.CancelOrder).Methods("POST")
+	router.HandleFunc("/orders/{id}/events", streamHandler.StreamEvents).Methods("GET")
+	router.HandleFunc("/orders/{id}/events/ws", streamHandler.StreamEventsWS).Methods("GET")
 
 	return router
 }
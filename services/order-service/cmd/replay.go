@@ -44,8 +44,12 @@ func main() {
 	if err := rabbitmq.SetupOrderQueues(ch, cfg.RabbitMQExchange, cfg.RabbitMQQueue, log); err != nil {
 		log.Fatal().Err(err).Msg("Failed to declare queues")
 	}
+	if err := rabbitmq.SetupQuarantineQueue(ch, log); err != nil {
+		log.Fatal().Err(err).Msg("Failed to declare quarantine queue")
+	}
 
-	publisher := event.NewPublisher(ch, cfg.RabbitMQExchange, cb, eventLogger, log)
+	quarantine := rabbitmq.NewQuarantine(ch, "order-replayer", log)
+	publisher := event.NewPublisher(ch, cfg.RabbitMQExchange, cb, eventLogger, quarantine, log)
 	replayer := event.NewReplayer(eventLogger, publisher, cb, log)
 
 	if err := replayer.ReplayFailedEvents(); err != nil {
@@ -39,7 +39,12 @@ func NewInventoryClient(baseURL string, log zerolog.Logger) *InventoryClient {
 type Product struct {
 	ProductID   int64  `json:"product_id"`
 	ProductName string `json:"product_name"`
+	SKU         string `json:"sku"`
+	PriceMinor  int64  `json:"price_minor"`
+	Currency    string `json:"currency"`
+	Status      string `json:"status"`
 	Stock       int    `json:"stock"`
+	Available   int    `json:"available"`
 }
 
 func (c *InventoryClient) GetProductByID(productID int64) (*Product, error) {
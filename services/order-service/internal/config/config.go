@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -14,6 +15,12 @@ type Config struct {
 	RabbitMQQueue       string
 	RabbitMQExchange    string
 	InventoryServiceURL string
+
+	// Order event streams are fed from RabbitMQEventQueue and pushed to
+	// clients within StreamPollInterval.
+	RabbitMQEventQueue string
+	StreamPollInterval time.Duration
+	StreamHeartbeat    time.Duration
 }
 
 func LoadConfig() *Config {
@@ -28,6 +35,9 @@ func LoadConfig() *Config {
 		RabbitMQQueue:       getEnv("RABBITMQ_ORDER_QUEUE", "order.created"),
 		RabbitMQExchange:    getEnv("RABBITMQ_EXCHANGE", ""),
 		InventoryServiceURL: getEnv("INVENTORY_SERVICE_URL", "http://localhost:8082"),
+		RabbitMQEventQueue:  getEnv("RABBITMQ_EVENT_QUEUE", "order.status.queue"),
+		StreamPollInterval:  getDurationEnv("STREAM_POLL_INTERVAL", 500*time.Millisecond),
+		StreamHeartbeat:     getDurationEnv("STREAM_HEARTBEAT", 15*time.Second),
 	}
 
 	log.Info().
@@ -37,6 +47,7 @@ func LoadConfig() *Config {
 		Str("rabbitmq_queue", cfg.RabbitMQQueue).
 		Str("rabbitmq_exchange", cfg.RabbitMQExchange).
 		Str("inventory_service_url", cfg.InventoryServiceURL).
+		Str("rabbitmq_event_queue", cfg.RabbitMQEventQueue).
 		Msg("Loaded configuration")
 
 	return cfg
@@ -49,3 +60,16 @@ func getEnv(key, fallback string) string {
 	log.Warn().Str("key", key).Msg("Using fallback for missing environment variable")
 	return fallback
 }
+
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn().Str("key", key).Str("value", value).Msg("Invalid duration, using fallback")
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,141 @@
+package event
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cemrezr/ecommerce-system/order-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/order-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+)
+
+// OrderEventTypes are the events recorded in an order's event stream: the
+// ones order-service publishes and those of other services about an order.
+var OrderEventTypes = []string{events.TypeOrderCreated, events.TypeOrderCancelled, events.TypeReservationExpired}
+
+// orderStatuses is the order status an event leaves the order in; events
+// that do not change it are not listed.
+var orderStatuses = map[string]string{
+	events.TypeOrderCreated:   "created",
+	events.TypeOrderCancelled: "cancelled",
+}
+
+// OrderEventConsumer records order events from the bus in order_events,
+// where the order status streams read them.
+type OrderEventConsumer struct {
+	ch         *amqp.Channel
+	queue      string
+	repo       repository.OrderEventRepository
+	quarantine *rabbitmq.Quarantine
+	log        zerolog.Logger
+}
+
+func NewOrderEventConsumer(ch *amqp.Channel, queue string, repo repository.OrderEventRepository, quarantine *rabbitmq.Quarantine, log zerolog.Logger) *OrderEventConsumer {
+	return &OrderEventConsumer{ch: ch, queue: queue, repo: repo, quarantine: quarantine, log: log}
+}
+
+func (c *OrderEventConsumer) StartConsuming(ctx context.Context) error {
+	msgs, err := c.ch.Consume(c.queue, "", false, false, false, false, nil)
+	if err != nil {
+		c.log.Error().Err(err).Str("queue", c.queue).Msg("Failed to start consuming order events")
+		return err
+	}
+
+	c.log.Info().Str("queue", c.queue).Msg("Order event consumer started")
+
+	go func() {
+		for msg := range msgs {
+			// Invalid events are quarantined with their validation report
+			// rather than recorded.
+			valid, err := c.quarantine.Check(c.queue, msg)
+			if err != nil {
+				c.log.Error().Err(err).Str("type", msg.Type).Msg("Failed to quarantine invalid order event — NACKing")
+				_ = msg.Nack(false, true)
+				continue
+			}
+			if !valid {
+				_ = msg.Ack(false)
+				continue
+			}
+
+			if err := c.record(ctx, msg); err != nil {
+				c.log.Error().Err(err).Str("type", msg.Type).Msg("Failed to record order event — NACKing")
+				_ = msg.Nack(false, true)
+				continue
+			}
+			_ = msg.Ack(false)
+		}
+	}()
+
+	<-ctx.Done()
+	c.log.Info().Msg("Order event consumer shutting down")
+	return nil
+}
+
+func (c *OrderEventConsumer) record(ctx context.Context, msg amqp.Delivery) error {
+	// Invalid events were quarantined already. Any other event that cannot be
+	// decoded is dropped: the stream is only a view of the orders.
+	version, _ := msg.Headers[events.VersionHeader].(string)
+	payload, err := events.Decode(msg.Type, version, msg.Body)
+	if err != nil {
+		c.log.Warn().Err(err).Str("type", msg.Type).Msg("Malformed order event — skipping")
+		return nil
+	}
+	orderID, err := eventOrderID(payload)
+	if err != nil {
+		c.log.Warn().Err(err).Str("type", msg.Type).Msg("Order event without an order ID — skipping")
+		return nil
+	}
+	// Stored as the latest version, so that stream clients see one shape
+	// per event type whatever version it was published as.
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", msg.Type, err)
+	}
+
+	e := &model.OrderEvent{
+		OrderID:   orderID,
+		EventID:   eventID(msg),
+		EventType: msg.Type,
+		Status:    orderStatuses[msg.Type],
+		Payload:   body,
+	}
+	stored, err := c.repo.Append(ctx, e)
+	if err != nil {
+		return err
+	}
+	if stored {
+		c.log.Debug().Int64("order_id", orderID).Str("type", msg.Type).Int64("event_id", e.ID).Msg("Order event recorded")
+	}
+	return nil
+}
+
+// eventOrderID finds the order a decoded event is about.
+func eventOrderID(payload interface{}) (int64, error) {
+	switch e := payload.(type) {
+	case *events.OrderCreated:
+		return e.ID, nil
+	case *events.OrderCancelled:
+		return e.ID, nil
+	case *events.ReservationExpired:
+		return e.OrderID, nil
+	default:
+		return 0, fmt.Errorf("%T is not an order event", payload)
+	}
+}
+
+// eventID is the publisher's message ID, or a hash of the message type and
+// body for messages published without one.
+func eventID(msg amqp.Delivery) string {
+	if msg.MessageId != "" {
+		return msg.MessageId
+	}
+	sum := sha256.Sum256(append([]byte(msg.Type+"\n"), msg.Body...))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
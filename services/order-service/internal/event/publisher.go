@@ -2,13 +2,15 @@ package event
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/cemrezr/ecommerce-system/order-service/internal/model"
 	"github.com/cemrezr/ecommerce-system/order-service/internal/repository"
 	"github.com/cemrezr/ecommerce-system/order-service/internal/utils"
+	"github.com/cemrezr/ecommerce-system/pkg/events"
+	"github.com/cemrezr/ecommerce-system/pkg/rabbitmq"
 	"github.com/rs/zerolog"
 	"github.com/sony/gobreaker"
 	"github.com/streadway/amqp"
@@ -19,6 +21,7 @@ type Publisher struct {
 	exchange    string
 	breaker     *gobreaker.CircuitBreaker
 	eventLogger repository.EventLogger
+	quarantine  *rabbitmq.Quarantine
 	log         zerolog.Logger
 }
 
@@ -27,6 +30,7 @@ func NewPublisher(
 	exchange string,
 	breaker *gobreaker.CircuitBreaker,
 	eventLogger repository.EventLogger,
+	quarantine *rabbitmq.Quarantine,
 	log zerolog.Logger,
 ) *Publisher {
 	return &Publisher{
@@ -34,77 +38,98 @@ func NewPublisher(
 		exchange:    exchange,
 		breaker:     breaker,
 		eventLogger: eventLogger,
+		quarantine:  quarantine,
 		log:         log,
 	}
 }
 
 func (p *Publisher) PublishOrderCreated(order *model.Order) error {
-	payload, _ := json.Marshal(order)
+	return p.publish(events.TypeOrderCreated, order.ID, events.OrderCreated{
+		ID:             order.ID,
+		UserID:         order.UserID,
+		VariantID:      order.VariantID,
+		ProductID:      order.ProductID,
+		Quantity:       order.Quantity,
+		UnitPriceMinor: order.UnitPriceMinor,
+		Currency:       order.Currency,
+		Status:         order.Status,
+		CreatedAt:      order.CreatedAt,
+	})
+}
 
-	logEntry := &repository.EventLog{
-		EventType:    "order.created",
-		EventVersion: "v1",
-		Payload:      string(payload),
-		Status:       "publishing",
-		RetryCount:   0,
-		OrderID:      &order.ID,
-	}
+// PublishOrderCancelled announces that order, as it was before being
+// cancelled, is now cancelled.
+func (p *Publisher) PublishOrderCancelled(order *model.Order) error {
+	return p.publish(events.TypeOrderCancelled, order.ID, events.OrderCancelled{
+		ID:          order.ID,
+		UserID:      order.UserID,
+		VariantID:   order.VariantID,
+		ProductID:   order.ProductID,
+		Quantity:    order.Quantity,
+		Status:      "cancelled",
+		CreatedAt:   order.CreatedAt,
+		CancelledAt: time.Now().UTC(),
+	})
+}
 
-	ctx := context.Background()
-	if err := p.eventLogger.Insert(ctx, logEntry); err != nil {
-		p.log.Error().Err(err).Msg("Failed to insert event log (order.created)")
+// publish records the event in event_logs and sends it. The payload must be
+// the latest version of eventType; an invalid one is quarantined, and neither
+// logged nor sent.
+func (p *Publisher) publish(eventType string, orderID int64, payload interface{}) error {
+	body, version, err := events.Encode(eventType, payload)
+	var verr *events.ValidationError
+	if errors.As(err, &verr) {
+		p.log.Error().Err(err).Str("event", eventType).Msg("Refusing to publish invalid event")
+		_ = p.quarantine.Publish(amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+			Type:        eventType,
+			Timestamp:   time.Now(),
+			Headers:     amqp.Table{events.VersionHeader: version},
+		}, err)
 		return err
 	}
-
-	msg := amqp.Publishing{
-		ContentType: "application/json",
-		Body:        payload,
-		Type:        "order.created",
-	}
-
-	return p.publishWithRetries(ctx, msg, logEntry)
-}
-
-func (p *Publisher) PublishOrderCancelled(order *model.Order) error {
-	payloadMap := map[string]interface{}{
-		"order_id":   order.ID,
-		"product_id": order.ProductID,
-		"quantity":   order.Quantity,
+	if err != nil {
+		p.log.Error().Err(err).Str("event", eventType).Msg("Refusing to publish event")
+		return err
 	}
-	payload, _ := json.Marshal(payloadMap)
 
 	logEntry := &repository.EventLog{
-		EventType:    "order.cancelled",
-		EventVersion: "v1",
-		Payload:      string(payload),
+		EventType:    eventType,
+		EventVersion: version,
+		Payload:      string(body),
 		Status:       "publishing",
 		RetryCount:   0,
-		OrderID:      &order.ID,
+		OrderID:      &orderID,
 	}
 
 	ctx := context.Background()
 	if err := p.eventLogger.Insert(ctx, logEntry); err != nil {
-		p.log.Error().Err(err).Msg("Failed to insert event log (order.cancelled)")
+		p.log.Error().Err(err).Msgf("Failed to insert event log (%s)", eventType)
 		return err
 	}
 
-	msg := amqp.Publishing{
-		ContentType: "application/json",
-		Body:        payload,
-		Type:        "order.cancelled",
-	}
-
-	return p.publishWithRetries(ctx, msg, logEntry)
+	return p.publishWithRetries(ctx, newPublishing(logEntry), logEntry)
 }
 
-func (p *Publisher) RepublishEvent(order *model.Order, logID int64, currentRetry *int) error {
-	ctx := context.Background()
-	payload, _ := json.Marshal(order)
+// ErrQuarantined is returned by RepublishEvent for a logged event that no
+// longer validates; it is quarantined rather than sent.
+var ErrQuarantined = errors.New("invalid event quarantined")
 
-	msg := amqp.Publishing{
-		ContentType: "application/json",
-		Body:        payload,
-		Type:        "order.created",
+// RepublishEvent sends a logged event again as it was first published,
+// with its original type and version.
+func (p *Publisher) RepublishEvent(logEntry *repository.EventLog, currentRetry *int) error {
+	ctx := context.Background()
+	logID := logEntry.ID
+	msg := newPublishing(logEntry)
+	payload := msg.Body
+
+	if err := events.Validate(logEntry.EventType, logEntry.EventVersion, payload); err != nil {
+		p.log.Error().Err(err).Int64("log_id", logID).Msg("Refusing to republish invalid event")
+		if qErr := p.quarantine.Publish(msg, err); qErr != nil {
+			return qErr
+		}
+		return fmt.Errorf("%w: %v", ErrQuarantined, err)
 	}
 
 	retryCount, err := utils.RetryWithBreaker(p.breaker, func() error {
@@ -166,3 +191,19 @@ func (p *Publisher) publishWithRetries(ctx context.Context, msg amqp.Publishing,
 	_ = p.eventLogger.UpdateStatus(ctx, logEntry.ID, "failed", logEntry.RetryCount)
 	return errors.New("event lost after retries")
 }
+
+func newPublishing(logEntry *repository.EventLog) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType: "application/json",
+		Body:        []byte(logEntry.Payload),
+		Type:        logEntry.EventType,
+		MessageId:   messageID(logEntry.ID),
+		Headers:     amqp.Table{events.VersionHeader: logEntry.EventVersion},
+	}
+}
+
+// messageID identifies an event by its event_logs row, so a replayed event
+// keeps the ID of the original and consumers can recognise it as a duplicate.
+func messageID(logID int64) string {
+	return fmt.Sprintf("order-service/event_logs/%d", logID)
+}
@@ -2,9 +2,8 @@ package event
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 
-	"github.com/cemrezr/ecommerce-system/order-service/internal/model"
 	"github.com/cemrezr/ecommerce-system/order-service/internal/repository"
 	"github.com/rs/zerolog"
 	"github.com/sony/gobreaker"
@@ -34,35 +33,36 @@ func NewReplayer(
 func (r *Replayer) ReplayFailedEvents() error {
 	ctx := context.Background()
 
-	events, err := r.eventLogger.ListFailed(ctx)
+	failed, err := r.eventLogger.ListFailed(ctx)
 	if err != nil {
 		r.log.Error().Err(err).Msg("Failed to list failed events")
 		return err
 	}
 
-	r.log.Info().Int("count", len(events)).Msg("🔁 Starting event replay")
+	r.log.Info().Int("count", len(failed)).Msg("🔁 Starting event replay")
 
-	for _, e := range events {
+	for _, e := range failed {
 		r.log.Info().
 			Int64("id", e.ID).
 			Str("type", e.EventType).
 			Interface("order_id", e.OrderID).
 			Msg("🔄 Replaying event")
 
-		var order model.Order
-		if err := json.Unmarshal([]byte(e.Payload), &order); err != nil {
-			r.log.Error().Err(err).Int64("id", e.ID).Msg("Invalid payload, skipping")
-			continue
-		}
-
+		// Events are replayed as the version they were logged as; consumers
+		// upgrade older versions themselves. Invalid ones are quarantined and
+		// not replayed again.
 		retryCount := e.RetryCount
-		err := r.publisher.RepublishEvent(&order, e.ID, &retryCount)
+		err := r.publisher.RepublishEvent(e, &retryCount)
 
 		status := "published"
-		if err != nil {
+		switch {
+		case errors.Is(err, ErrQuarantined):
+			status = "quarantined"
+			r.log.Error().Err(err).Int64("id", e.ID).Msg("Invalid payload, quarantined")
+		case err != nil:
 			status = "failed"
 			r.log.Error().Err(err).Int64("id", e.ID).Msg("Replay failed")
-		} else {
+		default:
 			r.log.Info().Int64("id", e.ID).Msg("Replay successful")
 		}
 
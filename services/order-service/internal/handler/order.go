@@ -57,6 +57,8 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 					errors[field] = field + " is required"
 				case "gt":
 					errors[field] = field + " must be greater than " + e.Param()
+				case "required_without":
+					errors[field] = field + " or " + e.Param() + " is required"
 				default:
 					errors[field] = "Invalid value for " + field
 				}
@@ -73,17 +75,23 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.InventoryClient.GetProductByID(req.ProductID)
+	product, err := h.InventoryClient.GetProductByID(req.ItemID())
 	if err != nil {
-		h.log.Warn().Err(err).Int64("product_id", req.ProductID).Msg("Product lookup failed")
+		h.log.Warn().Err(err).Int64("variant_id", req.ItemID()).Msg("Product lookup failed")
 		http.Error(w, "Product not found", http.StatusBadRequest)
 		return
 	}
 
-	if product.Stock < req.Quantity {
+	if product.Status != "" && product.Status != "active" {
+		h.log.Warn().Int64("variant_id", req.ItemID()).Str("status", product.Status).Msg("Product is not available for sale")
+		http.Error(w, "Product is not available for sale", http.StatusBadRequest)
+		return
+	}
+
+	if product.Available < req.Quantity {
 		h.log.Warn().
-			Int64("product_id", req.ProductID).
-			Int("stock", product.Stock).
+			Int64("variant_id", req.ItemID()).
+			Int("available", product.Available).
 			Int("requested", req.Quantity).
 			Msg("Insufficient stock")
 		http.Error(w, "Insufficient stock", http.StatusBadRequest)
@@ -91,6 +99,8 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	order := req.ToOrder()
+	order.UnitPriceMinor = product.PriceMinor
+	order.Currency = product.Currency
 
 	if err := h.Repo.Create(r.Context(), order); err != nil {
 		h.log.Error().Err(err).Msg("Failed to create order in DB")
@@ -101,7 +111,7 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	h.log.Info().
 		Int64("order_id", order.ID).
 		Int64("user_id", order.UserID).
-		Int64("product_id", order.ProductID).
+		Int64("variant_id", order.VariantID).
 		Int("quantity", order.Quantity).
 		Msg("✅ Order successfully created")
 
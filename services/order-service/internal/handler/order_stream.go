@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/order-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/order-service/internal/repository"
+	"github.com/cemrezr/ecommerce-system/order-service/internal/stream"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"golang.org/x/net/websocket"
+)
+
+var errSubscriberDropped = errors.New("subscriber fell behind")
+
+// pingCodec sends a WebSocket ping frame, which clients answer on their own.
+var pingCodec = websocket.Codec{Marshal: func(interface{}) ([]byte, byte, error) {
+	return nil, websocket.PingFrame, nil
+}}
+
+// OrderStreamHandler streams an order's events to front-ends as they are
+// observed, so that they need not poll for status changes. A stream starts
+// with every event of the order after the one the client last saw, or with
+// all of them, followed by new events as they arrive.
+type OrderStreamHandler struct {
+	orders    repository.OrderRepository
+	events    repository.OrderEventRepository
+	hub       *stream.Hub
+	heartbeat time.Duration
+	log       zerolog.Logger
+}
+
+func NewOrderStreamHandler(
+	orders repository.OrderRepository,
+	events repository.OrderEventRepository,
+	hub *stream.Hub,
+	heartbeat time.Duration,
+	log zerolog.Logger,
+) *OrderStreamHandler {
+	return &OrderStreamHandler{orders: orders, events: events, hub: hub, heartbeat: heartbeat, log: log}
+}
+
+// StreamEvents serves GET /orders/{id}/events as Server-Sent Events. Each
+// event's SSE id is its order event ID, so a reconnecting EventSource resumes
+// through the Last-Event-ID header; ?last_event_id= does the same for a
+// first connection.
+func (h *OrderStreamHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	orderID, lastID, ok := h.streamParams(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(e model.OrderEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.EventType, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	heartbeat := func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	h.log.Info().Int64("order_id", orderID).Int64("last_event_id", lastID).Msg("Order event stream opened")
+	err := h.follow(r.Context(), orderID, lastID, send, heartbeat)
+	h.closed(orderID, err)
+}
+
+// StreamEventsWS serves the same stream over a WebSocket at
+// GET /orders/{id}/events/ws, one JSON text message per event. Clients
+// resume with ?last_event_id=, the id of the last message they received.
+func (h *OrderStreamHandler) StreamEventsWS(w http.ResponseWriter, r *http.Request) {
+	orderID, lastID, ok := h.streamParams(w, r)
+	if !ok {
+		return
+	}
+
+	server := websocket.Server{
+		// Any origin may follow an order, as with the SSE endpoint.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			// The request context outlives a hijacked connection, so the
+			// stream ends when reading from the client fails instead.
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			go func() {
+				defer cancel()
+				var discard []byte
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+
+			send := func(e model.OrderEvent) error { return websocket.JSON.Send(ws, e) }
+			heartbeat := func() error { return pingCodec.Send(ws, nil) }
+
+			h.log.Info().Int64("order_id", orderID).Int64("last_event_id", lastID).Msg("Order event WebSocket opened")
+			err := h.follow(ctx, orderID, lastID, send, heartbeat)
+			h.closed(orderID, err)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// follow sends the order's events after lastID, then new ones as the hub
+// sees them, until ctx is done or sending fails.
+func (h *OrderStreamHandler) follow(ctx context.Context, orderID, lastID int64, send func(model.OrderEvent) error, heartbeat func() error) error {
+	// Subscribing before reading the backlog means no event falls between
+	// the two; the ones in both are skipped by ID.
+	sub := h.hub.Subscribe(orderID)
+	defer h.hub.Unsubscribe(sub)
+
+	backlog, err := h.events.ListByOrder(ctx, orderID, lastID)
+	if err != nil {
+		return err
+	}
+	for _, e := range backlog {
+		if err := send(e); err != nil {
+			return err
+		}
+		lastID = e.ID
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-sub.C:
+			if !ok {
+				return errSubscriberDropped
+			}
+			if e.ID <= lastID {
+				continue
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+			lastID = e.ID
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *OrderStreamHandler) closed(orderID int64, err error) {
+	if err != nil {
+		h.log.Warn().Err(err).Int64("order_id", orderID).Msg("Order event stream ended")
+		return
+	}
+	h.log.Info().Int64("order_id", orderID).Msg("Order event stream closed by client")
+}
+
+// streamParams reads the order ID and the ID of the last event the client
+// saw, and checks that the order exists.
+func (h *OrderStreamHandler) streamParams(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	orderID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || orderID <= 0 {
+		http.Error(w, "invalid order id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	var lastID int64
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw != "" {
+		if lastID, err = strconv.ParseInt(raw, 10, 64); err != nil || lastID < 0 {
+			http.Error(w, "invalid last event id", http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+
+	exists, err := h.orders.OrderExists(r.Context(), orderID)
+	if err != nil {
+		h.log.Error().Err(err).Int64("order_id", orderID).Msg("Failed to check order")
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	if !exists {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+	return orderID, lastID, true
+}
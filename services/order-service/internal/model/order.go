@@ -4,25 +4,43 @@ import (
 	"time"
 )
 
+// OrderRequest names the variant to order. product_id is still accepted for
+// clients that predate variants; a standalone product is its own variant.
 type OrderRequest struct {
 	UserID    int64 `json:"user_id" validate:"required,gt=0"`
-	ProductID int64 `json:"product_id" validate:"required,gt=0"`
+	VariantID int64 `json:"variant_id" validate:"required_without=ProductID,omitempty,gt=0"`
+	ProductID int64 `json:"product_id" validate:"required_without=VariantID,omitempty,gt=0"`
 	Quantity  int   `json:"quantity" validate:"required,gt=0"`
 }
 
+func (r *OrderRequest) ItemID() int64 {
+	if r.VariantID != 0 {
+		return r.VariantID
+	}
+	return r.ProductID
+}
+
 func (r *OrderRequest) ToOrder() *Order {
 	return &Order{
 		UserID:    r.UserID,
-		ProductID: r.ProductID,
+		VariantID: r.ItemID(),
+		ProductID: r.ItemID(),
 		Quantity:  r.Quantity,
 	}
 }
 
+// Order snapshots the product's unit price at creation time so later catalog
+// price changes do not alter existing orders. VariantID is the inventory item
+// whose stock the order consumes; ProductID carries the same value for
+// consumers that have not moved to variant_id yet.
 type Order struct {
-	ID        int64     `db:"id" json:"id"`
-	UserID    int64     `db:"user_id" json:"user_id"`
-	ProductID int64     `db:"product_id" json:"product_id"`
-	Quantity  int       `db:"quantity" json:"quantity"`
-	Status    string    `db:"status" json:"status"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID             int64     `db:"id" json:"id"`
+	UserID         int64     `db:"user_id" json:"user_id"`
+	VariantID      int64     `db:"variant_id" json:"variant_id"`
+	ProductID      int64     `db:"product_id" json:"product_id"`
+	Quantity       int       `db:"quantity" json:"quantity"`
+	UnitPriceMinor int64     `db:"unit_price_minor" json:"unit_price_minor"`
+	Currency       string    `db:"currency" json:"currency"`
+	Status         string    `db:"status" json:"status"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
 }
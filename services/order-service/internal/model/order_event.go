@@ -0,0 +1,19 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OrderEvent is something that happened to an order, as observed on the
+// event bus. IDs increase in the order events were observed, so a client
+// that has seen ID n resumes with the events after n.
+type OrderEvent struct {
+	ID         int64           `db:"id" json:"id"`
+	OrderID    int64           `db:"order_id" json:"order_id"`
+	EventID    string          `db:"event_id" json:"-"`
+	EventType  string          `db:"event_type" json:"type"`
+	Status     string          `db:"status" json:"status,omitempty"`
+	Payload    json.RawMessage `db:"payload" json:"data"`
+	OccurredAt time.Time       `db:"created_at" json:"occurred_at"`
+}
@@ -30,8 +30,8 @@ func (r *orderRepository) Create(ctx context.Context, order *model.Order) error
 	order.Status = "created"
 
 	query := `
-		INSERT INTO orders (user_id, product_id, quantity, status, created_at)
-		VALUES (:user_id, :product_id, :quantity, :status, :created_at)
+		INSERT INTO orders (user_id, variant_id, product_id, quantity, unit_price_minor, currency, status, created_at)
+		VALUES (:user_id, :variant_id, :product_id, :quantity, :unit_price_minor, :currency, :status, :created_at)
 		RETURNING id
 	`
 
@@ -47,7 +47,7 @@ func (r *orderRepository) Create(ctx context.Context, order *model.Order) error
 		return err
 	}
 
-	log.Info().Str("operation", "CreateOrder").Int64("order_id", order.ID).Int64("user_id", order.UserID).Int64("product_id", order.ProductID).Msg("✅ Order inserted into database")
+	log.Info().Str("operation", "CreateOrder").Int64("order_id", order.ID).Int64("user_id", order.UserID).Int64("variant_id", order.VariantID).Msg("✅ Order inserted into database")
 	return nil
 }
 
@@ -73,7 +73,7 @@ func (r *orderRepository) Cancel(ctx context.Context, orderID int64) error {
 
 func (r *orderRepository) GetByID(ctx context.Context, id int64) (*model.Order, error) {
 	var order model.Order
-	query := `SELECT id, user_id, product_id, quantity, status, created_at FROM orders WHERE id = $1`
+	query := `SELECT id, user_id, variant_id, product_id, quantity, unit_price_minor, currency, status, created_at FROM orders WHERE id = $1`
 
 	err := r.db.GetContext(ctx, &order, query, id)
 	if err != nil {
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cemrezr/ecommerce-system/order-service/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type OrderEventRepository interface {
+	Append(ctx context.Context, e *model.OrderEvent) (bool, error)
+	ListByOrder(ctx context.Context, orderID, afterID int64) ([]model.OrderEvent, error)
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]model.OrderEvent, error)
+	LatestID(ctx context.Context) (int64, error)
+}
+
+type orderEventRepository struct {
+	db *sqlx.DB
+}
+
+func NewOrderEventRepository(db *sqlx.DB) OrderEventRepository {
+	return &orderEventRepository{db: db}
+}
+
+const orderEventColumns = `id, order_id, event_id, event_type, status, payload, created_at`
+
+// orderEventsLock serialises appends, see Append.
+const orderEventsLock = 0x6f726465 // "orde"
+
+// Append stores e and fills in its ID. It reports false, and stores nothing,
+// if an event with the same event ID was already stored.
+//
+// Readers follow the table by ID, so IDs must become visible in order. Two
+// concurrent inserts could commit the other way round, which is why appends
+// are serialised with an advisory lock held until commit.
+func (r *orderEventRepository) Append(ctx context.Context, e *model.OrderEvent) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin order event transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, orderEventsLock); err != nil {
+		return false, fmt.Errorf("failed to lock order events: %w", err)
+	}
+
+	// Passed as text: lib/pq would send []byte as bytea, which JSONB rejects.
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO order_events (order_id, event_id, event_type, status, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (event_id) DO NOTHING
+		RETURNING id, created_at
+	`, e.OrderID, e.EventID, e.EventType, e.Status, string(e.Payload)).Scan(&e.ID, &e.OccurredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to append order event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit order event: %w", err)
+	}
+	return true, nil
+}
+
+// ListByOrder returns the order's events after afterID, oldest first.
+func (r *orderEventRepository) ListByOrder(ctx context.Context, orderID, afterID int64) ([]model.OrderEvent, error) {
+	query := `SELECT ` + orderEventColumns + ` FROM order_events WHERE order_id = $1 AND id > $2 ORDER BY id`
+
+	var list []model.OrderEvent
+	if err := r.db.SelectContext(ctx, &list, query, orderID, afterID); err != nil {
+		return nil, fmt.Errorf("failed to list order events: %w", err)
+	}
+	return list, nil
+}
+
+// ListAfter returns up to limit events of any order after afterID, oldest
+// first.
+func (r *orderEventRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]model.OrderEvent, error) {
+	query := `SELECT ` + orderEventColumns + ` FROM order_events WHERE id > $1 ORDER BY id LIMIT $2`
+
+	var list []model.OrderEvent
+	if err := r.db.SelectContext(ctx, &list, query, afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list new order events: %w", err)
+	}
+	return list, nil
+}
+
+func (r *orderEventRepository) LatestID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.db.GetContext(ctx, &id, `SELECT COALESCE(MAX(id), 0) FROM order_events`); err != nil {
+		return 0, fmt.Errorf("failed to get latest order event: %w", err)
+	}
+	return id, nil
+}
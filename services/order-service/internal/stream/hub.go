@@ -0,0 +1,139 @@
+// Package stream pushes order events to clients following an order.
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cemrezr/ecommerce-system/order-service/internal/model"
+	"github.com/cemrezr/ecommerce-system/order-service/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	pollBatchSize = 500
+	// subscriptionBuffer events may queue up for a slow client before it is
+	// dropped; it then reconnects and catches up from the database.
+	subscriptionBuffer = 64
+)
+
+// Hub follows order_events and hands new events to the subscriptions of
+// their order. Every instance of the service polls the table itself, so a
+// client sees all events whichever instance recorded them.
+type Hub struct {
+	repo     repository.OrderEventRepository
+	interval time.Duration
+	log      zerolog.Logger
+
+	mu     sync.Mutex
+	subs   map[int64]map[*Subscription]struct{}
+	lastID int64
+}
+
+// Subscription receives the events of one order on C. C is closed if the
+// subscriber falls too far behind.
+type Subscription struct {
+	OrderID int64
+	C       chan model.OrderEvent
+}
+
+func NewHub(repo repository.OrderEventRepository, interval time.Duration, log zerolog.Logger) *Hub {
+	return &Hub{
+		repo:     repo,
+		interval: interval,
+		log:      log,
+		subs:     make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Subscribe(orderID int64) *Subscription {
+	s := &Subscription{OrderID: orderID, C: make(chan model.OrderEvent, subscriptionBuffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[orderID] == nil {
+		h.subs[orderID] = make(map[*Subscription]struct{})
+	}
+	h.subs[orderID][s] = struct{}{}
+	return s
+}
+
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// Run polls for new events until ctx is done. Events recorded before it
+// starts are not pushed; clients read those from the database.
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	started := false
+	for {
+		if !started {
+			lastID, err := h.repo.LatestID(ctx)
+			if err != nil {
+				h.log.Error().Err(err).Msg("Failed to start order event stream")
+			} else {
+				h.lastID, started = lastID, true
+				h.log.Info().Int64("last_event_id", lastID).Dur("interval", h.interval).Msg("Order event stream started")
+			}
+		} else {
+			h.poll(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			h.log.Info().Msg("Order event stream stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) poll(ctx context.Context) {
+	for {
+		events, err := h.repo.ListAfter(ctx, h.lastID, pollBatchSize)
+		if err != nil {
+			h.log.Error().Err(err).Msg("Failed to poll order events")
+			return
+		}
+		for _, e := range events {
+			h.publish(e)
+			h.lastID = e.ID
+		}
+		if len(events) < pollBatchSize {
+			return
+		}
+	}
+}
+
+func (h *Hub) publish(e model.OrderEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs[e.OrderID] {
+		select {
+		case s.C <- e:
+		default:
+			h.log.Warn().Int64("order_id", e.OrderID).Msg("Order stream subscriber too slow — dropping it")
+			h.remove(s)
+		}
+	}
+}
+
+// remove closes s unless it was already removed; h.mu must be held.
+func (h *Hub) remove(s *Subscription) {
+	subs := h.subs[s.OrderID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subs, s.OrderID)
+	}
+	close(s.C)
+}
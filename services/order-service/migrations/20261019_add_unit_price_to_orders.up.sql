@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    ADD COLUMN unit_price_minor BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
@@ -0,0 +1,5 @@
+ALTER TABLE orders ADD COLUMN variant_id BIGINT;
+
+UPDATE orders SET variant_id = product_id WHERE variant_id IS NULL;
+
+ALTER TABLE orders ALTER COLUMN variant_id SET NOT NULL;
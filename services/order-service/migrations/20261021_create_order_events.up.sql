@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS order_events (
+                                            id BIGSERIAL PRIMARY KEY,
+                                            order_id BIGINT NOT NULL,
+                                            event_id TEXT NOT NULL UNIQUE,
+                                            event_type TEXT NOT NULL,
+                                            status TEXT NOT NULL DEFAULT '',
+                                            payload JSONB NOT NULL,
+                                            created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_events_order ON order_events (order_id, id);